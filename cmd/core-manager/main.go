@@ -15,9 +15,12 @@ import (
 	"github.com/gqls/agentchassis/internal/core-manager/api"
 
 	// Platform packages
+	"github.com/gqls/agentchassis/platform/aiservice"
 	"github.com/gqls/agentchassis/platform/config"
 	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/kafka"
 	"github.com/gqls/agentchassis/platform/logger"
+	"github.com/gqls/agentchassis/platform/memory"
 
 	"go.uber.org/zap"
 )
@@ -66,8 +69,72 @@ func main() {
 	}
 	defer clientsPool.Close()
 
-	// --- Step 4: Initialize and Start the API Server ---
-	apiServer, err := api.NewServer(ctx, cfg, appLogger, templatesPool, clientsPool)
+	// --- Step 4: Initialize Kafka for agent requests ---
+	producer, err := kafka.NewProducer(cfg.Infrastructure.KafkaBrokers, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize Kafka producer", zap.Error(err))
+	}
+	defer producer.Close()
+
+	replyTopic := "system.responses.core-manager"
+	if cfg.Custom != nil {
+		if t, ok := cfg.Custom["reply_topic"].(string); ok && t != "" {
+			replyTopic = t
+		}
+	}
+	replyClient, err := kafka.NewRequestReplyClient(cfg.Infrastructure.KafkaBrokers, producer, replyTopic, "core-manager-replies", appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize Kafka request/reply client", zap.Error(err))
+	}
+	defer replyClient.Close()
+	replyClient.Start(ctx)
+
+	// Embeddings for memory search and edits; the text model writes consolidation summaries
+	var embedder aiservice.EmbeddingService
+	var summarizer aiservice.AIService
+	aiConfig, ok := cfg.Custom["ai_service"].(map[string]interface{})
+	if ok {
+		embedder, err = aiservice.NewEmbeddingFromConfig(ctx, aiConfig)
+		if err != nil {
+			appLogger.Fatal("Failed to initialize embedding client", zap.Error(err))
+		}
+		if _, ok := aiConfig["provider"]; ok {
+			summarizer, err = aiservice.NewFromConfig(ctx, aiConfig)
+			if err != nil {
+				appLogger.Fatal("Failed to initialize AI client", zap.Error(err))
+			}
+		}
+	} else {
+		appLogger.Warn("No ai_service configured, memory search and editing are disabled")
+	}
+
+	// Memory retention enforces each persona's memory_config, and the shared scope
+	// settings, in the background
+	if retentionConfig, ok := cfg.Custom["memory_retention"].(map[string]interface{}); ok && retentionConfig["enabled"] == true {
+		interval := memory.DefaultRetentionInterval
+		switch minutes := retentionConfig["interval_minutes"].(type) {
+		case int:
+			if minutes > 0 {
+				interval = time.Duration(minutes) * time.Minute
+			}
+		case float64:
+			if minutes > 0 {
+				interval = time.Duration(minutes * float64(time.Minute))
+			}
+		}
+		if summarizer == nil {
+			appLogger.Warn("No ai_service provider configured, memory consolidation is disabled")
+		}
+		retention := memory.NewRetention(database.NewMemoryRepository(clientsPool, appLogger), summarizer, embedder, appLogger)
+		if shared, ok := retentionConfig["shared"].(map[string]interface{}); ok {
+			retention.SetSharedRetention(memory.SharedRetentionFromConfig(shared))
+		}
+		go retention.Run(ctx, interval)
+		appLogger.Info("Memory retention started", zap.Duration("interval", interval))
+	}
+
+	// --- Step 5: Initialize and Start the API Server ---
+	apiServer, err := api.NewServer(ctx, cfg, appLogger, templatesPool, clientsPool, embedder, producer, replyClient)
 	if err != nil {
 		appLogger.Fatal("Failed to initialize API server", zap.Error(err))
 	}
@@ -81,7 +148,7 @@ func main() {
 		}
 	}()
 
-	// --- Step 5: Handle Graceful Shutdown ---
+	// --- Step 6: Handle Graceful Shutdown ---
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	receivedSignal := <-sigCh
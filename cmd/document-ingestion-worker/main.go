@@ -0,0 +1,73 @@
+// FILE: cmd/document-ingestion-worker/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gqls/agentchassis/internal/adapters/documents"
+	"github.com/gqls/agentchassis/platform/config"
+	"github.com/gqls/agentchassis/platform/logger"
+	"go.uber.org/zap"
+)
+
+const shutdownTimeout = 2 * time.Minute
+
+func main() {
+	configPath := flag.String("config", "configs/document-ingestion-worker.yaml", "Path to config file")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	appLogger, err := logger.New(cfg.Logging.Level)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer appLogger.Sync()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	adapter, err := documents.NewAdapter(ctx, cfg, appLogger)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize document ingestion worker", zap.Error(err))
+	}
+
+	// Run serves health/metrics and consumes until the context is cancelled
+	done := make(chan error, 1)
+	go func() {
+		done <- adapter.Run()
+	}()
+
+	// Wait for shutdown signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+	select {
+	case <-quit:
+		appLogger.Info("Shutdown signal received, draining in-flight requests...")
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				appLogger.Error("Document ingestion worker shutdown error", zap.Error(err))
+			}
+		case <-time.After(shutdownTimeout):
+			appLogger.Warn("Timed out waiting for in-flight requests")
+		}
+	case err := <-done:
+		if err != nil {
+			appLogger.Error("Document ingestion worker failed to run", zap.Error(err))
+		}
+	}
+
+	appLogger.Info("Document ingestion worker stopped")
+}
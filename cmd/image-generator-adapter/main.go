@@ -16,6 +16,8 @@ import (
 	"go.uber.org/zap"
 )
 
+const shutdownTimeout = 2 * time.Minute
+
 func main() {
 	configPath := flag.String("config", "configs/image-adapter.yaml", "Path to config file")
 	flag.Parse()
@@ -39,24 +41,33 @@ func main() {
 		appLogger.Fatal("Failed to initialize image generator adapter", zap.Error(err))
 	}
 
-	// Start health endpoint HERE
-	adapter.StartHealthServer("9090")
-
-	// Start the adapter's main run loop in a goroutine
+	// Run serves health/metrics and consumes until the context is cancelled
+	done := make(chan error, 1)
 	go func() {
-		if err := adapter.Run(); err != nil {
-			appLogger.Error("Image generator adapter failed to run", zap.Error(err))
-			cancel()
-		}
+		done <- adapter.Run()
 	}()
 
 	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	appLogger.Info("Shutdown signal received, shutting down image generator adapter...")
 
-	cancel()
-	time.Sleep(2 * time.Second)
-	appLogger.Info("Image generator adapter stopped.")
+	select {
+	case <-quit:
+		appLogger.Info("Shutdown signal received, draining in-flight requests...")
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				appLogger.Error("Image generator adapter shutdown error", zap.Error(err))
+			}
+		case <-time.After(shutdownTimeout):
+			appLogger.Warn("Timed out waiting for in-flight requests")
+		}
+	case err := <-done:
+		if err != nil {
+			appLogger.Error("Image generator adapter failed to run", zap.Error(err))
+		}
+	}
+
+	appLogger.Info("Image generator adapter stopped")
 }
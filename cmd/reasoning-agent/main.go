@@ -8,7 +8,6 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/gqls/agentchassis/internal/agents/reasoning"
 	"github.com/gqls/agentchassis/platform/config"
@@ -39,24 +38,31 @@ func main() {
 		appLogger.Fatal("Failed to initialize reasoning agent", zap.Error(err))
 	}
 
-	// Start health endpoint HERE
-	agent.StartHealthServer("9090")
+	// Handle shutdown
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
-	// Start the agent's main run loop in a goroutine
+	// Run agent in goroutine
+	errCh := make(chan error, 1)
 	go func() {
 		if err := agent.Run(); err != nil {
-			appLogger.Error("Reasoning agent failed to run", zap.Error(err))
-			cancel()
+			errCh <- err
 		}
 	}()
 
-	// Wait for shutdown signal
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	appLogger.Info("Shutdown signal received, shutting down reasoning agent...")
+	// Wait for shutdown signal or error
+	select {
+	case <-sigCh:
+		appLogger.Info("Shutdown signal received")
+		cancel()
+		if err := agent.Shutdown(); err != nil {
+			appLogger.Error("Shutdown error", zap.Error(err))
+		}
+	case err := <-errCh:
+		appLogger.Error("Reasoning agent failed", zap.Error(err))
+		cancel()
+		agent.Shutdown()
+	}
 
-	cancel()
-	time.Sleep(2 * time.Second)
 	appLogger.Info("Reasoning agent service stopped.")
 }
@@ -16,6 +16,8 @@ import (
 	"go.uber.org/zap"
 )
 
+const shutdownTimeout = 2 * time.Minute
+
 func main() {
 	configPath := flag.String("config", "configs/web-search-adapter.yaml", "Path to config file")
 	flag.Parse()
@@ -39,19 +41,33 @@ func main() {
 		appLogger.Fatal("Failed to initialize web search adapter", zap.Error(err))
 	}
 
+	// Run serves health/metrics and consumes until the context is cancelled
+	done := make(chan error, 1)
 	go func() {
-		if err := adapter.Run(); err != nil {
-			appLogger.Error("Web search adapter failed", zap.Error(err))
-			cancel()
-		}
+		done <- adapter.Run()
 	}()
 
+	// Wait for shutdown signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	appLogger.Info("Shutdown signal received")
 
-	cancel()
-	time.Sleep(2 * time.Second)
+	select {
+	case <-quit:
+		appLogger.Info("Shutdown signal received, draining in-flight requests...")
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				appLogger.Error("Web search adapter shutdown error", zap.Error(err))
+			}
+		case <-time.After(shutdownTimeout):
+			appLogger.Warn("Timed out waiting for in-flight requests")
+		}
+	case err := <-done:
+		if err != nil {
+			appLogger.Error("Web search adapter failed to run", zap.Error(err))
+		}
+	}
+
 	appLogger.Info("Web search adapter stopped")
 }
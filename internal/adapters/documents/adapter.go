@@ -0,0 +1,97 @@
+// FILE: internal/adapters/documents/adapter.go
+package documents
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/adapter"
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/config"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/ingestion"
+	"github.com/gqls/agentchassis/platform/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	consumerGroup = "document-ingestion-worker-group"
+	// Large documents make hundreds of embedding calls
+	handlerTimeout = 15 * time.Minute
+)
+
+// Handler ingests uploaded documents into persona memory
+type Handler struct {
+	logger   *zap.Logger
+	ingester *ingestion.Ingester
+}
+
+// NewAdapter creates the document ingestion worker. It needs the clients database,
+// object storage and an embedding provider in custom.ai_service.
+func NewAdapter(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger) (*adapter.Adapter, error) {
+	clientsDB, err := database.NewPostgresConnection(ctx, cfg.Infrastructure.ClientsDatabase, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clients database: %w", err)
+	}
+
+	storageClient, err := storage.NewS3Client(ctx, cfg.Infrastructure.ObjectStorage)
+	if err != nil {
+		clientsDB.Close()
+		return nil, fmt.Errorf("failed to create storage client: %w", err)
+	}
+
+	aiConfig, ok := cfg.Custom["ai_service"].(map[string]interface{})
+	if !ok {
+		clientsDB.Close()
+		return nil, fmt.Errorf("custom.ai_service is required for embeddings")
+	}
+	embedder, err := aiservice.NewEmbeddingFromConfig(ctx, aiConfig)
+	if err != nil {
+		clientsDB.Close()
+		return nil, fmt.Errorf("failed to create embedding client: %w", err)
+	}
+
+	ingester := ingestion.NewIngester(storageClient, embedder, database.NewMemoryRepository(clientsDB, logger), logger)
+	if chunking, ok := cfg.Custom["chunking"].(map[string]interface{}); ok {
+		size, _ := chunking["size"].(float64)
+		overlap, _ := chunking["overlap"].(float64)
+		ingester.SetChunking(int(size), int(overlap))
+	}
+
+	return adapter.New(ctx, cfg, adapter.Config{
+		Name:           "document-ingestion",
+		RequestTopic:   ingestion.Topic,
+		ResponseTopic:  ingestion.ResponseTopic,
+		ConsumerGroup:  consumerGroup,
+		HandlerTimeout: handlerTimeout,
+	}, &Handler{logger: logger, ingester: ingester}, logger)
+}
+
+// Handle ingests one document
+func (h *Handler) Handle(ctx context.Context, req *adapter.Request) (interface{}, error) {
+	var data ingestion.Request
+	if err := req.Bind(&data); err != nil {
+		return nil, err
+	}
+	if data.URI == "" {
+		return nil, errors.ValidationError("uri", "uri is required")
+	}
+
+	result, err := h.ingester.Ingest(ctx, req.Headers["client_id"], data)
+	if err != nil {
+		switch {
+		case stderrors.Is(err, ingestion.ErrInvalidRequest),
+			stderrors.Is(err, ingestion.ErrUnsupportedFormat),
+			stderrors.Is(err, ingestion.ErrNoText),
+			stderrors.Is(err, ingestion.ErrTooLarge):
+			// Retrying will not help; the document goes to the DLQ
+			return nil, errors.ValidationError("document", err.Error())
+		default:
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -11,9 +11,9 @@ import (
 	"os"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/platform/adapter"
 	"github.com/gqls/agentchassis/platform/config"
-	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/errors"
 	"go.uber.org/zap"
 )
 
@@ -23,14 +23,11 @@ const (
 	consumerGroup = "web-search-adapter-group"
 )
 
-// RequestPayload for web search
-type RequestPayload struct {
-	Action string `json:"action"`
-	Data   struct {
-		Query      string `json:"query"`
-		NumResults int    `json:"num_results,omitempty"`
-		SearchType string `json:"search_type,omitempty"` // web, news, images
-	} `json:"data"`
+// SearchRequest is the data of a web search request
+type SearchRequest struct {
+	Query      string `json:"query"`
+	NumResults int    `json:"num_results,omitempty"`
+	SearchType string `json:"search_type,omitempty"` // web, news, images
 }
 
 // ResponsePayload with search results
@@ -48,106 +45,64 @@ type SearchResult struct {
 	PublishedAt string `json:"published_at,omitempty"`
 }
 
-// Adapter handles web search requests
-type Adapter struct {
-	ctx          context.Context
+// Handler performs web searches for the adapter framework
+type Handler struct {
 	logger       *zap.Logger
-	consumer     *kafka.Consumer
-	producer     kafka.Producer
 	httpClient   *http.Client
 	apiKey       string
 	searchAPIURL string
 }
 
 // NewAdapter creates a new web search adapter
-func NewAdapter(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger) (*Adapter, error) {
-	consumer, err := kafka.NewConsumer(cfg.Infrastructure.KafkaBrokers, requestTopic, consumerGroup, logger)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create consumer: %w", err)
-	}
-
-	producer, err := kafka.NewProducer(cfg.Infrastructure.KafkaBrokers, logger)
-	if err != nil {
-		consumer.Close()
-		return nil, fmt.Errorf("failed to create producer: %w", err)
-	}
-
+func NewAdapter(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger) (*adapter.Adapter, error) {
 	apiKey := os.Getenv("SERP_API_KEY")
 	if apiKey == "" {
-		consumer.Close()
-		producer.Close()
 		return nil, fmt.Errorf("SERP_API_KEY not set")
 	}
 
-	return &Adapter{
-		ctx:          ctx,
+	handler := &Handler{
 		logger:       logger,
-		consumer:     consumer,
-		producer:     producer,
 		httpClient:   &http.Client{Timeout: 30 * time.Second},
 		apiKey:       apiKey,
 		searchAPIURL: "https://serpapi.com/search",
-	}, nil
-}
-
-// Run starts the adapter's main loop
-func (a *Adapter) Run() error {
-	a.logger.Info("Web search adapter running")
-
-	for {
-		select {
-		case <-a.ctx.Done():
-			a.consumer.Close()
-			a.producer.Close()
-			return nil
-		default:
-			msg, err := a.consumer.FetchMessage(a.ctx)
-			if err != nil {
-				if err == context.Canceled {
-					continue
-				}
-				a.logger.Error("Failed to fetch message", zap.Error(err))
-				continue
-			}
-			go a.handleMessage(msg)
-		}
 	}
-}
 
-// handleMessage processes a search request
-func (a *Adapter) handleMessage(msg kafka.Message) {
-	headers := kafka.HeadersToMap(msg.Headers)
-	l := a.logger.With(zap.String("correlation_id", headers["correlation_id"]))
+	return adapter.New(ctx, cfg, adapter.Config{
+		Name:          "web-search",
+		RequestTopic:  requestTopic,
+		ResponseTopic: responseTopic,
+		ConsumerGroup: consumerGroup,
+	}, handler, logger)
+}
 
-	var req RequestPayload
-	if err := json.Unmarshal(msg.Value, &req); err != nil {
-		l.Error("Failed to unmarshal request", zap.Error(err))
-		a.consumer.CommitMessages(context.Background(), msg)
-		return
+// Handle processes a search request
+func (h *Handler) Handle(ctx context.Context, req *adapter.Request) (interface{}, error) {
+	var data SearchRequest
+	if err := req.Bind(&data); err != nil {
+		return nil, err
 	}
+	if data.Query == "" {
+		return nil, errors.ValidationError("query", "query is required")
+	}
+
+	h.logger.Debug("Performing web search",
+		zap.String("correlation_id", req.Headers["correlation_id"]),
+		zap.String("query", data.Query))
 
-	// Perform the search
-	results, err := a.performSearch(req.Data.Query, req.Data.NumResults)
+	results, err := h.performSearch(ctx, data.Query, data.NumResults)
 	if err != nil {
-		l.Error("Search failed", zap.Error(err))
-		a.sendErrorResponse(headers, "Search failed: "+err.Error())
-		a.consumer.CommitMessages(context.Background(), msg)
-		return
+		return nil, err
 	}
 
-	// Send response
-	response := ResponsePayload{
-		Query:   req.Data.Query,
+	return ResponsePayload{
+		Query:   data.Query,
 		Results: results,
 		Total:   len(results),
-	}
-
-	a.sendResponse(headers, response)
-	a.consumer.CommitMessages(context.Background(), msg)
+	}, nil
 }
 
 // performSearch executes the actual web search
-func (a *Adapter) performSearch(query string, numResults int) ([]SearchResult, error) {
+func (h *Handler) performSearch(ctx context.Context, query string, numResults int) ([]SearchResult, error) {
 	if numResults == 0 {
 		numResults = 10
 	}
@@ -155,14 +110,18 @@ func (a *Adapter) performSearch(query string, numResults int) ([]SearchResult, e
 	// Build search URL
 	params := url.Values{}
 	params.Add("q", query)
-	params.Add("api_key", a.apiKey)
+	params.Add("api_key", h.apiKey)
 	params.Add("num", fmt.Sprintf("%d", numResults))
 	params.Add("engine", "google")
 
-	searchURL := fmt.Sprintf("%s?%s", a.searchAPIURL, params.Encode())
+	searchURL := fmt.Sprintf("%s?%s", h.searchAPIURL, params.Encode())
 
 	// Execute request
-	resp, err := a.httpClient.Get(searchURL)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create search request: %w", err)
+	}
+	resp, err := h.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute search request: %w", err)
 	}
@@ -204,35 +163,3 @@ func (a *Adapter) performSearch(query string, numResults int) ([]SearchResult, e
 
 	return results, nil
 }
-
-// sendResponse sends a successful response
-func (a *Adapter) sendResponse(headers map[string]string, payload ResponsePayload) {
-	responseBytes, _ := json.Marshal(payload)
-	responseHeaders := map[string]string{
-		"correlation_id": headers["correlation_id"],
-		"causation_id":   headers["request_id"],
-		"request_id":     uuid.NewString(),
-	}
-
-	if err := a.producer.Produce(a.ctx, responseTopic, responseHeaders,
-		[]byte(headers["correlation_id"]), responseBytes); err != nil {
-		a.logger.Error("Failed to produce response", zap.Error(err))
-	}
-}
-
-// sendErrorResponse sends an error response
-func (a *Adapter) sendErrorResponse(headers map[string]string, errorMsg string) {
-	payload := map[string]interface{}{
-		"success": false,
-		"error":   errorMsg,
-	}
-	responseBytes, _ := json.Marshal(payload)
-	responseHeaders := map[string]string{
-		"correlation_id": headers["correlation_id"],
-		"causation_id":   headers["request_id"],
-		"request_id":     uuid.NewString(),
-	}
-
-	a.producer.Produce(a.ctx, responseTopic, responseHeaders,
-		[]byte(headers["correlation_id"]), responseBytes)
-}
@@ -5,29 +5,68 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"strings"
 
-	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/platform/agentbase"
 	"github.com/gqls/agentchassis/platform/aiservice"
 	"github.com/gqls/agentchassis/platform/config"
-	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/gqls/agentchassis/platform/prompts"
 	"go.uber.org/zap"
 )
 
 const (
+	agentType     = "reasoning"
 	requestTopic  = "system.agent.reasoning.process"
 	responseTopic = "system.responses.reasoning"
-	consumerGroup = "reasoning-agent-group"
+
+	// ActionReview is the workflow action that performs a reasoning review
+	ActionReview = "reasoning_review"
 )
 
-// RequestPayload defines the data this agent expects
-type RequestPayload struct {
-	Action string `json:"action"`
-	Data   struct {
-		ContentToReview string                 `json:"content_to_review"`
-		ReviewCriteria  []string               `json:"review_criteria"`
-		BriefContext    map[string]interface{} `json:"brief_context"`
-	} `json:"data"`
+// DefaultPromptTemplate is used when the persona references no prompt template and has
+// no reasoning_prompt. {{criteria}}, {{context}} and {{content}} are replaced before the
+// prompt is sent. Prompt templates get the same values as .criteria, .context and .content.
+// All three may come from the request and arrive wrapped in <untrusted_input> tags;
+// templates get the criteria as a list and the context as an object, with every
+// string in them wrapped.
+const DefaultPromptTemplate = `You are a logical reasoning engine. Review the following content based on these criteria:
+{{criteria}}
+
+Context:
+{{context}}
+
+Content to review:
+{{content}}
+
+Provide your analysis as a JSON object with the following structure:
+{
+    "review_passed": boolean,
+    "score": number (0-10),
+    "suggestions": ["suggestion1", "suggestion2", ...],
+    "reasoning": "detailed explanation of your analysis"
+}
+
+Be thorough but concise in your reasoning.`
+
+// ReviewSchema is the JSON Schema review responses are validated against
+var ReviewSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []string{"review_passed", "score", "suggestions", "reasoning"},
+	"properties": map[string]interface{}{
+		"review_passed": map[string]interface{}{"type": "boolean"},
+		"score":         map[string]interface{}{"type": "number", "minimum": 0, "maximum": 10},
+		"suggestions":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"reasoning":     map[string]interface{}{"type": "string", "minLength": 1},
+	},
+}
+
+// ReviewRequest is the data of a reasoning request
+type ReviewRequest struct {
+	ContentToReview string                 `json:"content_to_review"`
+	ReviewCriteria  []string               `json:"review_criteria"`
+	BriefContext    map[string]interface{} `json:"brief_context"`
 }
 
 // ResponsePayload defines the response format
@@ -38,184 +77,147 @@ type ResponsePayload struct {
 	Reasoning    string   `json:"reasoning"`
 }
 
-// Agent is the reasoning specialist
-type Agent struct {
-	ctx      context.Context
-	logger   *zap.Logger
-	consumer *kafka.Consumer
-	producer kafka.Producer
-	aiClient aiservice.AIService
-}
+// NewAgent creates the reasoning agent on the agent chassis
+func NewAgent(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger) (*agentbase.Agent, error) {
+	aiConfig, ok := cfg.Custom["ai_service"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("ai_service configuration is required")
+	}
 
-// NewAgent creates a new reasoning agent
-func NewAgent(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger) (*Agent, error) {
-	consumer, err := kafka.NewConsumer(cfg.Infrastructure.KafkaBrokers, requestTopic, consumerGroup, logger)
+	agent, err := agentbase.NewWithType(ctx, cfg, logger, agentType, requestTopic)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create consumer: %w", err)
+		return nil, err
 	}
-
-	producer, err := kafka.NewProducer(cfg.Infrastructure.KafkaBrokers, logger)
+	aiClient, err := agent.NewAIClient(ctx, aiConfig)
 	if err != nil {
-		consumer.Close()
-		return nil, fmt.Errorf("failed to create producer: %w", err)
+		agent.Shutdown()
+		return nil, err
 	}
 
-	// Initialize AI client from custom config
-	aiConfig := cfg.Custom["ai_service"].(map[string]interface{})
-	aiClient, err := aiservice.NewAnthropicClient(ctx, aiConfig)
-	if err != nil {
-		consumer.Close()
-		producer.Close()
-		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	ai := agentbase.NewMeteredAI(aiClient, governance.NewTokenFuelPolicy(governance.DefaultTokenRates),
+		aiservice.OptionsFromMap(aiConfig), agentType)
+	if err := agent.RegisterHandler(ActionReview, NewReviewHandler(ai, agent.PromptLibrary(), logger)); err != nil {
+		agent.Shutdown()
+		return nil, fmt.Errorf("failed to register reasoning handler: %w", err)
 	}
+	agent.SetResponseTopic(responseTopic)
 
-	return &Agent{
-		ctx:      ctx,
-		logger:   logger,
-		consumer: consumer,
-		producer: producer,
-		aiClient: aiClient,
-	}, nil
+	return agent, nil
 }
 
-// Run starts the agent's main loop
-func (a *Agent) Run() error {
-	a.logger.Info("Reasoning Agent is running and waiting for tasks...")
-
-	for {
-		select {
-		case <-a.ctx.Done():
-			a.consumer.Close()
-			a.producer.Close()
-			return nil
-		default:
-			msg, err := a.consumer.FetchMessage(a.ctx)
-			if err != nil {
-				if err == context.Canceled {
-					continue
-				}
-				a.logger.Error("Failed to fetch message", zap.Error(err))
-				continue
-			}
-			go a.handleMessage(msg)
+// NewReviewHandler creates the handler for reasoning_review steps.
+// The prompt, criteria and model come from the persona instance config, with the prompt
+// taken from a prompt template when the step or persona references one.
+func NewReviewHandler(ai *agentbase.MeteredAI, library prompts.Library, logger *zap.Logger) orchestration.ActionHandler {
+	return func(ctx context.Context, req *orchestration.ActionRequest) (map[string]interface{}, error) {
+		var task struct {
+			Data ReviewRequest `json:"data"`
 		}
-	}
-}
-
-// handleMessage processes a single reasoning request
-func (a *Agent) handleMessage(msg kafka.Message) {
-	headers := kafka.HeadersToMap(msg.Headers)
-	l := a.logger.With(zap.String("correlation_id", headers["correlation_id"]))
-
-	var req RequestPayload
-	if err := json.Unmarshal(msg.Value, &req); err != nil {
-		l.Error("Failed to unmarshal request", zap.Error(err))
-		a.consumer.CommitMessages(context.Background(), msg)
-		return
-	}
-
-	// Build the reasoning prompt
-	prompt := a.buildReasoningPrompt(req)
-
-	// Call the AI service
-	result, err := a.aiClient.GenerateText(a.ctx, prompt, nil)
-	if err != nil {
-		l.Error("AI reasoning call failed", zap.Error(err))
-		a.sendErrorResponse(headers, "Failed to perform reasoning")
-		a.consumer.CommitMessages(context.Background(), msg)
-		return
-	}
-
-	// Parse the AI response
-	var responsePayload ResponsePayload
-	if err := json.Unmarshal([]byte(result), &responsePayload); err != nil {
-		l.Error("Failed to parse AI response", zap.Error(err))
-		// Fallback response
-		responsePayload = ResponsePayload{
-			ReviewPassed: false,
-			Score:        0,
-			Suggestions:  []string{"Could not parse AI response"},
-			Reasoning:    result,
+		if err := json.Unmarshal(req.InitialData, &task); err != nil {
+			return nil, fmt.Errorf("failed to parse reasoning request: %w", err)
 		}
-	}
-
-	// Send response
-	a.sendResponse(headers, responsePayload)
+		review := task.Data
 
-	// Commit message
-	a.consumer.CommitMessages(context.Background(), msg)
-}
+		var coreLogic map[string]interface{}
+		if req.Config != nil {
+			coreLogic = req.Config.CoreLogic
+		}
 
-// buildReasoningPrompt creates the prompt for the LLM
-func (a *Agent) buildReasoningPrompt(req RequestPayload) string {
-	return fmt.Sprintf(`You are a logical reasoning engine. Review the following content based on these criteria: %v.
+		// Criteria in the request win over the persona's configured criteria
+		if len(review.ReviewCriteria) == 0 {
+			review.ReviewCriteria = agentbase.StringList(coreLogic["review_criteria"])
+		}
 
-Context: %v
+		guard, err := agentbase.NewGuard(req, agentType)
+		if err != nil {
+			return nil, err
+		}
+		contextJSON, _ := json.Marshal(review.BriefContext)
+		if err := guard.Input(map[string]string{
+			"criteria": strings.Join(review.ReviewCriteria, "\n"),
+			"context":  string(contextJSON),
+			"content":  review.ContentToReview,
+		}); err != nil {
+			return nil, err
+		}
 
-Content to review: "%s"
+		system, _ := coreLogic["system_prompt"].(string)
+		criteria := make([]interface{}, len(review.ReviewCriteria))
+		for i, criterion := range review.ReviewCriteria {
+			criteria[i] = criterion
+		}
+		rendered, ok, err := agentbase.RenderPromptTemplate(ctx, library, req, map[string]interface{}{
+			"criteria": agentbase.DelimitUntrustedVars("criteria", criteria),
+			"context":  agentbase.DelimitUntrustedVars("context", review.BriefContext),
+			"content":  aiservice.DelimitUntrusted("content", review.ContentToReview),
+		})
+		if err != nil {
+			return nil, err
+		}
 
-Provide your analysis as a JSON object with the following structure:
-{
-    "review_passed": boolean,
-    "score": number (0-10),
-    "suggestions": ["suggestion1", "suggestion2", ...],
-    "reasoning": "detailed explanation of your analysis"
-}
+		var prompt string
+		if ok {
+			prompt = rendered.Prompt
+			if rendered.System != "" {
+				system = rendered.System
+			}
+		} else {
+			promptTemplate := DefaultPromptTemplate
+			if p, ok := coreLogic["reasoning_prompt"].(string); ok && p != "" {
+				promptTemplate = p
+			}
+			prompt = buildReasoningPrompt(promptTemplate, review)
+		}
 
-Be thorough but concise in your reasoning.`,
-		req.Data.ReviewCriteria,
-		req.Data.BriefContext,
-		req.Data.ContentToReview,
-	)
-}
+		// Persona settings win over the ai_service defaults
+		genReq := aiservice.PromptRequest(prompt, aiservice.OptionsFromMap(coreLogic))
+		genReq.System = strings.TrimSpace(system + "\n\n" + aiservice.UntrustedInputInstruction)
 
-// sendResponse sends a successful response
-func (a *Agent) sendResponse(headers map[string]string, payload ResponsePayload) {
-	responseBytes, _ := json.Marshal(payload)
-	responseHeaders := map[string]string{
-		"correlation_id": headers["correlation_id"],
-		"causation_id":   headers["request_id"],
-		"request_id":     uuid.NewString(),
-	}
+		// Invalid answers are sent back to the model with the validation errors;
+		// every attempt is charged to the workflow's fuel
+		maxRepairs := aiservice.DefaultMaxRepairs
+		if v, ok := coreLogic["max_output_repairs"].(float64); ok {
+			maxRepairs = int(v)
+		}
+		structured := aiservice.StructuredOutput{Schema: ReviewSchema, MaxRepairs: maxRepairs}
+		generate := func(ctx context.Context, genReq *aiservice.GenerateRequest) (*aiservice.GenerateResponse, error) {
+			return ai.Generate(ctx, req, genReq)
+		}
 
-	if err := a.producer.Produce(a.ctx, responseTopic, responseHeaders,
-		[]byte(headers["correlation_id"]), responseBytes); err != nil {
-		a.logger.Error("Failed to produce response", zap.Error(err))
-	}
-}
+		var response ResponsePayload
+		result, err := structured.Generate(ctx, generate, genReq, &response)
+		if err != nil {
+			return nil, fmt.Errorf("reasoning call failed: %w", err)
+		}
+		if result.Attempts > 1 {
+			logger.Info("Reasoning output repaired",
+				zap.String("correlation_id", req.Headers["correlation_id"]),
+				zap.Int("attempts", result.Attempts))
+		}
+		if err := guard.Output(string(result.JSON), genReq.System); err != nil {
+			return nil, err
+		}
 
-// sendErrorResponse sends an error response
-func (a *Agent) sendErrorResponse(headers map[string]string, errorMsg string) {
-	payload := map[string]interface{}{
-		"success": false,
-		"error":   errorMsg,
-	}
-	responseBytes, _ := json.Marshal(payload)
-	responseHeaders := map[string]string{
-		"correlation_id": headers["correlation_id"],
-		"causation_id":   headers["request_id"],
-		"request_id":     uuid.NewString(),
+		output := map[string]interface{}{
+			"review_passed": response.ReviewPassed,
+			"score":         response.Score,
+			"suggestions":   response.Suggestions,
+			"reasoning":     response.Reasoning,
+		}
+		if flags := guard.Flags(); len(flags) > 0 {
+			output["guardrail_flags"] = flags
+		}
+		return output, nil
 	}
-
-	a.producer.Produce(a.ctx, responseTopic, responseHeaders,
-		[]byte(headers["correlation_id"]), responseBytes)
 }
 
-// StartHealthServer starts a simple HTTP server for health checks
-func (a *Agent) StartHealthServer(port string) {
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{
-			"status": "healthy",
-			"agent":  "reasoning-agent",
-		})
-	})
-
-	go func() {
-		a.logger.Info("Starting health server", zap.String("port", port))
-		if err := http.ListenAndServe(":"+port, nil); err != nil {
-			a.logger.Error("Health server failed", zap.Error(err))
-		}
-	}()
+// buildReasoningPrompt fills the prompt template with the review request. The criteria,
+// context and content are delimited so instructions hidden in them read as data.
+func buildReasoningPrompt(promptTemplate string, req ReviewRequest) string {
+	contextJSON, _ := json.Marshal(req.BriefContext)
+	return strings.NewReplacer(
+		"{{criteria}}", aiservice.DelimitUntrusted("criteria", strings.Join(req.ReviewCriteria, "\n")),
+		"{{context}}", aiservice.DelimitUntrusted("context", string(contextJSON)),
+		"{{content}}", aiservice.DelimitUntrusted("content", req.ContentToReview),
+	).Replace(promptTemplate)
 }
@@ -0,0 +1,39 @@
+// FILE: internal/core-manager/api/config_events.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/config"
+	"go.uber.org/zap"
+)
+
+// publishConfigChanged tells running agents to drop cached configs affected by a change.
+// Failures are logged only; agents fall back to their cache TTL.
+func (s *Server) publishConfigChanged(ctx context.Context, event config.AgentConfigChanged) {
+	if s.producer == nil {
+		return
+	}
+
+	event.EventType = config.AgentConfigChangedEvent
+	event.Timestamp = time.Now().UTC()
+	eventBytes, _ := json.Marshal(event)
+
+	key := event.InstanceID
+	if key == "" {
+		key = event.TemplateID
+	}
+	headers := map[string]string{
+		"event_type": config.AgentConfigChangedEvent,
+		"client_id":  event.ClientID,
+	}
+
+	if err := s.producer.Produce(ctx, config.AgentConfigChangedTopic, headers, []byte(key), eventBytes); err != nil {
+		s.logger.Error("Failed to publish config change event",
+			zap.String("instance_id", event.InstanceID),
+			zap.String("template_id", event.TemplateID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,128 @@
+// FILE: internal/core-manager/api/documents.go
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/internal/core-manager/middleware"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/ingestion"
+	"go.uber.org/zap"
+)
+
+// handleUploadDocument stores a document for the instance and queues it for ingestion
+// into the instance's memory. The multipart form takes the file as "file", an optional
+// "document_id" to replace an earlier upload, optional JSON "metadata" added to every
+// chunk and an optional "scope" (with "project_id" for the project scope) to share the
+// document beyond the instance. Ingestion runs asynchronously; the chunks appear as
+// memories of type "document".
+func (s *Server) handleUploadDocument(c *gin.Context) {
+	if s.documents == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Document upload is not available"})
+		return
+	}
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	if fileHeader.Size > ingestion.MaxDocumentBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Documents can be at most 20 MB"})
+		return
+	}
+	format, err := ingestion.DetectFormat(fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+	if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Supported formats are text, Markdown, HTML and PDF"})
+		return
+	}
+
+	documentID := uuid.New()
+	if v := c.PostForm("document_id"); v != "" {
+		if documentID, err = uuid.Parse(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+			return
+		}
+	}
+	var metadata map[string]interface{}
+	if v := c.PostForm("metadata"); v != "" {
+		if err := json.Unmarshal([]byte(v), &metadata); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "metadata must be a JSON object"})
+			return
+		}
+	}
+	project, ok := projectID(c, c.PostForm("project_id"))
+	if !ok {
+		return
+	}
+	scope := database.MemoryScope{Level: c.PostForm("scope"), ProjectID: project}
+	if err := s.memoryService.CheckScope(c.Request.Context(), clientID, scope); err != nil {
+		s.memoryError(c, err, "Failed to check memory scope")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	l := s.logger.With(zap.String("instance_id", instanceID.String()), zap.String("document_id", documentID.String()))
+
+	key := ingestion.DocumentKey(clientID, instanceID, documentID, fileHeader.Filename)
+	uri, err := s.documents.Upload(c.Request.Context(), key, ingestion.ContentType(format), file)
+	if err != nil {
+		l.Error("Failed to store document", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+
+	claims := c.MustGet("user_claims").(*middleware.AuthClaims)
+	correlationID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":    correlationID,
+		"request_id":        uuid.NewString(),
+		"client_id":         clientID,
+		"user_id":           claims.UserID,
+		"agent_instance_id": instanceID.String(),
+		"timestamp":         time.Now().UTC().Format(time.RFC3339),
+	}
+	request := ingestion.Request{
+		AgentInstanceID: instanceID.String(),
+		DocumentID:      documentID.String(),
+		URI:             uri,
+		Filename:        fileHeader.Filename,
+		ContentType:     fileHeader.Header.Get("Content-Type"),
+		Metadata:        metadata,
+		Scope:           scope.Level,
+	}
+	if scope.Level == database.ScopeProject {
+		request.ProjectID = project.String()
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"action": ingestion.ActionIngestDocument, "data": request})
+
+	if err := s.producer.Produce(c.Request.Context(), ingestion.Topic, headers, []byte(instanceID.String()), payload); err != nil {
+		l.Error("Failed to queue document for ingestion", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue document for ingestion"})
+		return
+	}
+
+	l.Info("Document queued for ingestion", zap.String("format", format), zap.Int64("size", fileHeader.Size))
+	c.JSON(http.StatusAccepted, gin.H{
+		"document_id":    documentID,
+		"correlation_id": correlationID,
+		"uri":            uri,
+		"filename":       fileHeader.Filename,
+		"format":         format,
+		"status":         "queued",
+	})
+}
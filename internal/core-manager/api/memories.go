@@ -0,0 +1,292 @@
+// FILE: internal/core-manager/api/memories.go
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/internal/core-manager/middleware"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/memory"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMemoryPageSize = 50
+	maxMemoryPageSize     = 200
+	defaultMemorySearch   = 10
+)
+
+// UpdateMemoryRequest is the body for correcting a memory
+type UpdateMemoryRequest struct {
+	Content  *string                `json:"content"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// SearchMemoriesRequest is the body for a memory search. The query is matched both by
+// meaning and by keywords; the other fields narrow the results. Scopes picks the shared
+// memories searched besides the instance's own, e.g. {"instance": 1, "project": 0.8},
+// with each result's score multiplied by the weight of its scope.
+type SearchMemoriesRequest struct {
+	Query         string                 `json:"query" binding:"required"`
+	Limit         int                    `json:"limit"`
+	Types         []string               `json:"types"`
+	Since         time.Time              `json:"since"`
+	Until         time.Time              `json:"until"`
+	Metadata      map[string]interface{} `json:"metadata"`
+	MinSimilarity float64                `json:"min_similarity"`
+	Scopes        map[string]float64     `json:"scopes"`
+	ProjectID     string                 `json:"project_id"`
+}
+
+// memoryScope resolves the persona instance in the path. Instances are looked up in the
+// caller's client schema, so memories of other tenants' instances are never reachable.
+func (s *Server) memoryScope(c *gin.Context) (string, uuid.UUID, bool) {
+	claims := c.MustGet("user_claims").(*middleware.AuthClaims)
+	ctx := context.WithValue(c.Request.Context(), "client_id", claims.ClientID)
+
+	instanceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid instance ID"})
+		return "", uuid.Nil, false
+	}
+	if _, err := s.personaRepo.GetInstanceByID(ctx, instanceID.String()); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return "", uuid.Nil, false
+	}
+	return claims.ClientID, instanceID, true
+}
+
+// memoryID parses the memory ID in the path
+func memoryID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("memory_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid memory ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// projectID parses an optional project ID; uuid.Nil stands for none
+func projectID(c *gin.Context, value string) (uuid.UUID, bool) {
+	if value == "" {
+		return uuid.Nil, true
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// handleListMemories pages through an instance's memories, pinned first. Supports
+// ?type=, ?pinned=, ?limit= and ?offset=. With ?scope=project&project_id= or
+// ?scope=client, it lists the memories shared in that scope instead.
+func (s *Server) handleListMemories(c *gin.Context) {
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+	project, ok := projectID(c, c.Query("project_id"))
+	if !ok {
+		return
+	}
+
+	filter := database.MemoryFilter{
+		Type:      c.Query("type"),
+		Scope:     c.Query("scope"),
+		ProjectID: project,
+		Limit:     defaultMemoryPageSize,
+	}
+	if v := c.Query("pinned"); v != "" {
+		pinned, err := strconv.ParseBool(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "pinned must be true or false"})
+			return
+		}
+		filter.Pinned = &pinned
+	}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be a positive integer"})
+			return
+		}
+		filter.Limit = min(n, maxMemoryPageSize)
+	}
+	if v := c.Query("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative integer"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	memories, total, err := s.memoryService.ListMemories(c.Request.Context(), clientID, instanceID, filter)
+	if err != nil {
+		s.memoryError(c, err, "Failed to retrieve memories")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"memories": memories, "total": total, "limit": filter.Limit, "offset": filter.Offset})
+}
+
+// handleSearchMemories returns the memories most relevant to a query
+func (s *Server) handleSearchMemories(c *gin.Context) {
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+
+	var req SearchMemoriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultMemorySearch
+	}
+	limit = min(limit, maxMemoryPageSize)
+
+	if req.MinSimilarity < 0 || req.MinSimilarity > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "min_similarity must be between 0 and 1"})
+		return
+	}
+	project, ok := projectID(c, req.ProjectID)
+	if !ok {
+		return
+	}
+
+	memories, err := s.memoryService.SearchMemories(c.Request.Context(), clientID, instanceID, req.Query, database.MemorySearch{
+		Types:         req.Types,
+		Since:         req.Since,
+		Until:         req.Until,
+		Metadata:      req.Metadata,
+		MinSimilarity: req.MinSimilarity,
+		Weights:       req.Scopes,
+		ProjectID:     project,
+		Limit:         limit,
+	})
+	if err != nil {
+		s.memoryError(c, err, "Failed to search memories")
+		return
+	}
+	if memories == nil {
+		memories = []database.MemoryRecord{}
+	}
+	c.JSON(http.StatusOK, gin.H{"memories": memories})
+}
+
+func (s *Server) handleGetMemory(c *gin.Context) {
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+	id, ok := memoryID(c)
+	if !ok {
+		return
+	}
+
+	record, err := s.memoryService.GetMemory(c.Request.Context(), clientID, instanceID, id)
+	if err != nil {
+		s.memoryError(c, err, "Failed to get memory")
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// handleUpdateMemory corrects a memory's content or metadata; new content is re-embedded
+func (s *Server) handleUpdateMemory(c *gin.Context) {
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+	id, ok := memoryID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMemoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Content != nil && *req.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content cannot be empty"})
+		return
+	}
+
+	record, err := s.memoryService.UpdateMemory(c.Request.Context(), clientID, instanceID, id,
+		memory.MemoryUpdate{Content: req.Content, Metadata: req.Metadata})
+	if err != nil {
+		s.memoryError(c, err, "Failed to update memory")
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+func (s *Server) handlePinMemory(c *gin.Context) {
+	s.setMemoryPinned(c, true)
+}
+
+func (s *Server) handleUnpinMemory(c *gin.Context) {
+	s.setMemoryPinned(c, false)
+}
+
+func (s *Server) setMemoryPinned(c *gin.Context, pinned bool) {
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+	id, ok := memoryID(c)
+	if !ok {
+		return
+	}
+
+	record, err := s.memoryService.SetPinned(c.Request.Context(), clientID, instanceID, id, pinned)
+	if err != nil {
+		s.memoryError(c, err, "Failed to pin memory")
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+func (s *Server) handleDeleteMemory(c *gin.Context) {
+	clientID, instanceID, ok := s.memoryScope(c)
+	if !ok {
+		return
+	}
+	id, ok := memoryID(c)
+	if !ok {
+		return
+	}
+
+	if err := s.memoryService.DeleteMemory(c.Request.Context(), clientID, instanceID, id); err != nil {
+		s.memoryError(c, err, "Failed to delete memory")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) memoryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, database.ErrMemoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+	case errors.Is(err, memory.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, memory.ErrScopeDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Project not found for this client"})
+	case errors.Is(err, memory.ErrEmbeddingsUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Memory search and editing are not available"})
+	default:
+		s.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
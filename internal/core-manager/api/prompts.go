@@ -0,0 +1,169 @@
+// FILE: internal/core-manager/api/prompts.go
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gqls/agentchassis/internal/core-manager/middleware"
+	"github.com/gqls/agentchassis/platform/prompts"
+	"go.uber.org/zap"
+)
+
+// PromptTemplateRequest is the body for creating a template or adding a version
+type PromptTemplateRequest struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	System      string             `json:"system"`
+	Body        string             `json:"body" binding:"required"`
+	Variables   []prompts.Variable `json:"variables"`
+}
+
+func (r PromptTemplateRequest) template(c *gin.Context) *prompts.Template {
+	claims := c.MustGet("user_claims").(*middleware.AuthClaims)
+	return &prompts.Template{
+		Name:        r.Name,
+		Description: r.Description,
+		System:      r.System,
+		Body:        r.Body,
+		Variables:   r.Variables,
+		CreatedBy:   claims.UserID,
+	}
+}
+
+func (s *Server) handleCreatePromptTemplate(c *gin.Context) {
+	var req PromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	tmpl := req.template(c)
+	if err := tmpl.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+
+	created, err := s.promptStore.Create(c.Request.Context(), tmpl)
+	if err != nil {
+		s.promptTemplateError(c, err, "Failed to create prompt template")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+func (s *Server) handleListPromptTemplates(c *gin.Context) {
+	templates, err := s.promptStore.List(c.Request.Context())
+	if err != nil {
+		s.logger.Error("Failed to list prompt templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve prompt templates"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prompt_templates": templates})
+}
+
+// handleGetPromptTemplate returns the latest version, or the one in ?version=
+func (s *Server) handleGetPromptTemplate(c *gin.Context) {
+	version := 0
+	if v := c.Query("version"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "version must be a positive integer"})
+			return
+		}
+		version = n
+	}
+
+	tmpl, err := s.promptStore.Get(c.Request.Context(), c.Param("id"), version)
+	if err != nil {
+		s.promptTemplateError(c, err, "Failed to get prompt template")
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
+
+func (s *Server) handleListPromptTemplateVersions(c *gin.Context) {
+	versions, err := s.promptStore.ListVersions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		s.promptTemplateError(c, err, "Failed to retrieve prompt template versions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// handleAddPromptTemplateVersion stores an edit as a new version; earlier versions stay available
+func (s *Server) handleAddPromptTemplateVersion(c *gin.Context) {
+	var req PromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	tmpl := req.template(c)
+	if err := tmpl.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template: " + err.Error()})
+		return
+	}
+
+	created, err := s.promptStore.AddVersion(c.Request.Context(), c.Param("id"), tmpl)
+	if err != nil {
+		s.promptTemplateError(c, err, "Failed to add prompt template version")
+		return
+	}
+	c.JSON(http.StatusCreated, created)
+}
+
+// handleRenderPromptTemplate previews a template with the given variables
+func (s *Server) handleRenderPromptTemplate(c *gin.Context) {
+	var req struct {
+		Version   int                    `json:"version"`
+		Variables map[string]interface{} `json:"variables"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	tmpl, err := s.promptStore.Get(c.Request.Context(), c.Param("id"), req.Version)
+	if err != nil {
+		s.promptTemplateError(c, err, "Failed to get prompt template")
+		return
+	}
+	rendered, err := tmpl.Render(req.Variables)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"version": tmpl.Version,
+		"system":  rendered.System,
+		"prompt":  rendered.Prompt,
+	})
+}
+
+func (s *Server) handleDeletePromptTemplate(c *gin.Context) {
+	if err := s.promptStore.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		s.promptTemplateError(c, err, "Failed to delete prompt template")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (s *Server) promptTemplateError(c *gin.Context, err error, message string) {
+	if errors.Is(err, prompts.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prompt template not found"})
+		return
+	}
+	if errors.Is(err, prompts.ErrTemplateExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A prompt template with this name already exists"})
+		return
+	}
+	s.logger.Error(message, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
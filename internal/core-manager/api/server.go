@@ -12,23 +12,37 @@ import (
 	"github.com/gqls/agentchassis/internal/core-manager/database"
 	"github.com/gqls/agentchassis/internal/core-manager/middleware"
 	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/aiservice"
 	"github.com/gqls/agentchassis/platform/config"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/memory"
+	"github.com/gqls/agentchassis/platform/prompts"
+	"github.com/gqls/agentchassis/platform/storage"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
 // Server represents the Core Manager API server
 type Server struct {
-	ctx         context.Context
-	cfg         *config.ServiceConfig
-	logger      *zap.Logger
-	router      *gin.Engine
-	httpServer  *http.Server
-	personaRepo models.PersonaRepository
+	ctx           context.Context
+	cfg           *config.ServiceConfig
+	logger        *zap.Logger
+	router        *gin.Engine
+	httpServer    *http.Server
+	personaRepo   models.PersonaRepository
+	promptStore   *prompts.Store
+	memoryService *memory.Service
+	documents     storage.Client // nil when object storage is not configured
+	producer      kafka.Producer
+	replyClient   *kafka.RequestReplyClient
+	agentTypes    map[string]bool // Agent types tasks may be run on
 }
 
 // FILE: internal/core-manager/api/server.go (updated NewServer function)
-func NewServer(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger, templatesDB, clientsDB *pgxpool.Pool) (*Server, error) {
+// embedder may be nil, in which case memories can be listed, pinned and deleted but not
+// searched or edited.
+func NewServer(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger, templatesDB, clientsDB *pgxpool.Pool, embedder aiservice.EmbeddingService, producer kafka.Producer, replyClient *kafka.RequestReplyClient) (*Server, error) {
 	// Initialize repositories
 	personaRepo := database.NewPersonaRepository(templatesDB, clientsDB, logger)
 
@@ -43,11 +57,26 @@ func NewServer(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logge
 	}
 
 	server := &Server{
-		ctx:         ctx,
-		cfg:         cfg,
-		logger:      logger,
-		router:      router,
-		personaRepo: personaRepo,
+		ctx:           ctx,
+		cfg:           cfg,
+		logger:        logger,
+		router:        router,
+		personaRepo:   personaRepo,
+		promptStore:   prompts.NewStore(templatesDB, logger),
+		memoryService: memory.NewService(clientsDB, embedder, logger),
+		producer:      producer,
+		replyClient:   replyClient,
+		agentTypes:    agentTypesFromConfig(cfg.Custom),
+	}
+
+	// Uploaded documents are stored for the ingestion worker
+	if cfg.Infrastructure.ObjectStorage.Bucket != "" {
+		documents, err := storage.NewS3Client(ctx, cfg.Infrastructure.ObjectStorage)
+		if err != nil {
+			logger.Warn("Object storage unavailable, document uploads are disabled", zap.Error(err))
+		} else {
+			server.documents = documents
+		}
 	}
 
 	// Setup routes with configured auth middleware
@@ -66,6 +95,7 @@ func NewServer(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logge
 func (s *Server) setupRoutesWithAuth(router *gin.Engine, authConfig *middleware.AuthMiddlewareConfig) {
 	// Health check (no auth)
 	router.GET("/health", s.handleHealth)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// API v1 group with authentication
 	apiV1 := router.Group("/api/v1")
@@ -83,6 +113,20 @@ func (s *Server) setupRoutesWithAuth(router *gin.Engine, authConfig *middleware.
 			templates.DELETE("/:id", s.handleDeleteTemplate)
 		}
 
+		// Prompt Template Library (Admin Only). Edits add versions; personas and
+		// workflow steps reference templates as "<id>" or "<id>@<version>".
+		promptTemplates := apiV1.Group("/prompt-templates")
+		promptTemplates.Use(middleware.AdminOnly())
+		{
+			promptTemplates.POST("", s.handleCreatePromptTemplate)
+			promptTemplates.GET("", s.handleListPromptTemplates)
+			promptTemplates.GET("/:id", s.handleGetPromptTemplate)
+			promptTemplates.DELETE("/:id", s.handleDeletePromptTemplate)
+			promptTemplates.GET("/:id/versions", s.handleListPromptTemplateVersions)
+			promptTemplates.POST("/:id/versions", s.handleAddPromptTemplateVersion)
+			promptTemplates.POST("/:id/render", s.handleRenderPromptTemplate)
+		}
+
 		// Persona Instance Management
 		instances := apiV1.Group("/personas/instances")
 		{
@@ -91,6 +135,17 @@ func (s *Server) setupRoutesWithAuth(router *gin.Engine, authConfig *middleware.
 			instances.GET("/:id", s.handleGetInstance)
 			instances.PATCH("/:id", s.handleUpdateInstance)
 			instances.DELETE("/:id", s.handleDeleteInstance)
+			instances.POST("/:id/run", s.handleRunTask)
+
+			// What the persona remembers, for users to review and correct
+			instances.GET("/:id/memories", s.handleListMemories)
+			instances.POST("/:id/memories/search", s.handleSearchMemories)
+			instances.GET("/:id/memories/:memory_id", s.handleGetMemory)
+			instances.PATCH("/:id/memories/:memory_id", s.handleUpdateMemory)
+			instances.DELETE("/:id/memories/:memory_id", s.handleDeleteMemory)
+			instances.POST("/:id/memories/:memory_id/pin", s.handlePinMemory)
+			instances.DELETE("/:id/memories/:memory_id/pin", s.handleUnpinMemory)
+			instances.POST("/:id/documents", s.handleUploadDocument)
 		}
 
 		// Projects
@@ -190,6 +245,7 @@ func (s *Server) handleUpdateTemplate(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update template"})
 		return
 	}
+	s.publishConfigChanged(c.Request.Context(), config.AgentConfigChanged{TemplateID: templateID, Change: "updated"})
 	c.JSON(http.StatusOK, updatedTemplate)
 }
 
@@ -199,6 +255,7 @@ func (s *Server) handleDeleteTemplate(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete template"})
 		return
 	}
+	s.publishConfigChanged(c.Request.Context(), config.AgentConfigChanged{TemplateID: templateID, Change: "deleted"})
 	c.Status(http.StatusNoContent)
 }
 
@@ -256,6 +313,9 @@ func (s *Server) handleListInstances(c *gin.Context) {
 }
 
 func (s *Server) handleUpdateInstance(c *gin.Context) {
+	claims := c.MustGet("user_claims").(*middleware.AuthClaims)
+	ctx := context.WithValue(c.Request.Context(), "client_id", claims.ClientID)
+
 	instanceID := c.Param("id")
 	var req struct {
 		Name   *string                `json:"name"`
@@ -266,20 +326,25 @@ func (s *Server) handleUpdateInstance(c *gin.Context) {
 		return
 	}
 
-	updatedInstance, err := s.personaRepo.UpdateInstance(c.Request.Context(), instanceID, req.Name, req.Config)
+	updatedInstance, err := s.personaRepo.UpdateInstance(ctx, instanceID, req.Name, req.Config)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update instance"})
 		return
 	}
+	s.publishConfigChanged(ctx, config.AgentConfigChanged{ClientID: claims.ClientID, InstanceID: instanceID, Change: "updated"})
 	c.JSON(http.StatusOK, updatedInstance)
 }
 
 func (s *Server) handleDeleteInstance(c *gin.Context) {
+	claims := c.MustGet("user_claims").(*middleware.AuthClaims)
+	ctx := context.WithValue(c.Request.Context(), "client_id", claims.ClientID)
+
 	instanceID := c.Param("id")
-	if err := s.personaRepo.DeleteInstance(c.Request.Context(), instanceID); err != nil {
+	if err := s.personaRepo.DeleteInstance(ctx, instanceID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete instance"})
 		return
 	}
+	s.publishConfigChanged(ctx, config.AgentConfigChanged{ClientID: claims.ClientID, InstanceID: instanceID, Change: "deleted"})
 	c.Status(http.StatusNoContent)
 }
 
@@ -0,0 +1,195 @@
+// FILE: internal/core-manager/api/tasks.go
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/internal/core-manager/middleware"
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRunTimeout = 60 * time.Second
+	maxRunTimeout     = 5 * time.Minute
+	agentTopicFormat  = "system.agent.%s.process"
+)
+
+// DefaultAgentTypes are the agent types tasks run on when the agent_types config is unset
+var DefaultAgentTypes = []string{"generic", "reasoning"}
+
+// agentTopicPattern is the only shape of topic a task is published to
+var agentTopicPattern = regexp.MustCompile(`^system\.agent\.[a-z0-9][a-z0-9_-]*\.process$`)
+
+// RunTaskRequest is the body for a blocking task run against a persona instance
+type RunTaskRequest struct {
+	Action         string                 `json:"action" binding:"required"`
+	Data           map[string]interface{} `json:"data"`
+	ProjectID      string                 `json:"project_id,omitempty"`
+	FuelBudget     int                    `json:"fuel_budget,omitempty"`
+	TimeoutSeconds int                    `json:"timeout_seconds,omitempty"`
+}
+
+// handleRunTask sends a task to the instance's agent and waits for the reply
+func (s *Server) handleRunTask(c *gin.Context) {
+	if s.replyClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Task execution is not available"})
+		return
+	}
+
+	claims := c.MustGet("user_claims").(*middleware.AuthClaims)
+	ctx := context.WithValue(c.Request.Context(), "client_id", claims.ClientID)
+
+	var req RunTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: " + err.Error()})
+		return
+	}
+
+	instanceID := c.Param("id")
+	instance, err := s.personaRepo.GetInstanceByID(ctx, instanceID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Instance not found"})
+		return
+	}
+
+	// Project memories are shared by everyone on the project; only the client's active
+	// projects can be read and written
+	project, ok := projectID(c, req.ProjectID)
+	if !ok {
+		return
+	}
+	if project != uuid.Nil {
+		scope := database.MemoryScope{Level: database.ScopeProject, ProjectID: project}
+		if err := s.memoryService.CheckScope(ctx, claims.ClientID, scope); err != nil {
+			s.memoryError(c, err, "Failed to check project")
+			return
+		}
+	}
+
+	timeout := defaultRunTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+		if timeout > maxRunTimeout {
+			timeout = maxRunTimeout
+		}
+	}
+	fuel := req.FuelBudget
+	if fuel <= 0 {
+		fuel = governance.DefaultFuelBudget
+	}
+
+	correlationID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":    correlationID,
+		"client_id":         claims.ClientID,
+		"user_id":           claims.UserID,
+		"agent_instance_id": instance.ID.String(),
+		"timestamp":         time.Now().UTC().Format(time.RFC3339),
+	}
+	if project != uuid.Nil {
+		headers["project_id"] = project.String()
+	}
+	governance.SetFuelHeader(headers, fuel)
+
+	topic, err := s.agentTopicForInstance(ctx, instance)
+	if err != nil {
+		s.logger.Warn("Instance has no runnable agent", zap.String("instance_id", instanceID), zap.Error(err))
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Instance has no runnable agent"})
+		return
+	}
+	payload, _ := json.Marshal(models.TaskRequest{Action: req.Action, Data: req.Data})
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	l := s.logger.With(
+		zap.String("correlation_id", correlationID),
+		zap.String("instance_id", instanceID),
+		zap.String("topic", topic),
+	)
+	l.Info("Running task and awaiting reply", zap.String("action", req.Action), zap.Duration("timeout", timeout))
+
+	reply, err := s.replyClient.Request(runCtx, topic, headers, []byte(correlationID), payload)
+	if err != nil {
+		if errors.Is(err, kafka.ErrRequestTimeout) {
+			c.JSON(http.StatusGatewayTimeout, gin.H{
+				"error":          "Timed out waiting for agent response",
+				"correlation_id": correlationID,
+			})
+			return
+		}
+		l.Error("Task request failed", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send task to agent"})
+		return
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(reply.Value, &response); err != nil {
+		l.Error("Agent reply is not valid JSON", zap.Error(err))
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Invalid response from agent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"correlation_id": correlationID,
+		"response":       response,
+	})
+}
+
+// agentTopicForInstance resolves the topic of the instance's agent from the agent_type
+// of its template. Instance configs are edited by tenants, so they never pick the topic;
+// templates are admin-only, and their agent type must be a known one.
+func (s *Server) agentTopicForInstance(ctx context.Context, instance *models.Persona) (string, error) {
+	template, err := s.personaRepo.GetTemplateByID(ctx, instance.TemplateID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get template of instance: %w", err)
+	}
+	agentType := "generic"
+	if at, ok := template.Config["agent_type"].(string); ok && at != "" {
+		agentType = at
+	}
+	return agentTopic(agentType, s.agentTypes)
+}
+
+// agentTopic returns the request topic of a known agent type
+func agentTopic(agentType string, known map[string]bool) (string, error) {
+	if !known[agentType] {
+		return "", fmt.Errorf("unknown agent type %q", agentType)
+	}
+	topic := fmt.Sprintf(agentTopicFormat, agentType)
+	if !agentTopicPattern.MatchString(topic) {
+		return "", fmt.Errorf("invalid agent type %q", agentType)
+	}
+	return topic, nil
+}
+
+// agentTypesFromConfig reads the agent types tasks may run on from the agent_types list
+// in config, defaulting to DefaultAgentTypes
+func agentTypesFromConfig(custom map[string]interface{}) map[string]bool {
+	known := make(map[string]bool)
+	if types, ok := custom["agent_types"].([]interface{}); ok {
+		for _, t := range types {
+			if agentType, ok := t.(string); ok && agentType != "" {
+				known[agentType] = true
+			}
+		}
+	}
+	if len(known) == 0 {
+		for _, agentType := range DefaultAgentTypes {
+			known[agentType] = true
+		}
+	}
+	return known
+}
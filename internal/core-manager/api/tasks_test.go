@@ -0,0 +1,76 @@
+// FILE: internal/core-manager/api/tasks_test.go
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// templateRepo serves templates by ID
+type templateRepo struct {
+	models.PersonaRepository
+	templates map[string]*models.Persona
+}
+
+func (r templateRepo) GetTemplateByID(ctx context.Context, id string) (*models.Persona, error) {
+	template, ok := r.templates[id]
+	if !ok {
+		return nil, fmt.Errorf("template %s not found", id)
+	}
+	return template, nil
+}
+
+func TestAgentTopicForInstance(t *testing.T) {
+	templates := map[string]*models.Persona{
+		"reasoning": {Config: map[string]interface{}{"agent_type": "reasoning"}},
+		"generic":   {Config: map[string]interface{}{}},
+		"unknown":   {Config: map[string]interface{}{"agent_type": "copywriter"}},
+	}
+	s := &Server{
+		personaRepo: templateRepo{templates: templates},
+		agentTypes:  agentTypesFromConfig(nil),
+	}
+	ctx := context.Background()
+
+	// Tenants edit instance configs; topics in them are ignored
+	instance := &models.Persona{
+		ID:         uuid.New(),
+		TemplateID: "reasoning",
+		Config:     map[string]interface{}{"topic": "system.commands.workflow.resume", "agent_type": "generic"},
+	}
+	topic, err := s.agentTopicForInstance(ctx, instance)
+	require.NoError(t, err)
+	assert.Equal(t, "system.agent.reasoning.process", topic)
+
+	instance.TemplateID = "generic"
+	topic, err = s.agentTopicForInstance(ctx, instance)
+	require.NoError(t, err)
+	assert.Equal(t, "system.agent.generic.process", topic)
+
+	for _, templateID := range []string{"unknown", "missing"} {
+		instance.TemplateID = templateID
+		_, err = s.agentTopicForInstance(ctx, instance)
+		assert.Error(t, err, templateID)
+	}
+}
+
+func TestAgentTopic(t *testing.T) {
+	known := agentTypesFromConfig(map[string]interface{}{"agent_types": []interface{}{"reasoning", "evil.process system", "Upper"}})
+	assert.Equal(t, map[string]bool{"reasoning": true, "evil.process system": true, "Upper": true}, known)
+
+	topic, err := agentTopic("reasoning", known)
+	require.NoError(t, err)
+	assert.Equal(t, "system.agent.reasoning.process", topic)
+
+	// Configured types must still make a well-formed agent topic
+	for _, agentType := range []string{"evil.process system", "Upper", "generic"} {
+		_, err := agentTopic(agentType, known)
+		assert.Error(t, err, agentType)
+	}
+}
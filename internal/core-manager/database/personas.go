@@ -204,15 +204,17 @@ func (r *personaRepository) CreateInstanceFromTemplate(ctx context.Context, temp
 
 	// Create the instance
 	instance := &models.Persona{
-		ID:          uuid.New(),
-		Name:        instanceName,
-		Description: template.Description,
-		Category:    template.Category,
-		Config:      template.Config,
-		IsTemplate:  false,
-		IsActive:    true,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		ID:            uuid.New(),
+		TemplateID:    templateID,
+		Name:          instanceName,
+		Description:   template.Description,
+		Category:      template.Category,
+		Config:        template.Config,
+		IsTemplate:    false,
+		IsActive:      true,
+		ConfigVersion: 1,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
 	}
 
 	configJSON, _ := json.Marshal(instance.Config)
@@ -249,15 +251,17 @@ func (r *personaRepository) GetInstanceByID(ctx context.Context, id string) (*mo
 	var configJSON []byte
 
 	query := fmt.Sprintf(`
-        SELECT id, name, config, is_active, created_at, updated_at
+        SELECT id, template_id::text, name, config, config_version, is_active, created_at, updated_at
         FROM client_%s.agent_instances
         WHERE id = $1 AND is_active = true
     `, clientID)
 
 	err := r.clientsDB.QueryRow(ctx, query, id).Scan(
 		&instance.ID,
+		&instance.TemplateID,
 		&instance.Name,
 		&configJSON,
+		&instance.ConfigVersion,
 		&instance.IsActive,
 		&instance.CreatedAt,
 		&instance.UpdatedAt,
@@ -275,7 +279,7 @@ func (r *personaRepository) ListInstances(ctx context.Context, userID string) ([
 	clientID, _ := ctx.Value("client_id").(string)
 
 	query := fmt.Sprintf(`
-        SELECT id, name, config, is_active, created_at, updated_at
+        SELECT id, name, config, config_version, is_active, created_at, updated_at
         FROM client_%s.agent_instances
         WHERE owner_user_id = $1 AND is_active = true
         ORDER BY created_at DESC
@@ -296,6 +300,7 @@ func (r *personaRepository) ListInstances(ctx context.Context, userID string) ([
 			&instance.ID,
 			&instance.Name,
 			&configJSON,
+			&instance.ConfigVersion,
 			&instance.IsActive,
 			&instance.CreatedAt,
 			&instance.UpdatedAt,
@@ -333,13 +338,16 @@ func (r *personaRepository) UpdateInstance(ctx context.Context, id string, name
 
 	configJSON, _ := json.Marshal(instance.Config)
 
+	// Every config change gets a new version; running workflows keep the version they started with
 	query := fmt.Sprintf(`
         UPDATE client_%s.agent_instances
-        SET name = $2, config = $3, updated_at = $4
+        SET name = $2, config = $3, updated_at = $4,
+            config_version = CASE WHEN $5::boolean THEN config_version + 1 ELSE config_version END
         WHERE id = $1
+        RETURNING config_version
     `, clientID)
 
-	_, err = r.clientsDB.Exec(ctx, query, id, instance.Name, configJSON, time.Now())
+	err = r.clientsDB.QueryRow(ctx, query, id, instance.Name, configJSON, time.Now(), config != nil).Scan(&instance.ConfigVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update instance: %w", err)
 	}
@@ -22,6 +22,29 @@ type MemoryConfiguration struct {
 	RetrievalCount     int      `json:"retrieval_count"`
 	EmbeddingModel     string   `json:"embedding_model"`
 	IncludeTypes       []string `json:"include_types"`
+	MinSimilarity      float64  `json:"min_similarity,omitempty"` // Retrieved memories must be at least this similar
+
+	// Retention. Pinned memories are never removed.
+	RetentionDays     int                 `json:"retention_days,omitempty"`      // Maximum age; 0 keeps memories indefinitely
+	TypeRetentionDays map[string]int      `json:"type_retention_days,omitempty"` // Maximum age by memory type, overriding RetentionDays; 0 keeps the type
+	Consolidation     MemoryConsolidation `json:"consolidation,omitempty"`
+
+	Scopes MemoryScopes `json:"scopes,omitempty"`
+}
+
+// MemoryScopes controls which memories an instance shares and sees. Project memories are
+// shared by every instance working on the same project, client memories by every
+// instance of the client.
+type MemoryScopes struct {
+	Store   string             `json:"store,omitempty"`   // Scope of stored memories: instance (default), project or client
+	Weights map[string]float64 `json:"weights,omitempty"` // Scopes retrieved, with their score multipliers; default {"instance": 1}
+}
+
+// MemoryConsolidation controls how clusters of similar memories are merged into summaries
+type MemoryConsolidation struct {
+	Enabled             bool    `json:"enabled"`
+	SimilarityThreshold float64 `json:"similarity_threshold,omitempty"` // Cosine similarity to join a cluster
+	MinClusterSize      int     `json:"min_cluster_size,omitempty"`     // Smallest cluster worth summarising
 }
 
 // MemoryEntry represents a single memory to be stored
@@ -30,6 +53,7 @@ type MemoryEntry struct {
 	Type      string                 `json:"type"`
 	Metadata  map[string]interface{} `json:"metadata"`
 	Timestamp time.Time              `json:"timestamp"`
+	Scope     string                 `json:"scope,omitempty"` // Set on retrieved memories
 }
 
 // WorkflowPlan defines the orchestration steps for an agent
@@ -47,6 +71,10 @@ type Step struct {
 	NextStep     string    `json:"next_step,omitempty"`
 	SubTasks     []SubTask `json:"sub_tasks,omitempty"`
 	StoreMemory  bool      `json:"store_memory,omitempty"` // New field
+
+	// PromptTemplate references a prompt template as "<id>" or "<id>@<version>".
+	// It overrides the persona's core_logic prompt_template for this step.
+	PromptTemplate string `json:"prompt_template,omitempty"`
 }
 
 // SubTask for fan-out operations
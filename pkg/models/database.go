@@ -9,15 +9,17 @@ import (
 
 // Persona represents both templates and instances
 type Persona struct {
-	ID          uuid.UUID              `json:"id"`
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	Category    string                 `json:"category"`
-	Config      map[string]interface{} `json:"config"`
-	IsTemplate  bool                   `json:"is_template"`
-	IsActive    bool                   `json:"is_active"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	ID            uuid.UUID              `json:"id"`
+	TemplateID    string                 `json:"template_id,omitempty"` // Template an instance was created from
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description"`
+	Category      string                 `json:"category"`
+	Config        map[string]interface{} `json:"config"`
+	ConfigVersion int                    `json:"config_version,omitempty"`
+	IsTemplate    bool                   `json:"is_template"`
+	IsActive      bool                   `json:"is_active"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
 }
 
 // PersonaRepository defines the interface for persona data access
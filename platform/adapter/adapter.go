@@ -0,0 +1,429 @@
+// FILE: platform/adapter/adapter.go
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/platform/config"
+	"github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/health"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/observability"
+	"github.com/gqls/agentchassis/platform/resilience"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHandlerTimeout = 2 * time.Minute
+	defaultRetryAfter     = 30 * time.Second
+	defaultMaxAttempts    = 3
+	defaultRetryBackoff   = 1 * time.Second
+)
+
+// Handler implements the service-specific part of an adapter
+type Handler interface {
+	Handle(ctx context.Context, req *Request) (interface{}, error)
+}
+
+// HandlerFunc adapts a plain function to the Handler interface
+type HandlerFunc func(ctx context.Context, req *Request) (interface{}, error)
+
+// Handle calls f(ctx, req)
+func (f HandlerFunc) Handle(ctx context.Context, req *Request) (interface{}, error) {
+	return f(ctx, req)
+}
+
+// Request is a single task handed to an adapter
+type Request struct {
+	Action  string
+	Data    json.RawMessage
+	Headers map[string]string
+}
+
+// Bind decodes the request data into v
+func (r *Request) Bind(v interface{}) error {
+	if len(r.Data) == 0 {
+		return errors.ValidationError("data", "request data is required")
+	}
+	if err := json.Unmarshal(r.Data, v); err != nil {
+		return errors.ValidationError("data", err.Error())
+	}
+	return nil
+}
+
+// Config describes how an adapter is wired to Kafka
+type Config struct {
+	Name          string // Used for metrics, logs and the default DLQ topic
+	RequestTopic  string
+	ResponseTopic string
+	ConsumerGroup string
+	DLQTopic      string // Defaults to dlq.<Name>
+
+	HealthPort     string // Defaults to 8080
+	MetricsPort    string // Defaults to 9090
+	HandlerTimeout time.Duration
+
+	// Retryable failures are retried up to MaxAttempts (defaults to 3), waiting
+	// RetryBackoff (defaults to 1s) before the first retry and doubling after that
+	MaxAttempts  int
+	RetryBackoff time.Duration
+
+	// Breaker overrides the default circuit breaker settings
+	Breaker *resilience.CircuitBreakerConfig
+}
+
+// messageSource is the subset of the consumer the adapter needs
+type messageSource interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// Adapter runs the consume loop for an external-service adapter.
+// It owns message decoding, the circuit breaker, metrics, tracing, responses,
+// dead-lettering, health endpoints and graceful shutdown.
+type Adapter struct {
+	ctx      context.Context
+	cfg      Config
+	handler  Handler
+	consumer messageSource
+	producer kafka.Producer
+	breaker  *resilience.CircuitBreaker
+	health   *health.Server
+	logger   *zap.Logger
+	inFlight sync.WaitGroup
+}
+
+// New creates an adapter with its own Kafka consumer and producer
+func New(ctx context.Context, serviceCfg *config.ServiceConfig, cfg Config, handler Handler, logger *zap.Logger) (*Adapter, error) {
+	consumer, err := kafka.NewConsumer(serviceCfg.Infrastructure.KafkaBrokers, cfg.RequestTopic, cfg.ConsumerGroup, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer: %w", err)
+	}
+
+	producer, err := kafka.NewProducer(serviceCfg.Infrastructure.KafkaBrokers, logger)
+	if err != nil {
+		consumer.Close()
+		return nil, fmt.Errorf("failed to create producer: %w", err)
+	}
+
+	return newAdapter(ctx, cfg, handler, consumer, producer, logger), nil
+}
+
+func newAdapter(ctx context.Context, cfg Config, handler Handler, consumer messageSource, producer kafka.Producer, logger *zap.Logger) *Adapter {
+	if cfg.DLQTopic == "" {
+		cfg.DLQTopic = "dlq." + cfg.Name
+	}
+	if cfg.HealthPort == "" {
+		cfg.HealthPort = "8080"
+	}
+	if cfg.MetricsPort == "" {
+		cfg.MetricsPort = "9090"
+	}
+	if cfg.HandlerTimeout == 0 {
+		cfg.HandlerTimeout = defaultHandlerTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultMaxAttempts
+	}
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+
+	breakerCfg := resilience.DefaultCircuitBreakerConfig(cfg.Name)
+	if cfg.Breaker != nil {
+		breakerCfg = *cfg.Breaker
+	}
+	// Bad input says nothing about the health of the external service
+	breakerCfg.IsSuccessful = func(err error) bool {
+		return err == nil || isValidationError(err)
+	}
+
+	a := &Adapter{
+		ctx:      ctx,
+		cfg:      cfg,
+		handler:  handler,
+		consumer: consumer,
+		producer: producer,
+		breaker:  resilience.NewCircuitBreaker(breakerCfg, logger),
+		logger:   logger.With(zap.String("adapter", cfg.Name)),
+	}
+
+	a.health = health.NewServer(cfg.Name,
+		health.Config{HealthPort: cfg.HealthPort, MetricsPort: cfg.MetricsPort},
+		health.Checkers{
+			"circuit_breaker": func(ctx context.Context) error {
+				if a.breaker.IsOpen() {
+					return fmt.Errorf("circuit breaker is open")
+				}
+				return nil
+			},
+		},
+		logger,
+	)
+
+	return a
+}
+
+// Run starts the health server and consumes requests until the context is cancelled.
+// In-flight requests are allowed to finish before the Kafka clients are closed.
+func (a *Adapter) Run() error {
+	a.logger.Info("Adapter running",
+		zap.String("request_topic", a.cfg.RequestTopic),
+		zap.String("response_topic", a.cfg.ResponseTopic))
+
+	a.health.Start()
+	observability.AgentPoolSize.WithLabelValues(a.cfg.Name).Inc()
+	defer observability.AgentPoolSize.WithLabelValues(a.cfg.Name).Dec()
+
+	for {
+		msg, err := a.consumer.FetchMessage(a.ctx)
+		if err != nil {
+			if a.ctx.Err() != nil {
+				return a.shutdown()
+			}
+			a.logger.Error("Failed to fetch message", zap.Error(err))
+			observability.SystemErrors.WithLabelValues(a.cfg.Name, "fetch_message").Inc()
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		observability.KafkaMessagesConsumed.WithLabelValues(msg.Topic, a.cfg.ConsumerGroup).Inc()
+
+		a.inFlight.Add(1)
+		go func(msg kafka.Message) {
+			defer a.inFlight.Done()
+			a.handleMessage(msg)
+		}(msg)
+	}
+}
+
+// shutdown waits for in-flight requests and releases the Kafka clients
+func (a *Adapter) shutdown() error {
+	a.logger.Info("Adapter shutting down, waiting for in-flight requests")
+	a.inFlight.Wait()
+
+	var firstErr error
+	if err := a.consumer.Close(); err != nil {
+		firstErr = err
+	}
+	if err := a.producer.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	a.logger.Info("Adapter stopped")
+	return firstErr
+}
+
+// handleMessage runs one request through the handler and always commits it
+func (a *Adapter) handleMessage(msg kafka.Message) {
+	startTime := time.Now()
+	headers := kafka.HeadersToMap(msg.Headers)
+
+	// In-flight work is not cut short by shutdown, only by its own timeout
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.HandlerTimeout)
+	defer cancel()
+
+	ctx, span := observability.StartSpan(ctx, "adapter."+a.cfg.Name)
+	defer span.End()
+
+	l := a.logger.With(
+		zap.String("correlation_id", headers["correlation_id"]),
+		zap.String("request_id", headers["request_id"]),
+	)
+
+	defer func() {
+		if err := a.consumer.CommitMessages(context.Background(), msg); err != nil {
+			l.Error("Failed to commit message", zap.Error(err))
+			observability.SystemErrors.WithLabelValues(a.cfg.Name, "commit_message").Inc()
+		}
+	}()
+
+	req, err := decodeRequest(msg.Value, headers)
+	if err != nil {
+		l.Warn("Invalid request payload", zap.Error(err))
+		a.fail(ctx, l, msg, headers, "", err)
+		return
+	}
+
+	observability.AddSpanAttributes(ctx,
+		attribute.String("correlation_id", headers["correlation_id"]),
+		attribute.String("action", req.Action))
+	observability.AgentTasksReceived.WithLabelValues(a.cfg.Name, req.Action).Inc()
+	defer func() {
+		observability.AgentProcessingDuration.WithLabelValues(a.cfg.Name, req.Action).
+			Observe(time.Since(startTime).Seconds())
+	}()
+
+	result, err := a.executeWithRetries(ctx, l, req)
+	if err != nil {
+		l.Error("Adapter request failed", zap.String("action", req.Action), zap.Error(err))
+		observability.RecordError(ctx, err)
+		a.fail(ctx, l, msg, headers, req.Action, err)
+		return
+	}
+
+	a.respond(ctx, l, headers, map[string]interface{}{
+		"success": true,
+		"data":    result,
+	})
+	observability.AgentTasksProcessed.WithLabelValues(a.cfg.Name, req.Action, "success").Inc()
+}
+
+// executeWithRetries runs the handler until it succeeds, fails for good, runs out of
+// attempts or the request times out
+func (a *Adapter) executeWithRetries(ctx context.Context, l *zap.Logger, req *Request) (interface{}, error) {
+	backoff := a.cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		result, err := a.execute(ctx, req)
+		if err == nil || attempt >= a.cfg.MaxAttempts || !a.toDomainError(err).Retryable {
+			return result, err
+		}
+
+		l.Warn("Adapter request failed, retrying",
+			zap.String("action", req.Action),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", backoff),
+			zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// execute runs the handler through the circuit breaker and records breaker metrics
+func (a *Adapter) execute(ctx context.Context, req *Request) (interface{}, error) {
+	wasOpen := a.breaker.IsOpen()
+
+	result, err := a.breaker.ExecuteWithContext(ctx, func(ctx context.Context) (interface{}, error) {
+		return a.handler.Handle(ctx, req)
+	})
+
+	isOpen := a.breaker.IsOpen()
+	if isOpen && !wasOpen {
+		observability.CircuitBreakerTrips.WithLabelValues(a.cfg.Name).Inc()
+	}
+	observability.CircuitBreakerState.WithLabelValues(a.cfg.Name).Set(breakerStateValue(a.breaker.State()))
+
+	return result, err
+}
+
+// fail sends an error response and dead-letters the request. Retryable failures have
+// already used up their attempts, so they are dead-lettered too rather than lost.
+func (a *Adapter) fail(ctx context.Context, l *zap.Logger, msg kafka.Message, headers map[string]string, action string, err error) {
+	domainErr := a.toDomainError(err)
+	domainErr.TraceID = headers["correlation_id"]
+
+	a.respond(ctx, l, headers, map[string]interface{}{
+		"success": false,
+		"error":   domainErr,
+		"agent":   a.cfg.Name,
+	})
+	observability.AgentTasksProcessed.WithLabelValues(a.cfg.Name, action, string(domainErr.Code)).Inc()
+
+	a.deadLetter(ctx, l, msg, domainErr)
+}
+
+// toDomainError maps handler and breaker errors onto the platform error model
+func (a *Adapter) toDomainError(err error) *errors.DomainError {
+	if domainErr, ok := err.(*errors.DomainError); ok {
+		return domainErr
+	}
+	if resilience.IsCircuitBreakerError(err) {
+		retryAfter := defaultRetryAfter
+		return errors.New(errors.ErrExternalService, fmt.Sprintf("%s is temporarily unavailable", a.cfg.Name)).
+			WithCause(err).
+			AsRetryable(&retryAfter).
+			Build()
+	}
+	return errors.New(errors.ErrExternalService, fmt.Sprintf("%s request failed", a.cfg.Name)).
+		WithCause(err).
+		Build()
+}
+
+// respond publishes to the caller's reply topic if it asked for one, otherwise the response topic
+func (a *Adapter) respond(ctx context.Context, l *zap.Logger, headers map[string]string, payload map[string]interface{}) {
+	topic := a.cfg.ResponseTopic
+	if replyTopic := headers[kafka.ReplyTopicHeader]; replyTopic != "" {
+		topic = replyTopic
+	}
+
+	responseBytes, _ := json.Marshal(payload)
+	if err := a.producer.Produce(ctx, topic, responseHeaders(headers, a.cfg.Name),
+		[]byte(headers["correlation_id"]), responseBytes); err != nil {
+		l.Error("Failed to produce response", zap.String("topic", topic), zap.Error(err))
+		observability.SystemErrors.WithLabelValues(a.cfg.Name, "produce_response").Inc()
+		return
+	}
+	observability.KafkaMessagesProduced.WithLabelValues(topic).Inc()
+}
+
+// deadLetter copies the original message to the DLQ with the failure reason attached
+func (a *Adapter) deadLetter(ctx context.Context, l *zap.Logger, msg kafka.Message, domainErr *errors.DomainError) {
+	dlqHeaders := kafka.HeadersToMap(msg.Headers)
+	dlqHeaders["dlq_reason"] = domainErr.Error()
+	dlqHeaders["dlq_error_code"] = string(domainErr.Code)
+	dlqHeaders["dlq_source_topic"] = msg.Topic
+	dlqHeaders["dlq_adapter"] = a.cfg.Name
+	dlqHeaders["dlq_timestamp"] = time.Now().UTC().Format(time.RFC3339)
+
+	if err := a.producer.Produce(ctx, a.cfg.DLQTopic, dlqHeaders, msg.Key, msg.Value); err != nil {
+		l.Error("Failed to dead-letter message", zap.String("topic", a.cfg.DLQTopic), zap.Error(err))
+		observability.SystemErrors.WithLabelValues(a.cfg.Name, "produce_dlq").Inc()
+		return
+	}
+	observability.KafkaMessagesProduced.WithLabelValues(a.cfg.DLQTopic).Inc()
+}
+
+// decodeRequest parses the standard {action, data} envelope
+func decodeRequest(value []byte, headers map[string]string) (*Request, error) {
+	var envelope struct {
+		Action string          `json:"action"`
+		Data   json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(value, &envelope); err != nil {
+		return nil, errors.ValidationError("payload", "invalid JSON")
+	}
+	return &Request{
+		Action:  envelope.Action,
+		Data:    envelope.Data,
+		Headers: headers,
+	}, nil
+}
+
+// responseHeaders creates response headers with proper causality tracking
+func responseHeaders(headers map[string]string, name string) map[string]string {
+	return map[string]string{
+		"correlation_id": headers["correlation_id"],
+		"causation_id":   headers["request_id"],
+		"request_id":     uuid.NewString(),
+		"client_id":      headers["client_id"],
+		"agent_type":     name,
+		"timestamp":      time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+func isValidationError(err error) bool {
+	domainErr, ok := err.(*errors.DomainError)
+	return ok && domainErr.Code == errors.ErrValidation
+}
+
+// breakerStateValue maps breaker states onto the CircuitBreakerState gauge
+func breakerStateValue(state string) float64 {
+	switch state {
+	case "open":
+		return 1
+	case "half-open":
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,205 @@
+// FILE: platform/adapter/adapter_test.go
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// MockConsumer records commits so tests can assert every message is committed.
+type MockConsumer struct {
+	mock.Mock
+}
+
+func (m *MockConsumer) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(kafka.Message), args.Error(1)
+}
+
+func (m *MockConsumer) CommitMessages(ctx context.Context, msgs ...kafka.Message) error {
+	args := m.Called(ctx, msgs)
+	return args.Error(0)
+}
+
+func (m *MockConsumer) Close() error {
+	return nil
+}
+
+func setupAdapter(t *testing.T, handler Handler) (*Adapter, *MockConsumer, *kafka.MockProducer) {
+	consumer := new(MockConsumer)
+	producer := new(kafka.MockProducer)
+	consumer.On("CommitMessages", mock.Anything, mock.Anything).Return(nil).Once()
+
+	a := newAdapter(context.Background(), Config{
+		Name:          "test-adapter",
+		RequestTopic:  "system.adapter.test",
+		ResponseTopic: "system.responses.test",
+		ConsumerGroup: "test-group",
+		RetryBackoff:  time.Millisecond,
+	}, handler, consumer, producer, zap.NewNop())
+	return a, consumer, producer
+}
+
+func testMessage(value string) kafka.Message {
+	return kafka.Message{
+		Topic: "system.adapter.test",
+		Value: []byte(value),
+		Headers: []kafka.Header{
+			{Key: "correlation_id", Value: []byte("corr-1")},
+			{Key: "request_id", Value: []byte("req-1")},
+		},
+	}
+}
+
+func decodeEnvelope(t *testing.T, args mock.Arguments) map[string]interface{} {
+	var envelope map[string]interface{}
+	require.NoError(t, json.Unmarshal(args.Get(4).([]byte), &envelope))
+	return envelope
+}
+
+func TestAdapter_Success(t *testing.T) {
+	handler := HandlerFunc(func(ctx context.Context, req *Request) (interface{}, error) {
+		var data struct {
+			Query string `json:"query"`
+		}
+		if err := req.Bind(&data); err != nil {
+			return nil, err
+		}
+		return map[string]string{"echo": data.Query}, nil
+	})
+	a, consumer, producer := setupAdapter(t, handler)
+
+	producer.On("Produce", mock.Anything, "system.responses.test", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			headers := args.Get(2).(map[string]string)
+			assert.Equal(t, "req-1", headers["causation_id"])
+
+			envelope := decodeEnvelope(t, args)
+			assert.Equal(t, true, envelope["success"])
+			assert.Equal(t, map[string]interface{}{"echo": "hello"}, envelope["data"])
+		}).Return(nil).Once()
+
+	a.handleMessage(testMessage(`{"action":"search","data":{"query":"hello"}}`))
+
+	producer.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
+
+func TestAdapter_ReplyTopicOverridesResponseTopic(t *testing.T) {
+	handler := HandlerFunc(func(ctx context.Context, req *Request) (interface{}, error) {
+		return "ok", nil
+	})
+	a, consumer, producer := setupAdapter(t, handler)
+
+	producer.On("Produce", mock.Anything, "caller.replies", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	msg := testMessage(`{"action":"search","data":{}}`)
+	msg.Headers = append(msg.Headers, kafka.Header{Key: kafka.ReplyTopicHeader, Value: []byte("caller.replies")})
+	a.handleMessage(msg)
+
+	producer.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
+
+func TestAdapter_FailureIsDeadLettered(t *testing.T) {
+	handler := HandlerFunc(func(ctx context.Context, req *Request) (interface{}, error) {
+		return nil, fmt.Errorf("upstream returned 500")
+	})
+	a, consumer, producer := setupAdapter(t, handler)
+
+	producer.On("Produce", mock.Anything, "system.responses.test", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			envelope := decodeEnvelope(t, args)
+			assert.Equal(t, false, envelope["success"])
+			assert.Equal(t, "test-adapter", envelope["agent"])
+			errObj := envelope["error"].(map[string]interface{})
+			assert.Equal(t, string(errors.ErrExternalService), errObj["code"])
+			assert.Equal(t, "corr-1", errObj["trace_id"])
+		}).Return(nil).Once()
+	producer.On("Produce", mock.Anything, "dlq.test-adapter", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			headers := args.Get(2).(map[string]string)
+			assert.Equal(t, "system.adapter.test", headers["dlq_source_topic"])
+			assert.Equal(t, "corr-1", headers["correlation_id"])
+		}).Return(nil).Once()
+
+	a.handleMessage(testMessage(`{"action":"search","data":{}}`))
+
+	producer.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
+
+func TestAdapter_InvalidPayload(t *testing.T) {
+	handler := HandlerFunc(func(ctx context.Context, req *Request) (interface{}, error) {
+		t.Fatal("handler should not be called for an invalid payload")
+		return nil, nil
+	})
+	a, consumer, producer := setupAdapter(t, handler)
+
+	producer.On("Produce", mock.Anything, "system.responses.test", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			errObj := decodeEnvelope(t, args)["error"].(map[string]interface{})
+			assert.Equal(t, string(errors.ErrValidation), errObj["code"])
+		}).Return(nil).Once()
+	producer.On("Produce", mock.Anything, "dlq.test-adapter", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	a.handleMessage(testMessage(`not json`))
+
+	producer.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
+
+func TestAdapter_RetryableFailureIsRetriedThenDeadLettered(t *testing.T) {
+	attempts := 0
+	handler := HandlerFunc(func(ctx context.Context, req *Request) (interface{}, error) {
+		attempts++
+		return nil, errors.New(errors.ErrExternalService, "upstream rate limited").AsRetryable(nil).Build()
+	})
+	a, consumer, producer := setupAdapter(t, handler)
+
+	producer.On("Produce", mock.Anything, "system.responses.test", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			errObj := decodeEnvelope(t, args)["error"].(map[string]interface{})
+			assert.Equal(t, true, errObj["retryable"])
+		}).Return(nil).Once()
+	producer.On("Produce", mock.Anything, "dlq.test-adapter", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	a.handleMessage(testMessage(`{"action":"search","data":{}}`))
+
+	assert.Equal(t, defaultMaxAttempts, attempts)
+	producer.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
+
+func TestAdapter_RetryableFailureRecovers(t *testing.T) {
+	attempts := 0
+	handler := HandlerFunc(func(ctx context.Context, req *Request) (interface{}, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New(errors.ErrExternalService, "upstream rate limited").AsRetryable(nil).Build()
+		}
+		return "ok", nil
+	})
+	a, consumer, producer := setupAdapter(t, handler)
+
+	producer.On("Produce", mock.Anything, "system.responses.test", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			assert.Equal(t, true, decodeEnvelope(t, args)["success"])
+		}).Return(nil).Once()
+
+	a.handleMessage(testMessage(`{"action":"search","data":{}}`))
+
+	assert.Equal(t, 2, attempts)
+	producer.AssertExpectations(t)
+	consumer.AssertExpectations(t)
+}
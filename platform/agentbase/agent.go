@@ -4,13 +4,23 @@ package agentbase
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
 	"github.com/gqls/agentchassis/platform/config"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/governance"
 	"github.com/gqls/agentchassis/platform/health"
 	"github.com/gqls/agentchassis/platform/infrastructure"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/memory"
 	"github.com/gqls/agentchassis/platform/messaging"
 	"github.com/gqls/agentchassis/platform/observability"
 	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/gqls/agentchassis/platform/prompts"
+	"github.com/gqls/agentchassis/platform/storage"
 	"github.com/gqls/agentchassis/platform/validation"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/stdlib"
 	"go.uber.org/zap"
 )
@@ -27,8 +37,22 @@ type Agent struct {
 	infraManager  *infrastructure.Manager
 	messageRunner *MessageRunner
 	healthServer  *health.Server
+	handlers      *HandlerRegistry
+	orchestrator  *orchestration.SagaCoordinator
+	configCache   *config.AgentConfigCache
+	toolClient    *kafka.RequestReplyClient
+	stepListener  *StepListener
+	promptLibrary prompts.Library
+	imageFetcher  aiservice.ImageFetcher
 }
 
+const (
+	// promptCacheTTL is how long agents reuse the latest version of a prompt template
+	promptCacheTTL = time.Minute
+	// responseCachePurgeInterval is how often expired cached AI responses are deleted
+	responseCachePurgeInterval = time.Hour
+)
+
 // New creates a new agent with defaults from config
 func New(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Logger) (*Agent, error) {
 	agentType := "generic"
@@ -65,12 +89,59 @@ func NewWithType(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Log
 	connections := infraManager.GetConnections()
 
 	// Create components
-	components, err := createComponents(connections, agentType, logger)
+	handlers := NewHandlerRegistry()
+	components, err := createComponents(connections, agentType, handlers, logger)
 	if err != nil {
 		infraManager.Close()
 		return nil, fmt.Errorf("failed to create components: %w", err)
 	}
 
+	// Prompt templates live in the templates database, when the agent is connected to it
+	var promptLibrary prompts.Library
+	if connections.TemplatesDB != nil {
+		promptLibrary = prompts.NewCache(prompts.NewStore(connections.TemplatesDB, logger), promptCacheTTL)
+		components.orchestrator.SetPromptLibrary(promptLibrary)
+	}
+
+	// Images referenced by s3:// URI are loaded from object storage, when the agent has it
+	var imageFetcher aiservice.ImageFetcher
+	if cfg.Infrastructure.ObjectStorage.Bucket != "" {
+		storageClient, err := storage.NewS3Client(ctx, cfg.Infrastructure.ObjectStorage)
+		if err != nil {
+			logger.Warn("Object storage unavailable, s3:// images cannot be sent to models", zap.Error(err))
+		} else {
+			imageFetcher = storage.NewImageFetcher(storageClient, cfg.Infrastructure.ObjectStorage.Bucket, aiservice.MaxImageBytes)
+		}
+	}
+
+	// Workflows use long-term memory when the agent has an embedding provider:
+	// an embedding section, or a text provider with an embedding API
+	if aiConfig, ok := cfg.Custom["ai_service"].(map[string]interface{}); ok {
+		embedder, err := aiservice.NewEmbeddingFromConfig(ctx, aiConfig)
+		if err != nil {
+			logger.Warn("Embeddings unavailable, workflows run without memory", zap.Error(err))
+		} else {
+			components.orchestrator.SetMemoryStore(memory.NewService(connections.ClientsDB, embedder, logger))
+		}
+	}
+
+	// Register built-in handlers
+	toolClient, err := registerBuiltinHandlers(ctx, cfg, connections, agentType, handlers, promptLibrary, imageFetcher, logger)
+	if err != nil {
+		infraManager.Close()
+		return nil, fmt.Errorf("failed to register built-in handlers: %w", err)
+	}
+
+	// Workflows wait for remote steps on the step reply topic; without it they cannot use remote steps
+	stepReplyTopic := StepReplyTopic(agentType)
+	if t, ok := cfg.Custom["step_reply_topic"].(string); ok && t != "" {
+		stepReplyTopic = t
+	}
+	stepListener, err := NewStepListener(ctx, cfg.Infrastructure.KafkaBrokers, stepReplyTopic, agentType, components.orchestrator, logger)
+	if err != nil {
+		logger.Warn("Remote workflow steps unavailable", zap.Error(err))
+	}
+
 	// Create message runner
 	messageRunner := NewMessageRunner(
 		ctx,
@@ -96,9 +167,154 @@ func NewWithType(ctx context.Context, cfg *config.ServiceConfig, logger *zap.Log
 		infraManager:  infraManager,
 		messageRunner: messageRunner,
 		healthServer:  healthServer,
+		handlers:      handlers,
+		orchestrator:  components.orchestrator,
+		configCache:   components.messageProcessor.ConfigCache(),
+		toolClient:    toolClient,
+		stepListener:  stepListener,
+		promptLibrary: promptLibrary,
+		imageFetcher:  imageFetcher,
 	}, nil
 }
 
+// RegisterHandler registers an in-process handler for a workflow action.
+// Steps with a registered action run locally instead of being sent to their topic.
+func (a *Agent) RegisterHandler(action string, handler orchestration.ActionHandler) error {
+	if err := a.handlers.Register(action, handler); err != nil {
+		return err
+	}
+	a.logger.Info("Action handler registered", zap.String("action", action))
+	return nil
+}
+
+// SetResponseTopic sets where workflow outcomes are published when the request
+// did not carry a reply_topic header
+func (a *Agent) SetResponseTopic(topic string) {
+	a.orchestrator.SetResponseTopic(topic)
+}
+
+// PromptLibrary returns the agent's prompt templates, or nil without a templates database
+func (a *Agent) PromptLibrary() prompts.Library {
+	return a.promptLibrary
+}
+
+// NewAIClient creates a client from an ai_service config section, with response caching
+// when the section sets cache_store
+func (a *Agent) NewAIClient(ctx context.Context, aiConfig map[string]interface{}) (aiservice.AIService, error) {
+	return newAIClient(ctx, aiConfig, a.infraManager.GetConnections().ClientsDB, a.imageFetcher, a.logger)
+}
+
+// newAIClient creates the AI client for an ai_service config section. Image URIs are
+// loaded through images, which may be nil. cache_store "memory" or "postgres" caches
+// responses for personas that opt in with response_cache.
+func newAIClient(ctx context.Context, aiConfig map[string]interface{}, clientsDB *pgxpool.Pool, images aiservice.ImageFetcher, logger *zap.Logger) (aiservice.AIService, error) {
+	provider, err := aiservice.NewFromConfig(ctx, aiConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+	var client aiservice.AIService = aiservice.NewImageResolvingAI(provider, images)
+
+	var cache aiservice.ResponseCache
+	switch store, _ := aiConfig["cache_store"].(string); store {
+	case "":
+		return client, nil
+	case "memory":
+		var maxEntries int
+		switch n := aiConfig["cache_max_entries"].(type) {
+		case int:
+			maxEntries = n
+		case float64:
+			maxEntries = int(n)
+		}
+		cache = aiservice.NewMemoryCache(maxEntries)
+	case "postgres":
+		if clientsDB == nil {
+			return nil, fmt.Errorf("cache_store postgres requires the clients database")
+		}
+		repo := database.NewResponseCacheRepository(clientsDB, logger)
+		go purgeResponseCache(ctx, repo, logger)
+		cache = repo
+	default:
+		return nil, fmt.Errorf("unknown cache_store '%s'", store)
+	}
+
+	// Semantic matching embeds prompts with the embedding provider; without one only
+	// exact matches are served
+	embedder, err := aiservice.NewEmbeddingFromConfig(ctx, aiConfig)
+	if err != nil {
+		logger.Warn("Semantic response caching unavailable", zap.Error(err))
+	}
+	logger.Info("AI response cache enabled", zap.Any("store", aiConfig["cache_store"]))
+	return aiservice.NewCachingAI(client, cache, embedder, logger), nil
+}
+
+// purgeResponseCache deletes expired cached responses until ctx is done
+func purgeResponseCache(ctx context.Context, repo *database.ResponseCacheRepository, logger *zap.Logger) {
+	ticker := time.NewTicker(responseCachePurgeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := repo.PurgeExpired(ctx)
+			if err != nil {
+				logger.Warn("Failed to purge response cache", zap.Error(err))
+			} else if purged > 0 {
+				logger.Debug("Purged expired cached responses", zap.Int64("count", purged))
+			}
+		}
+	}
+}
+
+// Handlers returns the agent's action handler registry
+func (a *Agent) Handlers() *HandlerRegistry {
+	return a.handlers
+}
+
+// registerBuiltinHandlers registers the handlers the chassis provides out of the box.
+// It returns the request/reply client used for tool calls, if one was started.
+func registerBuiltinHandlers(ctx context.Context, cfg *config.ServiceConfig, connections *infrastructure.Connections, agentType string, handlers *HandlerRegistry, promptLibrary prompts.Library, images aiservice.ImageFetcher, logger *zap.Logger) (*kafka.RequestReplyClient, error) {
+	if cfg.Custom == nil {
+		return nil, nil
+	}
+
+	aiConfig, ok := cfg.Custom["ai_service"].(map[string]interface{})
+	if !ok {
+		logger.Info("No ai_service configured, ai_text_generate steps need a topic")
+		return nil, nil
+	}
+
+	aiClient, err := newAIClient(ctx, aiConfig, connections.ClientsDB, images, logger)
+	if err != nil {
+		return nil, err
+	}
+	ai := NewMeteredAI(aiClient, governance.NewTokenFuelPolicy(governance.DefaultTokenRates), aiservice.OptionsFromMap(aiConfig), agentType)
+
+	// Tool calls reach the adapters over Kafka; without a reply consumer personas cannot use tools
+	replyTopic := DefaultToolReplyTopic
+	if t, ok := cfg.Custom["tool_reply_topic"].(string); ok && t != "" {
+		replyTopic = t
+	}
+	var toolLoop *ToolLoop
+	toolClient, err := kafka.NewRequestReplyClient(cfg.Infrastructure.KafkaBrokers, connections.KafkaProducer, replyTopic, agentType+"-tools", logger)
+	if err != nil {
+		logger.Warn("Tool calling unavailable", zap.Error(err))
+	} else {
+		toolClient.Start(ctx)
+		timeouts, _ := cfg.Custom["tool_timeouts"].(map[string]interface{})
+		toolLoop = NewToolLoop(ai, toolClient, WithToolTimeouts(DefaultTools, timeouts), logger)
+	}
+
+	if err := handlers.Register(ActionAITextGenerate, NewAITextGenerateHandler(ai, toolLoop, promptLibrary)); err != nil {
+		if toolClient != nil {
+			toolClient.Close()
+		}
+		return nil, err
+	}
+	return toolClient, nil
+}
+
 // Components holds the processing components
 type Components struct {
 	messageProcessor *messaging.MessageProcessor
@@ -106,14 +322,16 @@ type Components struct {
 	validator        *validation.WorkflowValidator
 }
 
-func createComponents(connections *infrastructure.Connections, agentType string, logger *zap.Logger) (*Components, error) {
+func createComponents(connections *infrastructure.Connections, agentType string, handlers *HandlerRegistry, logger *zap.Logger) (*Components, error) {
 	// Create orchestrator
 	connConfig := connections.ClientsDB.Config().ConnConfig.Copy()
 	stdDB := stdlib.OpenDB(*connConfig)
 	orchestrator := orchestration.NewSagaCoordinator(stdDB, connections.KafkaProducer, logger)
+	orchestrator.SetActionExecutor(handlers)
 
 	// Create validator
 	validator := validation.NewWorkflowValidator()
+	validator.SetLocalActionResolver(handlers.Has)
 
 	// Create message processor
 	messageProcessor := messaging.NewMessageProcessor(
@@ -159,6 +377,17 @@ func (a *Agent) Run() error {
 	// Start health server
 	a.healthServer.Start()
 
+	// Keep cached configs fresh; without the listener entries still expire on their TTL
+	listener, err := NewConfigListener(a.ctx, a.cfg.Infrastructure.KafkaBrokers, a.agentType, a.configCache, a.logger)
+	if err != nil {
+		a.logger.Warn("Config change listener unavailable, relying on cache TTL", zap.Error(err))
+	} else {
+		go listener.Run()
+	}
+	if a.stepListener != nil {
+		go a.stepListener.Run()
+	}
+
 	// Run message processing
 	return a.messageRunner.Run()
 }
@@ -167,5 +396,8 @@ func (a *Agent) Run() error {
 func (a *Agent) Shutdown() error {
 	a.logger.Info("Agent shutting down")
 	observability.AgentPoolSize.WithLabelValues(a.agentType).Dec()
+	if a.toolClient != nil {
+		a.toolClient.Close()
+	}
 	return a.infraManager.Close()
 }
@@ -0,0 +1,333 @@
+// FILE: platform/agentbase/builtin_handlers.go
+package agentbase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/gqls/agentchassis/platform/prompts"
+)
+
+// ActionAITextGenerate is the action used by the default workflow
+const ActionAITextGenerate = "ai_text_generate"
+
+// NewAITextGenerateHandler creates a handler that prompts the AI service with the task data.
+// The system prompt and generation options come from the agent's core logic; anything
+// the persona does not set falls back to the ai_service config. Output is streamed so
+// partial text reaches the UI while it is generated, and token usage is charged as fuel.
+// The persona's guardrails screen the user-supplied content and the output.
+// Personas that list tools in their core logic run through toolLoop instead, which may be nil
+// when the agent has no way to reach the adapters. library resolves prompt templates and may
+// be nil when the agent has no templates database.
+func NewAITextGenerateHandler(ai *MeteredAI, toolLoop *ToolLoop, library prompts.Library) orchestration.ActionHandler {
+	return func(ctx context.Context, req *orchestration.ActionRequest) (map[string]interface{}, error) {
+		genReq, inputs, err := buildTaskRequest(ctx, req, library)
+		if err != nil {
+			return nil, err
+		}
+
+		guard, err := NewGuard(req, ai.agentType)
+		if err != nil {
+			return nil, err
+		}
+		if err := guard.Input(inputs); err != nil {
+			return nil, err
+		}
+
+		output, err := generateText(ctx, ai, toolLoop, req, genReq)
+		if err != nil {
+			return nil, err
+		}
+
+		text, _ := output["text"].(string)
+		if err := guard.Output(text, genReq.System); err != nil {
+			return nil, err
+		}
+		if flags := guard.Flags(); len(flags) > 0 {
+			output["guardrail_flags"] = flags
+		}
+		return output, nil
+	}
+}
+
+// generateText runs a task request through the tool loop, a stream or a single call
+func generateText(ctx context.Context, ai *MeteredAI, toolLoop *ToolLoop, req *orchestration.ActionRequest, genReq *aiservice.GenerateRequest) (map[string]interface{}, error) {
+	if req.Config != nil {
+		if tools := StringList(req.Config.CoreLogic["tools"]); len(tools) > 0 {
+			if toolLoop == nil {
+				return nil, fmt.Errorf("persona uses tools but tool calling is not available on this agent")
+			}
+			maxIterations, _ := req.Config.CoreLogic["max_tool_iterations"].(float64)
+			result, err := toolLoop.Run(ctx, req, genReq, tools, int(maxIterations))
+			if err != nil {
+				return nil, fmt.Errorf("text generation failed: %w", err)
+			}
+			return map[string]interface{}{"text": result.Text, "tool_calls": result.ToolCalls}, nil
+		}
+	}
+
+	if req.Progress == nil {
+		resp, err := ai.Generate(ctx, req, genReq)
+		if err != nil {
+			return nil, fmt.Errorf("text generation failed: %w", err)
+		}
+		return map[string]interface{}{"text": resp.Text, "usage": resp.Usage}, nil
+	}
+
+	result, err := ai.Stream(ctx, req, genReq, req.Progress)
+	if err != nil {
+		return nil, fmt.Errorf("text generation failed: %w", err)
+	}
+
+	return map[string]interface{}{"text": result.Text, "usage": result.Usage}, nil
+}
+
+// buildTaskRequest builds a generation request from the persona config, any conversation
+// history in the task data and the output of earlier steps. When the step or persona
+// references a prompt template, the template is rendered with the task data as variables,
+// the earlier steps' output as "steps" and injected memories as JSON in "memories", and it
+// replaces the default prompt layout. Task data, user turns, step output and memories are
+// wrapped with aiservice.DelimitUntrusted; inputs holds that content unwrapped for
+// screening, keyed by where it came from.
+// Images in the task data and images produced by earlier steps go with the last user turn.
+func buildTaskRequest(ctx context.Context, req *orchestration.ActionRequest, library prompts.Library) (genReq *aiservice.GenerateRequest, inputs map[string]string, err error) {
+	var task struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if len(req.InitialData) > 0 {
+		if err := json.Unmarshal(req.InitialData, &task); err != nil {
+			return nil, nil, fmt.Errorf("failed to parse request data: %w", err)
+		}
+	}
+
+	inputs = make(map[string]string)
+	untrusted := func(name, content string) string {
+		inputs[name] = content
+		return aiservice.DelimitUntrusted(name, content)
+	}
+
+	genReq = &aiservice.GenerateRequest{}
+	if req.Config != nil {
+		genReq.Options = aiservice.OptionsFromMap(req.Config.CoreLogic)
+		genReq.System, _ = req.Config.CoreLogic["system_prompt"].(string)
+	}
+
+	// Earlier turns of the conversation, oldest first
+	if history, ok := task.Data["messages"].([]interface{}); ok {
+		for i, item := range history {
+			turn, _ := item.(map[string]interface{})
+			role, _ := turn["role"].(string)
+			content, _ := turn["content"].(string)
+			if role == "" || content == "" {
+				continue
+			}
+			if role == aiservice.RoleUser {
+				content = untrusted(fmt.Sprintf("messages[%d]", i), content)
+			}
+			genReq.Messages = append(genReq.Messages, aiservice.Message{Role: role, Content: content})
+		}
+	}
+
+	steps, memories := splitMemories(req.Data)
+	var vars map[string]interface{}
+	if PromptTemplateRef(req) != "" {
+		vars = make(map[string]interface{}, len(task.Data)+2)
+		for k, v := range task.Data {
+			vars[k] = untrustedVars(k, v, untrusted)
+		}
+		// Step output may hold Go values from local steps; as JSON every string is reachable
+		var stepData interface{}
+		stepsJSON, _ := json.Marshal(steps)
+		_ = json.Unmarshal(stepsJSON, &stepData)
+		vars["steps"] = untrustedVars("steps", stepData, untrusted)
+		if memories != nil {
+			memoriesJSON, _ := json.MarshalIndent(memories, "", "  ")
+			vars["memories"] = untrusted("memories", string(memoriesJSON))
+		}
+	}
+	rendered, ok, err := RenderPromptTemplate(ctx, library, req, vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		if rendered.System != "" {
+			genReq.System = rendered.System
+		}
+		genReq.Messages = append(genReq.Messages, aiservice.Message{Role: aiservice.RoleUser, Content: rendered.Prompt})
+	} else {
+		var b strings.Builder
+		if p, ok := task.Data["prompt"].(string); ok && p != "" {
+			b.WriteString(untrusted("prompt", p))
+		} else if len(genReq.Messages) == 0 {
+			request := make(map[string]interface{}, len(task.Data))
+			for k, v := range task.Data {
+				if k != "images" { // Sent as image parts
+					request[k] = v
+				}
+			}
+			requestJSON, _ := json.MarshalIndent(request, "", "  ")
+			b.WriteString("Request:\n")
+			b.WriteString(untrusted("request", string(requestJSON)))
+		}
+
+		for _, section := range []struct {
+			name, title string
+			data        interface{}
+		}{
+			{"steps", "Results from previous steps:", steps},
+			{"memories", "Relevant memories:", memories},
+		} {
+			if section.data == nil {
+				continue
+			}
+			sectionJSON, _ := json.MarshalIndent(section.data, "", "  ")
+			if b.Len() > 0 {
+				b.WriteString("\n\n")
+			}
+			b.WriteString(section.title + "\n")
+			b.WriteString(untrusted(section.name, string(sectionJSON)))
+		}
+
+		if b.Len() > 0 {
+			genReq.Messages = append(genReq.Messages, aiservice.Message{Role: aiservice.RoleUser, Content: b.String()})
+		}
+	}
+	if len(genReq.Messages) == 0 {
+		return nil, nil, fmt.Errorf("request has no prompt or messages")
+	}
+	if len(inputs) > 0 {
+		genReq.System = strings.TrimSpace(genReq.System + "\n\n" + aiservice.UntrustedInputInstruction)
+	}
+
+	images, err := taskImages(task.Data, req.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(images) > 0 {
+		last := &genReq.Messages[len(genReq.Messages)-1]
+		if last.Role != aiservice.RoleUser {
+			genReq.Messages = append(genReq.Messages, aiservice.Message{Role: aiservice.RoleUser, Content: "Review the attached images."})
+			last = &genReq.Messages[len(genReq.Messages)-1]
+		}
+		last.Images = append(last.Images, images...)
+	}
+
+	return genReq, inputs, nil
+}
+
+// splitMemories separates the memories the coordinator injected from the output of
+// earlier steps. Either is nil when there is none.
+func splitMemories(data map[string]interface{}) (steps map[string]interface{}, memories interface{}) {
+	for k, v := range data {
+		if k == orchestration.MemoryContextKey {
+			memories = v
+			continue
+		}
+		if steps == nil {
+			steps = make(map[string]interface{}, len(data))
+		}
+		steps[k] = v
+	}
+	return steps, memories
+}
+
+// DelimitUntrustedVars wraps every non-empty string in a template variable built from
+// request data with aiservice.DelimitUntrusted, naming it by its path
+func DelimitUntrustedVars(path string, v interface{}) interface{} {
+	return untrustedVars(path, v, aiservice.DelimitUntrusted)
+}
+
+// untrustedVars wraps every non-empty string in template variables with
+// aiservice.DelimitUntrusted, naming it by its path, so structured values keep their shape
+func untrustedVars(path string, v interface{}, wrap func(name, content string) string) interface{} {
+	switch value := v.(type) {
+	case string:
+		if value == "" {
+			return value
+		}
+		return wrap(path, value)
+	case map[string]interface{}:
+		wrapped := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			name := k
+			if path != "" {
+				name = path + "." + k
+			}
+			wrapped[k] = untrustedVars(name, item, wrap)
+		}
+		return wrapped
+	case []interface{}:
+		wrapped := make([]interface{}, len(value))
+		for i, item := range value {
+			wrapped[i] = untrustedVars(fmt.Sprintf("%s[%d]", path, i), item, wrap)
+		}
+		return wrapped
+	}
+	return v
+}
+
+// taskImages collects the images for a request: the images listed in the task data,
+// as URIs or {uri, media_type, data} objects with base64 data, then the image_uri
+// of every earlier step that produced one, in step name order
+func taskImages(data, steps map[string]interface{}) ([]aiservice.ImagePart, error) {
+	var images []aiservice.ImagePart
+	listed, _ := data["images"].([]interface{})
+	for i, item := range listed {
+		switch v := item.(type) {
+		case string:
+			images = append(images, aiservice.ImagePart{URI: v})
+		case map[string]interface{}:
+			var img aiservice.ImagePart
+			img.URI, _ = v["uri"].(string)
+			img.MediaType, _ = v["media_type"].(string)
+			if encoded, ok := v["data"].(string); ok && encoded != "" {
+				decoded, err := base64.StdEncoding.DecodeString(encoded)
+				if err != nil {
+					return nil, fmt.Errorf("image %d has invalid base64 data: %w", i, err)
+				}
+				img.Data = decoded
+			}
+			if img.URI == "" && len(img.Data) == 0 {
+				return nil, fmt.Errorf("image %d has neither a uri nor data", i)
+			}
+			images = append(images, img)
+		default:
+			return nil, fmt.Errorf("image %d must be a URI or an object", i)
+		}
+	}
+
+	names := make([]string, 0, len(steps))
+	for name := range steps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		output, _ := steps[name].(map[string]interface{})
+		if uri, ok := output["image_uri"].(string); ok && uri != "" {
+			images = append(images, aiservice.ImagePart{URI: uri})
+		}
+	}
+	return images, nil
+}
+
+// StringList converts a JSON array from the persona config into strings, skipping
+// anything that is not a string
+func StringList(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
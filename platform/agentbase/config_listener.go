@@ -0,0 +1,69 @@
+// FILE: platform/agentbase/config_listener.go
+package agentbase
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/config"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"go.uber.org/zap"
+)
+
+// ConfigListener invalidates cached agent configs when core-manager announces a change
+type ConfigListener struct {
+	ctx      context.Context
+	consumer *kafka.Consumer
+	cache    *config.AgentConfigCache
+	logger   *zap.Logger
+}
+
+// NewConfigListener subscribes this replica to agent_config_changed events.
+// Every replica gets its own consumer group so all caches see every event.
+func NewConfigListener(ctx context.Context, brokers []string, agentType string, cache *config.AgentConfigCache, logger *zap.Logger) (*ConfigListener, error) {
+	consumer, err := kafka.NewBroadcastConsumer(brokers, config.AgentConfigChangedTopic, agentType+"-config", logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfigListener{
+		ctx:      ctx,
+		consumer: consumer,
+		cache:    cache,
+		logger:   logger,
+	}, nil
+}
+
+// Run applies change events to the cache until the context is cancelled
+func (l *ConfigListener) Run() {
+	l.logger.Info("Listening for agent config changes", zap.String("topic", config.AgentConfigChangedTopic))
+	defer l.consumer.Close()
+
+	for {
+		msg, err := l.consumer.FetchMessage(l.ctx)
+		if err != nil {
+			if l.ctx.Err() != nil {
+				return
+			}
+			l.logger.Error("Failed to fetch config change event", zap.Error(err))
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		var event config.AgentConfigChanged
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			l.logger.Warn("Ignoring malformed config change event", zap.Error(err))
+			continue
+		}
+		if event.EventType != config.AgentConfigChangedEvent {
+			continue
+		}
+
+		l.cache.Apply(event)
+		l.logger.Info("Agent config cache invalidated",
+			zap.String("client_id", event.ClientID),
+			zap.String("instance_id", event.InstanceID),
+			zap.String("template_id", event.TemplateID))
+	}
+}
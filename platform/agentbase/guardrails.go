@@ -0,0 +1,68 @@
+// FILE: platform/agentbase/guardrails.go
+package agentbase
+
+import (
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/observability"
+	"github.com/gqls/agentchassis/platform/orchestration"
+)
+
+// GuardrailTriggeredEvent tells the UI that a guardrail blocked or flagged a step
+const GuardrailTriggeredEvent = "GUARDRAIL_TRIGGERED"
+
+// Guard applies a persona's guardrails to one step. Every match is reported to the UI;
+// blocking matches fail the step and flagged ones are collected for the step output.
+type Guard struct {
+	guardrails *aiservice.Guardrails
+	req        *orchestration.ActionRequest
+	agentType  string
+	flags      []aiservice.Violation
+}
+
+// NewGuard reads the guardrails object of the persona's core logic
+func NewGuard(req *orchestration.ActionRequest, agentType string) (*Guard, error) {
+	var config interface{}
+	if req.Config != nil {
+		config = req.Config.CoreLogic["guardrails"]
+	}
+	guardrails, err := aiservice.GuardrailsFromMap(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Guard{guardrails: guardrails, req: req, agentType: agentType}, nil
+}
+
+// Input screens user-supplied inputs before they reach the model
+func (g *Guard) Input(inputs map[string]string) error {
+	return g.report(g.guardrails.CheckInput(inputs))
+}
+
+// Output screens model output. Streamed output has already reached the UI by then,
+// but a blocked step still fails and its output is not passed on.
+func (g *Guard) Output(text, system string) error {
+	return g.report(g.guardrails.CheckOutput(text, system))
+}
+
+// Flags returns the flagged violations so far, for the step output
+func (g *Guard) Flags() []aiservice.Violation {
+	return g.flags
+}
+
+func (g *Guard) report(result aiservice.GuardrailResult) error {
+	if len(result.Violations) == 0 {
+		return nil
+	}
+	for _, v := range result.Violations {
+		observability.GuardrailViolations.WithLabelValues(g.agentType, v.Rule, v.Stage, string(v.Action)).Inc()
+		if v.Action == aiservice.GuardrailFlag {
+			g.flags = append(g.flags, v)
+		}
+	}
+	if g.req.Notify != nil {
+		g.req.Notify(GuardrailTriggeredEvent, map[string]interface{}{
+			"blocked":    result.Blocked(),
+			"violations": result.Violations,
+		})
+	}
+	return result.Err()
+}
@@ -0,0 +1,69 @@
+// FILE: platform/agentbase/handlers.go
+package agentbase
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/gqls/agentchassis/platform/orchestration"
+)
+
+// HandlerRegistry maps workflow actions to in-process handlers
+type HandlerRegistry struct {
+	mu       sync.RWMutex
+	handlers map[string]orchestration.ActionHandler
+}
+
+// NewHandlerRegistry creates an empty handler registry
+func NewHandlerRegistry() *HandlerRegistry {
+	return &HandlerRegistry{
+		handlers: make(map[string]orchestration.ActionHandler),
+	}
+}
+
+// Register adds a handler for an action. Each action can only be registered once.
+func (r *HandlerRegistry) Register(action string, handler orchestration.ActionHandler) error {
+	if action == "" {
+		return fmt.Errorf("action name cannot be empty")
+	}
+	if handler == nil {
+		return fmt.Errorf("handler for action '%s' cannot be nil", action)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.handlers[action]; exists {
+		return fmt.Errorf("handler for action '%s' is already registered", action)
+	}
+	r.handlers[action] = handler
+	return nil
+}
+
+// Get returns the handler for an action, if one is registered
+func (r *HandlerRegistry) Get(action string) (orchestration.ActionHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	handler, ok := r.handlers[action]
+	return handler, ok
+}
+
+// Has reports whether an action has a registered handler
+func (r *HandlerRegistry) Has(action string) bool {
+	_, ok := r.Get(action)
+	return ok
+}
+
+// Actions returns the registered action names in sorted order
+func (r *HandlerRegistry) Actions() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	actions := make([]string, 0, len(r.handlers))
+	for action := range r.handlers {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
@@ -0,0 +1,37 @@
+// FILE: platform/agentbase/handlers_test.go
+package agentbase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerRegistry(t *testing.T) {
+	registry := NewHandlerRegistry()
+
+	handler := func(ctx context.Context, req *orchestration.ActionRequest) (map[string]interface{}, error) {
+		return map[string]interface{}{"echo": req.Action}, nil
+	}
+
+	require.NoError(t, registry.Register("summarise", handler))
+	require.NoError(t, registry.Register("classify", handler))
+
+	// Duplicate and invalid registrations are rejected
+	assert.Error(t, registry.Register("summarise", handler))
+	assert.Error(t, registry.Register("", handler))
+	assert.Error(t, registry.Register("translate", nil))
+
+	assert.True(t, registry.Has("summarise"))
+	assert.False(t, registry.Has("translate"))
+	assert.Equal(t, []string{"classify", "summarise"}, registry.Actions())
+
+	got, ok := registry.Get("classify")
+	require.True(t, ok)
+	output, err := got(context.Background(), &orchestration.ActionRequest{Action: "classify"})
+	require.NoError(t, err)
+	assert.Equal(t, "classify", output["echo"])
+}
@@ -0,0 +1,236 @@
+// FILE: platform/agentbase/metered.go
+package agentbase
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/gqls/agentchassis/platform/observability"
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/gqls/agentchassis/platform/resilience"
+	"go.uber.org/zap"
+)
+
+// minAffordableOutputTokens is the shortest answer worth asking for when the budget
+// cannot cover a model's full max_tokens
+const minAffordableOutputTokens = 256
+
+// Reasons a call moved off a model, as recorded in AIModelFallbacks
+const (
+	fallbackFuel        = "fuel"
+	fallbackUnavailable = "unavailable"
+	fallbackError       = "error"
+)
+
+// MeteredAI charges AI calls to the workflow's fuel budget by token usage.
+// Each call reserves its worst-case cost up front and settles on the reported usage.
+//
+// Requests run against a model chain: the configured model followed by its
+// fallback_models. When the remaining budget cannot cover a model's max_tokens, the
+// answer is capped at what it can pay for. A model is skipped when the budget cannot
+// cover a useful answer or its circuit breaker is open, and abandoned after retryable
+// errors the client could not recover from.
+type MeteredAI struct {
+	client    aiservice.AIService
+	policy    *governance.TokenFuelPolicy
+	defaults  aiservice.TextGenerationOptions // The client's configured model and max_tokens
+	agentType string
+
+	mu       sync.Mutex
+	breakers map[string]*resilience.CircuitBreaker // Per model
+}
+
+// NewMeteredAI wraps client. defaults should match the ai_service config the client was built from.
+func NewMeteredAI(client aiservice.AIService, policy *governance.TokenFuelPolicy, defaults aiservice.TextGenerationOptions, agentType string) *MeteredAI {
+	return &MeteredAI{
+		client:    client,
+		policy:    policy,
+		defaults:  defaults.WithDefaults(aiservice.TextGenerationOptions{MaxTokens: aiservice.DefaultMaxTokens}),
+		agentType: agentType,
+		breakers:  make(map[string]*resilience.CircuitBreaker),
+	}
+}
+
+// Client returns the unmetered client
+func (m *MeteredAI) Client() aiservice.AIService {
+	return m.client
+}
+
+// Generate runs a request, charging its token usage to the action's fuel budget
+func (m *MeteredAI) Generate(ctx context.Context, action *orchestration.ActionRequest, req *aiservice.GenerateRequest) (*aiservice.GenerateResponse, error) {
+	var resp *aiservice.GenerateResponse
+	err := m.withFallback(action, req, func(attempt *aiservice.GenerateRequest, reservation *governance.FuelReservation) (bool, error) {
+		r, err := m.client.Generate(ctx, attempt)
+		if err != nil {
+			return false, err
+		}
+		m.settle(action, reservation, r.Model, r.Usage)
+		resp = r
+		return true, nil
+	})
+	return resp, err
+}
+
+// Stream runs a streaming request, forwarding deltas to onDelta and charging the usage
+// reported at the end of the stream. Fallback only happens before the stream starts.
+func (m *MeteredAI) Stream(ctx context.Context, action *orchestration.ActionRequest, req *aiservice.GenerateRequest, onDelta func(string)) (*aiservice.StreamResult, error) {
+	var result *aiservice.StreamResult
+	err := m.withFallback(action, req, func(attempt *aiservice.GenerateRequest, reservation *governance.FuelReservation) (bool, error) {
+		stream, err := m.client.GenerateStream(ctx, attempt)
+		if err != nil {
+			return false, err
+		}
+
+		r, err := aiservice.CollectStream(stream, onDelta)
+		if err != nil {
+			// Tokens were generated but the stream broke before usage arrived; keep the reservation
+			m.settle(action, reservation, "", aiservice.Usage{
+				InputTokens:  estimateRequestTokens(attempt),
+				OutputTokens: governance.EstimateTokens(r.Text),
+			})
+			return true, err
+		}
+
+		m.settle(action, reservation, r.Model, r.Usage)
+		result = r
+		return true, nil
+	})
+	return result, err
+}
+
+// withFallback runs call against each model in the request's chain until one succeeds.
+// call reports whether it settled the reservation; unsettled reservations are released.
+// Once a call has settled, its outcome is final.
+func (m *MeteredAI) withFallback(action *orchestration.ActionRequest, req *aiservice.GenerateRequest, call func(*aiservice.GenerateRequest, *governance.FuelReservation) (bool, error)) error {
+	opts := req.Options.WithDefaults(m.defaults)
+	estimatedInput := estimateRequestTokens(req)
+
+	var lastErr error
+	affordable := false
+	for _, model := range modelChain(opts) {
+		maxTokens := opts.MaxTokens
+		reservation, err := m.policy.Reserve(action.Headers, model, estimatedInput, maxTokens)
+		if errors.Is(err, governance.ErrInsufficientFuel) {
+			if affordableTokens := m.policy.AffordableOutputTokens(action.Headers, model, estimatedInput); affordableTokens >= min(minAffordableOutputTokens, maxTokens) {
+				maxTokens = affordableTokens
+				reservation, err = m.policy.Reserve(action.Headers, model, estimatedInput, maxTokens)
+			}
+		}
+		if errors.Is(err, governance.ErrInsufficientFuel) {
+			observability.AIModelFallbacks.WithLabelValues(m.agentType, model, fallbackFuel).Inc()
+			if lastErr == nil {
+				lastErr = err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		affordable = true
+
+		attempt := *req
+		attempt.Options.Model = model
+		attempt.Options.MaxTokens = maxTokens
+		attempt.ClientID = action.Headers["client_id"]
+		if attempt.Options.Cache != nil {
+			// Cached answers stay with the client and persona instance they were given to
+			policy := *attempt.Options.Cache
+			policy.ClientID = action.Headers["client_id"]
+			policy.AgentInstanceID = action.Headers["agent_instance_id"]
+			attempt.Options.Cache = &policy
+		}
+
+		settled := false
+		_, err = m.breaker(model).Execute(func() (interface{}, error) {
+			var callErr error
+			settled, callErr = call(&attempt, reservation)
+			return nil, callErr
+		})
+		if !settled {
+			reservation.Release()
+		}
+		if err == nil || settled {
+			return err
+		}
+
+		lastErr = err
+		switch {
+		case resilience.IsCircuitBreakerError(err):
+			observability.AIModelFallbacks.WithLabelValues(m.agentType, model, fallbackUnavailable).Inc()
+		case isRetryable(err):
+			observability.AIModelFallbacks.WithLabelValues(m.agentType, model, fallbackError).Inc()
+		default:
+			// The request itself is at fault; another model would fail the same way
+			return err
+		}
+	}
+
+	if !affordable {
+		observability.FuelExhausted.WithLabelValues(m.agentType, action.Action, action.Headers["client_id"]).Inc()
+	}
+	return lastErr
+}
+
+// breaker returns the circuit breaker for a model. Only failures that suggest the
+// model is unavailable count against it.
+func (m *MeteredAI) breaker(model string) *resilience.CircuitBreaker {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if cb, ok := m.breakers[model]; ok {
+		return cb
+	}
+	cbConfig := resilience.DefaultCircuitBreakerConfig("ai-model-" + model)
+	cbConfig.IsSuccessful = func(err error) bool {
+		return err == nil || !isRetryable(err)
+	}
+	cb := resilience.NewCircuitBreaker(cbConfig, zap.L())
+	m.breakers[model] = cb
+	return cb
+}
+
+func (m *MeteredAI) settle(action *orchestration.ActionRequest, reservation *governance.FuelReservation, model string, usage aiservice.Usage) {
+	consumed := reservation.Settle(model, usage.InputTokens, usage.OutputTokens)
+	observability.FuelConsumed.WithLabelValues(m.agentType, action.Action, action.Headers["client_id"]).Add(float64(consumed))
+}
+
+// modelChain is the preferred model followed by its fallbacks, without repeats
+func modelChain(opts aiservice.TextGenerationOptions) []string {
+	chain := []string{opts.Model}
+	seen := map[string]bool{opts.Model: true}
+	for _, model := range opts.FallbackModels {
+		if model != "" && !seen[model] {
+			seen[model] = true
+			chain = append(chain, model)
+		}
+	}
+	return chain
+}
+
+// isRetryable reports whether err is a provider failure worth retrying elsewhere
+func isRetryable(err error) bool {
+	var domainErr *platformerrors.DomainError
+	return errors.As(err, &domainErr) && domainErr.Retryable
+}
+
+// estimateRequestTokens estimates the prompt size of a request
+func estimateRequestTokens(req *aiservice.GenerateRequest) int {
+	chars := len(req.System)
+	for _, msg := range req.Messages {
+		chars += len(msg.Content)
+		for _, call := range msg.ToolCalls {
+			chars += len(call.Input)
+		}
+		for _, result := range msg.ToolResults {
+			chars += len(result.Content)
+		}
+	}
+	for _, tool := range req.Tools {
+		chars += len(tool.Description) + 200 // Schema overhead
+	}
+	return chars/4 + aiservice.EstimateImageTokens(req) + 1
+}
@@ -0,0 +1,147 @@
+// FILE: platform/agentbase/metered_test.go
+package agentbase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/config"
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// modelAI fails for the models in failures and answers for every other model
+type modelAI struct {
+	scriptedAI
+	failures map[string]error
+	models   []string
+}
+
+func (m *modelAI) Generate(ctx context.Context, req *aiservice.GenerateRequest) (*aiservice.GenerateResponse, error) {
+	m.models = append(m.models, req.Options.Model)
+	if err := m.failures[req.Options.Model]; err != nil {
+		return nil, err
+	}
+	return &aiservice.GenerateResponse{Text: "from " + req.Options.Model, Model: req.Options.Model,
+		Usage: aiservice.Usage{InputTokens: 1000, OutputTokens: 200}}, nil
+}
+
+func overloaded() error {
+	return platformerrors.New(platformerrors.ErrAIServiceError, "anthropic request failed with status 529").
+		AsRetryable(nil).Build()
+}
+
+func chainRequest() *aiservice.GenerateRequest {
+	return aiservice.PromptRequest("hi", aiservice.TextGenerationOptions{
+		Model:          "claude-3-opus-20240229",
+		FallbackModels: []string{"claude-3-5-sonnet-20240620", "claude-3-haiku-20240307"},
+	})
+}
+
+func TestMeteredAI_FallsBackOnProviderErrors(t *testing.T) {
+	ai := &modelAI{failures: map[string]error{"claude-3-opus-20240229": overloaded()}}
+	headers := map[string]string{governance.FuelHeader: "100"}
+
+	resp, err := meter(ai).Generate(context.Background(), actionWith(headers), chainRequest())
+	require.NoError(t, err)
+
+	assert.Equal(t, "from claude-3-5-sonnet-20240620", resp.Text)
+	assert.Equal(t, []string{"claude-3-opus-20240229", "claude-3-5-sonnet-20240620"}, ai.models)
+	// Only the model that answered is charged: 1000 input at 3/1K plus 200 output at 15/1K
+	assert.Equal(t, "94", headers[governance.FuelHeader])
+}
+
+func TestMeteredAI_SkipsModelsTheBudgetCannotCover(t *testing.T) {
+	ai := &modelAI{}
+	// 100 output tokens reserve 8 fuel on opus and 2 on sonnet; haiku needs 1
+	headers := map[string]string{governance.FuelHeader: "1"}
+
+	resp, err := meter(ai).Generate(context.Background(), actionWith(headers), chainRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "from claude-3-haiku-20240307", resp.Text)
+	assert.Equal(t, []string{"claude-3-haiku-20240307"}, ai.models)
+}
+
+func TestMeteredAI_RequestErrorsDoNotFallBack(t *testing.T) {
+	invalid := platformerrors.New(platformerrors.ErrAIServiceError, "anthropic request failed with status 400").Build()
+	ai := &modelAI{failures: map[string]error{"claude-3-opus-20240229": invalid}}
+	headers := map[string]string{governance.FuelHeader: "100"}
+
+	_, err := meter(ai).Generate(context.Background(), actionWith(headers), chainRequest())
+	require.Error(t, err)
+	assert.Equal(t, []string{"claude-3-opus-20240229"}, ai.models)
+	assert.Equal(t, "100", headers[governance.FuelHeader], "the reservation is returned")
+}
+
+func TestMeteredAI_OpenBreakerSkipsModel(t *testing.T) {
+	ai := &modelAI{failures: map[string]error{"claude-3-opus-20240229": overloaded()}}
+	metered := meter(ai)
+	headers := map[string]string{governance.FuelHeader: "1000"}
+
+	// Enough failures trip opus's breaker
+	for i := 0; i < 5; i++ {
+		_, err := metered.Generate(context.Background(), actionWith(headers), chainRequest())
+		require.NoError(t, err)
+	}
+	ai.models = nil
+
+	_, err := metered.Generate(context.Background(), actionWith(headers), chainRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claude-3-5-sonnet-20240620"}, ai.models, "opus is not called while its breaker is open")
+}
+
+func TestMeteredAI_ChainExhausted(t *testing.T) {
+	ai := &modelAI{failures: map[string]error{
+		"claude-3-opus-20240229":     overloaded(),
+		"claude-3-5-sonnet-20240620": overloaded(),
+		"claude-3-haiku-20240307":    errors.New("connection reset"),
+	}}
+	_, err := meter(ai).Generate(context.Background(), actionWith(map[string]string{governance.FuelHeader: "100"}), chainRequest())
+	require.EqualError(t, err, "connection reset")
+	assert.Len(t, ai.models, 3)
+}
+
+// maxTokensAI answers every request, recording the max_tokens it was sent
+type maxTokensAI struct {
+	scriptedAI
+	maxTokens []int
+}
+
+func (m *maxTokensAI) Generate(ctx context.Context, req *aiservice.GenerateRequest) (*aiservice.GenerateResponse, error) {
+	m.maxTokens = append(m.maxTokens, req.Options.MaxTokens)
+	return &aiservice.GenerateResponse{Text: "ok", Model: req.Options.Model, Usage: aiservice.Usage{InputTokens: 500, OutputTokens: 300}}, nil
+}
+
+func TestMeteredAI_DefaultPersonasRunUnderDefaultBudget(t *testing.T) {
+	prompt := strings.Repeat("Summarise the quarterly results for the board. ", 40)
+	for _, agentType := range []string{"copywriter", "reasoning", "researcher", "assistant"} {
+		coreLogic := config.DefaultCoreLogic(agentType)
+		ai := &maxTokensAI{}
+		headers := map[string]string{governance.FuelHeader: "100"}
+		req := aiservice.PromptRequest(prompt, aiservice.OptionsFromMap(coreLogic))
+
+		_, err := meter(ai).Generate(context.Background(), actionWith(headers), req)
+		require.NoError(t, err, agentType)
+		require.Len(t, ai.maxTokens, 1, agentType)
+		assert.GreaterOrEqual(t, ai.maxTokens[0], minAffordableOutputTokens, agentType)
+		assert.LessOrEqual(t, ai.maxTokens[0], coreLogic["max_tokens"].(int), agentType)
+	}
+}
+
+func TestMeteredAI_CapsMaxTokensToBudget(t *testing.T) {
+	ai := &maxTokensAI{}
+	// 4000 output tokens on opus need 300 fuel; 100 pays for about 1330
+	headers := map[string]string{governance.FuelHeader: "100"}
+	req := aiservice.PromptRequest("hi", aiservice.TextGenerationOptions{Model: "claude-3-opus-20240229", MaxTokens: 4000})
+
+	_, err := meter(ai).Generate(context.Background(), actionWith(headers), req)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1333}, ai.maxTokens)
+	// Settled on the reported usage: 500 input at 15/1K plus 300 output at 75/1K
+	assert.Equal(t, "70", headers[governance.FuelHeader])
+}
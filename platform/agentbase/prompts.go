@@ -0,0 +1,46 @@
+// FILE: platform/agentbase/prompts.go
+package agentbase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/gqls/agentchassis/platform/prompts"
+)
+
+// PromptTemplateRef returns the prompt template a step uses: the step's own
+// prompt_template, else prompt_template in the persona's core logic
+func PromptTemplateRef(req *orchestration.ActionRequest) string {
+	if req.Step.PromptTemplate != "" {
+		return req.Step.PromptTemplate
+	}
+	if req.Config != nil {
+		ref, _ := req.Config.CoreLogic["prompt_template"].(string)
+		return ref
+	}
+	return ""
+}
+
+// RenderPromptTemplate renders the step's prompt template with vars.
+// ok is false when the step and persona reference no template.
+func RenderPromptTemplate(ctx context.Context, library prompts.Library, req *orchestration.ActionRequest, vars map[string]interface{}) (rendered *prompts.Rendered, ok bool, err error) {
+	refString := PromptTemplateRef(req)
+	if refString == "" {
+		return nil, false, nil
+	}
+	if library == nil {
+		return nil, true, fmt.Errorf("prompt template '%s' requested but no templates database is configured", refString)
+	}
+
+	ref, err := prompts.ParseRef(refString)
+	if err != nil {
+		return nil, true, err
+	}
+	tmpl, err := library.Get(ctx, ref.ID, ref.Version)
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to load prompt template '%s': %w", ref, err)
+	}
+	rendered, err = tmpl.Render(vars)
+	return rendered, true, err
+}
@@ -0,0 +1,77 @@
+// FILE: platform/agentbase/step_listener.go
+package agentbase
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"go.uber.org/zap"
+)
+
+// StepListener hands the responses of remote workflow steps to the orchestrator,
+// which continues the workflows waiting on them
+type StepListener struct {
+	ctx          context.Context
+	consumer     *kafka.Consumer
+	orchestrator *orchestration.SagaCoordinator
+	topic        string
+	logger       *zap.Logger
+}
+
+// StepReplyTopic is where services running remote steps reply to agents of agentType
+func StepReplyTopic(agentType string) string {
+	return fmt.Sprintf("system.responses.%s-steps", agentType)
+}
+
+// NewStepListener consumes topic and asks remote steps to reply on it. Replicas share
+// the consumer group; replies are keyed by correlation ID, so one replica sees all
+// the responses of a workflow, in order.
+func NewStepListener(ctx context.Context, brokers []string, topic, agentType string, orchestrator *orchestration.SagaCoordinator, logger *zap.Logger) (*StepListener, error) {
+	consumer, err := kafka.NewConsumer(brokers, topic, agentType+"-steps", logger)
+	if err != nil {
+		return nil, err
+	}
+	orchestrator.SetStepReplyTopic(topic)
+
+	return &StepListener{
+		ctx:          ctx,
+		consumer:     consumer,
+		orchestrator: orchestrator,
+		topic:        topic,
+		logger:       logger,
+	}, nil
+}
+
+// Run processes step responses until the context is cancelled
+func (l *StepListener) Run() {
+	l.logger.Info("Listening for remote step responses", zap.String("topic", l.topic))
+	defer l.consumer.Close()
+
+	for {
+		msg, err := l.consumer.FetchMessage(l.ctx)
+		if err != nil {
+			if l.ctx.Err() != nil {
+				return
+			}
+			l.logger.Error("Failed to fetch step response", zap.Error(err))
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		headers := kafka.HeadersToMap(msg.Headers)
+		if err := l.orchestrator.HandleResponse(l.ctx, headers, msg.Value); err != nil {
+			// Failed workflows have already replied to their callers
+			l.logger.Warn("Step response did not continue the workflow",
+				zap.String("correlation_id", headers["correlation_id"]),
+				zap.String("causation_id", headers["causation_id"]),
+				zap.Error(err))
+		}
+
+		if err := l.consumer.CommitMessages(context.Background(), msg); err != nil {
+			l.logger.Error("Failed to commit step response", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,288 @@
+// FILE: platform/agentbase/tools.go
+package agentbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/observability"
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultMaxToolIterations caps model round trips when the persona does not set max_tool_iterations
+	DefaultMaxToolIterations = 5
+
+	// maxToolIterationsLimit is the most a persona can ask for
+	maxToolIterationsLimit = 10
+
+	// DefaultToolReplyTopic receives adapter replies to tool calls
+	DefaultToolReplyTopic = "system.responses.agent-tools"
+
+	// DefaultToolTimeout is how long a tool call may take when its Tool sets no Timeout
+	DefaultToolTimeout = kafka.DefaultRequestTimeout
+)
+
+var (
+	// ErrToolIterationsExceeded is returned when the model keeps calling tools past the cap
+	ErrToolIterationsExceeded = errors.New("tool iteration limit exceeded")
+
+	// ErrToolFuelExhausted is returned when the budget cannot cover a tool call
+	ErrToolFuelExhausted = errors.New("insufficient fuel for tool call")
+)
+
+// Tool maps a tool the model can call onto an adapter topic
+type Tool struct {
+	Definition aiservice.ToolDefinition
+	Topic      string // Adapter request topic
+	Action     string // Action sent in the adapter envelope
+	FuelAction string // CostTable entry charged per call
+
+	// Timeout is how long a call may wait for the adapter's reply, DefaultToolTimeout if zero.
+	// Timed-out calls are not charged.
+	Timeout time.Duration
+}
+
+// DefaultTools are the adapters every agent can offer to the model
+var DefaultTools = []Tool{
+	{
+		Definition: aiservice.ToolDefinition{
+			Name:        "web_search",
+			Description: "Search the web for current information. Returns titles, URLs and snippets.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"query":       map[string]interface{}{"type": "string", "description": "The search query"},
+					"num_results": map[string]interface{}{"type": "integer", "description": "Number of results, default 10"},
+					"search_type": map[string]interface{}{"type": "string", "enum": []string{"web", "news", "images"}},
+				},
+				"required": []string{"query"},
+			},
+		},
+		Topic:      "system.adapter.web.search",
+		Action:     "web_search",
+		FuelAction: "web_search",
+	},
+	{
+		Definition: aiservice.ToolDefinition{
+			Name:        "generate_image",
+			Description: "Generate an image from a text description. Returns the URI of the stored image.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"prompt":       map[string]interface{}{"type": "string", "description": "Description of the image"},
+					"aspect_ratio": map[string]interface{}{"type": "string", "description": "For example 1:1 or 16:9"},
+					"style":        map[string]interface{}{"type": "string"},
+				},
+				"required": []string{"prompt"},
+			},
+		},
+		Topic:      "system.adapter.image.generate",
+		Action:     "generate_image",
+		FuelAction: "ai_image_generate_sdxl",
+		Timeout:    2 * time.Minute, // Image models take far longer than a search
+	},
+}
+
+// WithToolTimeouts returns a copy of tools with the timeouts in overrides, given in seconds
+// by tool name, as read from the tool_timeouts custom config. Other entries are ignored.
+func WithToolTimeouts(tools []Tool, overrides map[string]interface{}) []Tool {
+	result := make([]Tool, len(tools))
+	copy(result, tools)
+	for i, tool := range result {
+		var seconds float64
+		switch v := overrides[tool.Definition.Name].(type) {
+		case int:
+			seconds = float64(v)
+		case float64:
+			seconds = v
+		}
+		if seconds > 0 {
+			result[i].Timeout = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	return result
+}
+
+// ToolInvoker sends a request to an adapter and waits for its reply.
+// *kafka.RequestReplyClient satisfies it.
+type ToolInvoker interface {
+	Request(ctx context.Context, topic string, headers map[string]string, key, value []byte) (kafka.Message, error)
+}
+
+// ToolLoop lets the model call tools until it produces a final answer
+type ToolLoop struct {
+	ai          *MeteredAI
+	invoker     ToolInvoker
+	tools       map[string]Tool
+	fuelManager *governance.FuelManager
+	logger      *zap.Logger
+}
+
+// ToolLoopResult is the final answer and the tool calls made along the way
+type ToolLoopResult struct {
+	Text       string
+	ToolCalls  int
+	Iterations int
+}
+
+// NewToolLoop creates a tool loop over the given tools
+func NewToolLoop(ai *MeteredAI, invoker ToolInvoker, tools []Tool, logger *zap.Logger) *ToolLoop {
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Definition.Name] = tool
+	}
+	return &ToolLoop{
+		ai:          ai,
+		invoker:     invoker,
+		tools:       byName,
+		fuelManager: governance.NewFuelManager(),
+		logger:      logger,
+	}
+}
+
+// Run offers the named tools to the model and executes its tool calls. Model calls and
+// tool calls are both charged to the fuel budget in the action's headers.
+func (l *ToolLoop) Run(ctx context.Context, action *orchestration.ActionRequest, req *aiservice.GenerateRequest, toolNames []string, maxIterations int) (*ToolLoopResult, error) {
+	if maxIterations <= 0 {
+		maxIterations = DefaultMaxToolIterations
+	}
+	if maxIterations > maxToolIterationsLimit {
+		maxIterations = maxToolIterationsLimit
+	}
+
+	loopReq := *req
+	loopReq.Messages = append([]aiservice.Message(nil), req.Messages...)
+	loopReq.Tools = nil
+	for _, name := range toolNames {
+		tool, ok := l.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool '%s'", name)
+		}
+		loopReq.Tools = append(loopReq.Tools, tool.Definition)
+	}
+
+	result := &ToolLoopResult{}
+	for result.Iterations < maxIterations {
+		result.Iterations++
+
+		resp, err := l.ai.Generate(ctx, action, &loopReq)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.ToolCalls) == 0 {
+			result.Text = resp.Text
+			return result, nil
+		}
+
+		loopReq.Messages = append(loopReq.Messages, aiservice.Message{
+			Role:      aiservice.RoleAssistant,
+			Content:   resp.Text,
+			ToolCalls: resp.ToolCalls,
+		})
+
+		results := make([]aiservice.ToolResult, 0, len(resp.ToolCalls))
+		for _, call := range resp.ToolCalls {
+			toolResult, err := l.call(ctx, action, call)
+			if err != nil {
+				return nil, err
+			}
+			result.ToolCalls++
+			results = append(results, toolResult)
+		}
+		loopReq.Messages = append(loopReq.Messages, aiservice.Message{
+			Role:        aiservice.RoleUser,
+			ToolResults: results,
+		})
+	}
+
+	return nil, fmt.Errorf("%w (%d iterations)", ErrToolIterationsExceeded, maxIterations)
+}
+
+// call runs one tool call through its adapter. Tool failures go back to the model
+// as error results; only fuel exhaustion stops the loop.
+func (l *ToolLoop) call(ctx context.Context, action *orchestration.ActionRequest, call aiservice.ToolCall) (aiservice.ToolResult, error) {
+	headers := action.Headers
+	log := l.logger.With(
+		zap.String("correlation_id", headers["correlation_id"]),
+		zap.String("tool", call.Name),
+		zap.String("tool_call_id", call.ID))
+
+	tool, ok := l.tools[call.Name]
+	if !ok {
+		return toolError(call, fmt.Sprintf("unknown tool '%s'", call.Name)), nil
+	}
+
+	fuel, err := governance.GetFuelFromHeader(headers)
+	if err != nil {
+		return aiservice.ToolResult{}, fmt.Errorf("%w: %v", ErrToolFuelExhausted, err)
+	}
+	if !l.fuelManager.HasEnoughFuel(fuel, tool.FuelAction) {
+		return aiservice.ToolResult{}, fmt.Errorf("%w: '%s' costs %d, %d left",
+			ErrToolFuelExhausted, call.Name, l.fuelManager.GetCost(tool.FuelAction), fuel)
+	}
+	governance.SetFuelHeader(headers, l.fuelManager.DeductFuel(fuel, tool.FuelAction))
+
+	input := call.Input
+	if len(input) == 0 {
+		input = json.RawMessage("{}")
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"action": tool.Action,
+		"data":   input,
+	})
+
+	timeout := tool.Timeout
+	if timeout <= 0 {
+		timeout = DefaultToolTimeout
+	}
+	callCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	reply, err := l.invoker.Request(callCtx, tool.Topic, headers, []byte(headers["correlation_id"]), payload)
+	if errors.Is(err, kafka.ErrRequestTimeout) {
+		// The call produced nothing, so it is not charged
+		governance.SetFuelHeader(headers, fuel)
+		log.Warn("Tool call timed out", zap.Duration("timeout", timeout), zap.Error(err))
+		return toolError(call, fmt.Sprintf("tool call timed out after %s", timeout)), nil
+	}
+	observability.FuelConsumed.WithLabelValues(l.ai.agentType, tool.FuelAction, headers["client_id"]).
+		Add(float64(l.fuelManager.GetCost(tool.FuelAction)))
+	if err != nil {
+		log.Warn("Tool call failed", zap.Error(err))
+		return toolError(call, fmt.Sprintf("tool call failed: %v", err)), nil
+	}
+
+	var response struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(reply.Value, &response); err != nil {
+		return toolError(call, "tool returned an invalid response"), nil
+	}
+	if !response.Success {
+		message := "tool call failed"
+		if response.Error != nil && response.Error.Message != "" {
+			message = response.Error.Message
+		}
+		return toolError(call, aiservice.DelimitUntrusted(call.Name, message)), nil
+	}
+
+	// Tool output is data from outside the persona, like the task itself
+	log.Info("Tool call completed")
+	return aiservice.ToolResult{ToolCallID: call.ID, Content: aiservice.DelimitUntrusted(call.Name, string(response.Data))}, nil
+}
+
+func toolError(call aiservice.ToolCall, message string) aiservice.ToolResult {
+	return aiservice.ToolResult{ToolCallID: call.ID, Content: message, IsError: true}
+}
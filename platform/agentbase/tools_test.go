@@ -0,0 +1,238 @@
+// FILE: platform/agentbase/tools_test.go
+package agentbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/orchestration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// scriptedAI returns its responses in order and records the requests it saw
+type scriptedAI struct {
+	responses []*aiservice.GenerateResponse
+	requests  []aiservice.GenerateRequest
+}
+
+func (s *scriptedAI) Generate(ctx context.Context, req *aiservice.GenerateRequest) (*aiservice.GenerateResponse, error) {
+	s.requests = append(s.requests, *req)
+	if len(s.responses) == 0 {
+		return nil, errors.New("no more scripted responses")
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	return resp, nil
+}
+
+func (s *scriptedAI) GenerateStream(ctx context.Context, req *aiservice.GenerateRequest) (<-chan aiservice.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *scriptedAI) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (s *scriptedAI) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan aiservice.StreamChunk, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (s *scriptedAI) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return nil, errors.New("not implemented")
+}
+
+// fakeInvoker answers adapter requests with a fixed reply
+type fakeInvoker struct {
+	reply  string
+	topics []string
+}
+
+func (f *fakeInvoker) Request(ctx context.Context, topic string, headers map[string]string, key, value []byte) (kafka.Message, error) {
+	f.topics = append(f.topics, topic)
+	return kafka.Message{Value: []byte(f.reply)}, nil
+}
+
+// meter wraps a scripted client with the default token rates and a small max_tokens,
+// so reservations stay well inside the test budgets
+func meter(ai aiservice.AIService) *MeteredAI {
+	return NewMeteredAI(ai, governance.NewTokenFuelPolicy(governance.DefaultTokenRates),
+		aiservice.TextGenerationOptions{Model: "claude-3-haiku-20240307", MaxTokens: 100}, "test")
+}
+
+func actionWith(headers map[string]string) *orchestration.ActionRequest {
+	return &orchestration.ActionRequest{Action: ActionAITextGenerate, Headers: headers}
+}
+
+func searchCall(id string) aiservice.ToolCall {
+	return aiservice.ToolCall{ID: id, Name: "web_search", Input: json.RawMessage(`{"query":"go generics"}`)}
+}
+
+func TestToolLoop_Run(t *testing.T) {
+	ai := &scriptedAI{responses: []*aiservice.GenerateResponse{
+		{StopReason: aiservice.StopReasonToolUse, ToolCalls: []aiservice.ToolCall{searchCall("call_1")}},
+		{Text: "Generics arrived in Go 1.18."},
+	}}
+	invoker := &fakeInvoker{reply: `{"success":true,"data":{"results":[{"title":"Go 1.18"}]}}`}
+	loop := NewToolLoop(meter(ai), invoker, DefaultTools, zap.NewNop())
+
+	headers := map[string]string{"correlation_id": "c1", governance.FuelHeader: "100"}
+	req := aiservice.PromptRequest("When did Go get generics?", aiservice.TextGenerationOptions{})
+
+	result, err := loop.Run(context.Background(), actionWith(headers), req, []string{"web_search"}, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Generics arrived in Go 1.18.", result.Text)
+	assert.Equal(t, 1, result.ToolCalls)
+	assert.Equal(t, 2, result.Iterations)
+	assert.Equal(t, []string{"system.adapter.web.search"}, invoker.topics)
+
+	// Only the requested tool is offered, and the result is fed back to the model
+	require.Len(t, ai.requests, 2)
+	require.Len(t, ai.requests[0].Tools, 1)
+	assert.Equal(t, "web_search", ai.requests[0].Tools[0].Name)
+	last := ai.requests[1].Messages[len(ai.requests[1].Messages)-1]
+	require.Len(t, last.ToolResults, 1)
+	assert.Equal(t, "call_1", last.ToolResults[0].ToolCallID)
+	assert.Equal(t, aiservice.DelimitUntrusted("web_search", `{"results":[{"title":"Go 1.18"}]}`), last.ToolResults[0].Content)
+
+	// The caller's request is left untouched
+	assert.Len(t, req.Messages, 1)
+
+	// The search was charged to the budget
+	assert.Equal(t, "95", headers[governance.FuelHeader])
+}
+
+func TestToolLoop_IterationCap(t *testing.T) {
+	ai := &scriptedAI{}
+	for i := 0; i < 3; i++ {
+		ai.responses = append(ai.responses, &aiservice.GenerateResponse{
+			StopReason: aiservice.StopReasonToolUse,
+			ToolCalls:  []aiservice.ToolCall{searchCall("call")},
+		})
+	}
+	invoker := &fakeInvoker{reply: `{"success":true,"data":{}}`}
+	loop := NewToolLoop(meter(ai), invoker, DefaultTools, zap.NewNop())
+
+	headers := map[string]string{governance.FuelHeader: "100"}
+	_, err := loop.Run(context.Background(), actionWith(headers), aiservice.PromptRequest("loop", aiservice.TextGenerationOptions{}), []string{"web_search"}, 2)
+	require.ErrorIs(t, err, ErrToolIterationsExceeded)
+	assert.Len(t, invoker.topics, 2)
+}
+
+func TestToolLoop_FuelExhausted(t *testing.T) {
+	ai := &scriptedAI{responses: []*aiservice.GenerateResponse{
+		{StopReason: aiservice.StopReasonToolUse, ToolCalls: []aiservice.ToolCall{searchCall("call_1")}},
+	}}
+	invoker := &fakeInvoker{reply: `{"success":true,"data":{}}`}
+	loop := NewToolLoop(meter(ai), invoker, DefaultTools, zap.NewNop())
+
+	headers := map[string]string{governance.FuelHeader: "3"}
+	_, err := loop.Run(context.Background(), actionWith(headers), aiservice.PromptRequest("search", aiservice.TextGenerationOptions{}), []string{"web_search"}, 0)
+	require.ErrorIs(t, err, ErrToolFuelExhausted)
+	assert.Empty(t, invoker.topics)
+	assert.Equal(t, "3", headers[governance.FuelHeader])
+}
+
+func TestToolLoop_AdapterFailureGoesBackToModel(t *testing.T) {
+	ai := &scriptedAI{responses: []*aiservice.GenerateResponse{
+		{StopReason: aiservice.StopReasonToolUse, ToolCalls: []aiservice.ToolCall{searchCall("call_1")}},
+		{Text: "Search is unavailable right now."},
+	}}
+	invoker := &fakeInvoker{reply: `{"success":false,"error":{"code":"EXTERNAL_SERVICE_ERROR","message":"web-search is temporarily unavailable"}}`}
+	loop := NewToolLoop(meter(ai), invoker, DefaultTools, zap.NewNop())
+
+	headers := map[string]string{governance.FuelHeader: "100"}
+	result, err := loop.Run(context.Background(), actionWith(headers), aiservice.PromptRequest("search", aiservice.TextGenerationOptions{}), []string{"web_search"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Search is unavailable right now.", result.Text)
+
+	last := ai.requests[1].Messages[len(ai.requests[1].Messages)-1]
+	require.Len(t, last.ToolResults, 1)
+	assert.True(t, last.ToolResults[0].IsError)
+	assert.Equal(t, aiservice.DelimitUntrusted("web_search", "web-search is temporarily unavailable"), last.ToolResults[0].Content)
+}
+
+func TestToolLoop_UnknownTool(t *testing.T) {
+	loop := NewToolLoop(meter(&scriptedAI{}), &fakeInvoker{}, DefaultTools, zap.NewNop())
+	_, err := loop.Run(context.Background(), actionWith(map[string]string{}), aiservice.PromptRequest("x", aiservice.TextGenerationOptions{}), []string{"send_email"}, 0)
+	require.Error(t, err)
+}
+
+// slowInvoker never replies, like an adapter that is still working
+type slowInvoker struct{}
+
+func (slowInvoker) Request(ctx context.Context, topic string, headers map[string]string, key, value []byte) (kafka.Message, error) {
+	<-ctx.Done()
+	return kafka.Message{}, fmt.Errorf("%w (request_id r1)", kafka.ErrRequestTimeout)
+}
+
+func TestToolLoop_TimeoutIsNotCharged(t *testing.T) {
+	ai := &scriptedAI{responses: []*aiservice.GenerateResponse{
+		{StopReason: aiservice.StopReasonToolUse, ToolCalls: []aiservice.ToolCall{searchCall("call_1")}},
+		{Text: "Search timed out."},
+	}}
+	tools := WithToolTimeouts(DefaultTools, map[string]interface{}{"web_search": 0.01})
+	loop := NewToolLoop(meter(ai), slowInvoker{}, tools, zap.NewNop())
+
+	headers := map[string]string{governance.FuelHeader: "100"}
+	result, err := loop.Run(context.Background(), actionWith(headers), aiservice.PromptRequest("search", aiservice.TextGenerationOptions{}), []string{"web_search"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "Search timed out.", result.Text)
+
+	last := ai.requests[1].Messages[len(ai.requests[1].Messages)-1]
+	require.Len(t, last.ToolResults, 1)
+	assert.True(t, last.ToolResults[0].IsError)
+	assert.Equal(t, "100", headers[governance.FuelHeader])
+}
+
+func TestWithToolTimeouts(t *testing.T) {
+	timeouts := func(tools []Tool) map[string]time.Duration {
+		byName := make(map[string]time.Duration)
+		for _, tool := range tools {
+			byName[tool.Definition.Name] = tool.Timeout
+		}
+		return byName
+	}
+
+	// Image generation waits longer than the default by itself
+	assert.Equal(t, map[string]time.Duration{"web_search": 0, "generate_image": 2 * time.Minute}, timeouts(DefaultTools))
+
+	tools := WithToolTimeouts(DefaultTools, map[string]interface{}{"web_search": 10.0, "generate_image": "soon", "send_email": 5.0})
+	assert.Equal(t, map[string]time.Duration{"web_search": 10 * time.Second, "generate_image": 2 * time.Minute}, timeouts(tools))
+
+	// YAML config decodes whole numbers as ints
+	tools = WithToolTimeouts(DefaultTools, map[string]interface{}{"generate_image": 300})
+	assert.Equal(t, 5*time.Minute, timeouts(tools)["generate_image"])
+	assert.Zero(t, DefaultTools[0].Timeout)
+}
+
+func TestMeteredAI_SettlesOnUsage(t *testing.T) {
+	ai := &scriptedAI{responses: []*aiservice.GenerateResponse{
+		{Text: "done", Model: "claude-3-haiku-20240307", Usage: aiservice.Usage{InputTokens: 2000, OutputTokens: 400}},
+	}}
+	headers := map[string]string{governance.FuelHeader: "50"}
+
+	_, err := meter(ai).Generate(context.Background(), actionWith(headers), aiservice.PromptRequest("hi", aiservice.TextGenerationOptions{}))
+	require.NoError(t, err)
+
+	// 2000 input tokens at 1/1K plus 400 output tokens at 5/1K
+	assert.Equal(t, "46", headers[governance.FuelHeader])
+}
+
+func TestMeteredAI_InsufficientFuel(t *testing.T) {
+	ai := &scriptedAI{responses: []*aiservice.GenerateResponse{{Text: "unreachable"}}}
+	headers := map[string]string{governance.FuelHeader: "0"}
+
+	_, err := meter(ai).Generate(context.Background(), actionWith(headers), aiservice.PromptRequest("hi", aiservice.TextGenerationOptions{}))
+	require.ErrorIs(t, err, governance.ErrInsufficientFuel)
+	assert.Empty(t, ai.requests)
+}
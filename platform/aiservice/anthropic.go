@@ -4,113 +4,387 @@ package aiservice
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/resilience"
 )
 
+const anthropicBaseURL = "https://api.anthropic.com"
+
 // AnthropicClient implements the AIService interface for Anthropic's Claude
 type AnthropicClient struct {
 	apiKey     string
-	model      string
-	httpClient *http.Client
+	baseURL    string
+	defaults   TextGenerationOptions // From the ai_service config section
+	httpClient resilience.HTTPDoer
+	limiter    *tokenBucket
+	retry      RetryConfig
 }
 
-// NewAnthropicClient creates a new Anthropic client
+// NewAnthropicClient creates a new Anthropic client.
+// model, temperature, max_tokens and stop_sequences in config become the client defaults.
+// timeout_seconds, requests_per_minute and max_retries tune the HTTP client.
 func NewAnthropicClient(ctx context.Context, config map[string]interface{}) (*AnthropicClient, error) {
-	apiKeyEnvVar := config["api_key_env_var"].(string)
+	apiKeyEnvVar, _ := config["api_key_env_var"].(string)
 	apiKey := os.Getenv(apiKeyEnvVar)
 	if apiKey == "" {
 		return nil, fmt.Errorf("API key not found in environment variable %s", apiKeyEnvVar)
 	}
 
-	model := config["model"].(string)
+	defaults := OptionsFromMap(config).WithDefaults(TextGenerationOptions{
+		Temperature: Float(DefaultTemperature),
+		MaxTokens:   DefaultMaxTokens,
+	})
+	if defaults.Model == "" {
+		return nil, fmt.Errorf("ai_service model is required")
+	}
+
+	timeout := defaultRequestTimeout
+	if v, ok := toFloat(config["timeout_seconds"]); ok && v > 0 {
+		timeout = time.Duration(v * float64(time.Second))
+	}
+	requestsPerMinute := defaultRequestsPerMinute
+	if v, ok := toFloat(config["requests_per_minute"]); ok && v > 0 {
+		requestsPerMinute = int(v)
+	}
 
 	return &AnthropicClient{
 		apiKey:     apiKey,
-		model:      model,
-		httpClient: &http.Client{},
+		baseURL:    anthropicBaseURL,
+		defaults:   defaults,
+		httpClient: newBreakerClient("anthropic", &http.Client{Timeout: timeout}),
+		limiter:    limiterFor(apiKey, requestsPerMinute),
+		retry:      retryConfigFromMap(config),
 	}, nil
 }
 
-// GenerateText generates text using Claude
-func (c *AnthropicClient) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
-	// Build request
-	requestBody := map[string]interface{}{
-		"model":       c.model,
-		"max_tokens":  2048,
-		"temperature": 0.7,
-		"messages": []map[string]string{
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-	}
-
-	// Override with provided options
-	if options != nil {
-		if maxTokens, ok := options["max_tokens"]; ok {
-			requestBody["max_tokens"] = maxTokens
+// anthropicRequest is the Messages API request body
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Tools         []ToolDefinition   `json:"tools,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+// anthropicMessage holds either plain string content or a list of content blocks
+type anthropicMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+// anthropicBlock is a content block in requests and responses
+type anthropicBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+// anthropicImageSource is inline base64 image data or a URL
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// toAnthropicMessage converts a message, using content blocks only when tools or images
+// are involved
+func toAnthropicMessage(msg Message) (anthropicMessage, error) {
+	if len(msg.ToolCalls) == 0 && len(msg.ToolResults) == 0 && len(msg.Images) == 0 {
+		return anthropicMessage{Role: msg.Role, Content: msg.Content}, nil
+	}
+
+	blocks := make([]anthropicBlock, 0, 1+len(msg.Images)+len(msg.ToolCalls)+len(msg.ToolResults))
+	// Tool results must come first in a user turn
+	for _, result := range msg.ToolResults {
+		blocks = append(blocks, anthropicBlock{
+			Type:      "tool_result",
+			ToolUseID: result.ToolCallID,
+			Content:   result.Content,
+			IsError:   result.IsError,
+		})
+	}
+	// Images work best before the text that refers to them
+	for _, img := range msg.Images {
+		if isRemoteURL(img.URI) && len(img.Data) == 0 {
+			blocks = append(blocks, anthropicBlock{Type: "image", Source: &anthropicImageSource{Type: "url", URL: img.URI}})
+			continue
 		}
-		if temperature, ok := options["temperature"]; ok {
-			requestBody["temperature"] = temperature
+		mediaType, data, err := img.inline()
+		if err != nil {
+			return anthropicMessage{}, err
 		}
+		blocks = append(blocks, anthropicBlock{Type: "image", Source: &anthropicImageSource{
+			Type:      "base64",
+			MediaType: mediaType,
+			Data:      base64.StdEncoding.EncodeToString(data),
+		}})
 	}
-
-	jsonBody, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+	if msg.Content != "" {
+		blocks = append(blocks, anthropicBlock{Type: "text", Text: msg.Content})
+	}
+	for _, call := range msg.ToolCalls {
+		input := call.Input
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: input})
 	}
+	return anthropicMessage{Role: msg.Role, Content: blocks}, nil
+}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonBody))
+// Generate generates text using Claude
+func (c *AnthropicClient) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	body, err := c.buildRequest(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	// Execute request
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.sendMessages(ctx, body)
 	if err != nil {
-		return "", fmt.Errorf("failed to execute request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	// Read response
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Parse response
 	var response struct {
-		Content []struct {
-			Text string `json:"text"`
-		} `json:"content"`
+		Model      string           `json:"model"`
+		StopReason string           `json:"stop_reason"`
+		Content    []anthropicBlock `json:"content"`
+		Usage      Usage            `json:"usage"`
 	}
 
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
 	if len(response.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
+		return nil, fmt.Errorf("no content in response")
+	}
+
+	var text strings.Builder
+	var toolCalls []ToolCall
+	for _, block := range response.Content {
+		switch block.Type {
+		case "", "text":
+			text.WriteString(block.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, ToolCall{ID: block.ID, Name: block.Name, Input: block.Input})
+		}
 	}
 
-	return response.Content[0].Text, nil
+	return &GenerateResponse{
+		Text:       text.String(),
+		Model:      response.Model,
+		StopReason: response.StopReason,
+		ToolCalls:  toolCalls,
+		Usage:      response.Usage,
+	}, nil
+}
+
+// GenerateText generates text for a single prompt
+func (c *AnthropicClient) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	resp, err := c.Generate(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// StreamText streams text for a single prompt
+func (c *AnthropicClient) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return c.GenerateStream(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+}
+
+// GenerateStream generates text using Claude's streaming API, sending text deltas as they arrive.
+// Tool calls are not supported on streams; use Generate for tool use.
+func (c *AnthropicClient) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	if req != nil && len(req.Tools) > 0 {
+		return nil, fmt.Errorf("tools are not supported when streaming")
+	}
+	body, err := c.buildRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	body.Stream = true
+
+	resp, err := c.sendMessages(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := make(chan StreamChunk)
+	go func() {
+		defer close(stream)
+		defer resp.Body.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case stream <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var usage Usage
+		var model string
+		events := newSSEReader(resp.Body)
+		for {
+			event, err := events.Next()
+			if err == io.EOF {
+				send(StreamChunk{Err: fmt.Errorf("stream ended before message_stop")})
+				return
+			}
+			if err != nil {
+				send(StreamChunk{Err: fmt.Errorf("failed to read stream: %w", err)})
+				return
+			}
+
+			switch event.Event {
+			case "message_start":
+				var payload struct {
+					Message struct {
+						Model string `json:"model"`
+						Usage Usage  `json:"usage"`
+					} `json:"message"`
+				}
+				if err := json.Unmarshal([]byte(event.Data), &payload); err == nil {
+					model = payload.Message.Model
+					usage.InputTokens = payload.Message.Usage.InputTokens
+				}
+			case "message_delta":
+				// Output tokens are cumulative
+				var payload struct {
+					Usage Usage `json:"usage"`
+				}
+				if err := json.Unmarshal([]byte(event.Data), &payload); err == nil && payload.Usage.OutputTokens > 0 {
+					usage.OutputTokens = payload.Usage.OutputTokens
+				}
+			case "content_block_delta":
+				var payload struct {
+					Delta struct {
+						Type string `json:"type"`
+						Text string `json:"text"`
+					} `json:"delta"`
+				}
+				if err := json.Unmarshal([]byte(event.Data), &payload); err != nil {
+					send(StreamChunk{Err: fmt.Errorf("failed to parse stream event: %w", err)})
+					return
+				}
+				if payload.Delta.Type == "text_delta" && payload.Delta.Text != "" {
+					if !send(StreamChunk{Text: payload.Delta.Text}) {
+						return
+					}
+				}
+			case "message_stop":
+				send(StreamChunk{Usage: &usage, Model: model})
+				return
+			case "error":
+				var payload struct {
+					Error struct {
+						Type    string `json:"type"`
+						Message string `json:"message"`
+					} `json:"error"`
+				}
+				json.Unmarshal([]byte(event.Data), &payload)
+				send(StreamChunk{Err: fmt.Errorf("stream error %s: %s", payload.Error.Type, payload.Error.Message)})
+				return
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+// buildRequest converts a GenerateRequest into a Messages API request, applying client defaults
+func (c *AnthropicClient) buildRequest(req *GenerateRequest) (*anthropicRequest, error) {
+	if req == nil || len(req.Messages) == 0 {
+		return nil, fmt.Errorf("at least one message is required")
+	}
+	for i, msg := range req.Messages {
+		if msg.Role != RoleUser && msg.Role != RoleAssistant {
+			return nil, fmt.Errorf("message %d has invalid role %q", i, msg.Role)
+		}
+	}
+
+	messages := make([]anthropicMessage, 0, len(req.Messages))
+	for i, msg := range req.Messages {
+		converted, err := toAnthropicMessage(msg)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		messages = append(messages, converted)
+	}
+
+	opts := req.Options.WithDefaults(c.defaults)
+	return &anthropicRequest{
+		Model:         opts.Model,
+		MaxTokens:     opts.MaxTokens,
+		Temperature:   opts.Temperature,
+		System:        req.System,
+		Messages:      messages,
+		Tools:         req.Tools,
+		StopSequences: opts.StopSequences,
+	}, nil
+}
+
+// sendMessages posts a request to the Messages API, retrying rate limits and overloads.
+// Non-200 responses are returned as DomainErrors.
+func (c *AnthropicClient) sendMessages(ctx context.Context, requestBody *anthropicRequest) (*http.Response, error) {
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	return doWithRetry(ctx, c.httpClient, c.limiter, c.retry, "anthropic", func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/v1/messages", bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", c.apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+		if requestBody.Stream {
+			req.Header.Set("Accept", eventStream)
+		}
+		return req, nil
+	})
 }
 
-// GenerateEmbedding generates embeddings (not implemented for Anthropic)
+// GenerateEmbedding generates embeddings (not implemented for Anthropic).
+// Configure an ai_service.embedding section to use another provider for embeddings.
 func (c *AnthropicClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
-	return nil, fmt.Errorf("embedding generation not supported by Anthropic")
+	return nil, fmt.Errorf("embedding generation not supported by Anthropic; configure ai_service.embedding")
 }
@@ -0,0 +1,209 @@
+// FILE: platform/aiservice/anthropic_test.go
+package aiservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *AnthropicClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &AnthropicClient{
+		apiKey:     "test-key",
+		baseURL:    server.URL,
+		defaults:   TextGenerationOptions{Model: "test-model", MaxTokens: DefaultMaxTokens},
+		httpClient: newBreakerClient("anthropic", server.Client()),
+		limiter:    newTokenBucket(6000),
+		retry:      RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Second},
+	}
+}
+
+func TestGenerate_RequestBody(t *testing.T) {
+	var body anthropicRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"model":"persona-model","stop_reason":"stop_sequence","content":[{"type":"text","text":"Hi "},{"type":"text","text":"there"}],"usage":{"input_tokens":30,"output_tokens":2}}`)
+	})
+	client.defaults.Temperature = Float(0.7)
+
+	// Persona core logic as it arrives from JSON
+	options := OptionsFromMap(map[string]interface{}{
+		"model":          "persona-model",
+		"temperature":    0.0,
+		"stop_sequences": []interface{}{"END"},
+	})
+
+	resp, err := client.Generate(context.Background(), &GenerateRequest{
+		System: "Be brief.",
+		Messages: []Message{
+			{Role: RoleUser, Content: "Hello"},
+			{Role: RoleAssistant, Content: "Hello! How can I help?"},
+			{Role: RoleUser, Content: "Say hi"},
+		},
+		Options: options,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Hi there", resp.Text)
+	assert.Equal(t, "stop_sequence", resp.StopReason)
+	assert.Equal(t, Usage{InputTokens: 30, OutputTokens: 2}, resp.Usage)
+
+	assert.Equal(t, "persona-model", body.Model)
+	assert.Equal(t, "Be brief.", body.System)
+	assert.Len(t, body.Messages, 3)
+	assert.Equal(t, []string{"END"}, body.StopSequences)
+	require.NotNil(t, body.Temperature)
+	assert.Equal(t, 0.0, *body.Temperature, "an explicit zero temperature must not fall back to the default")
+	assert.Equal(t, DefaultMaxTokens, body.MaxTokens)
+}
+
+func TestGenerate_ToolUse(t *testing.T) {
+	var body map[string]interface{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"stop_reason":"tool_use","content":[{"type":"text","text":"Searching."},{"type":"tool_use","id":"toolu_2","name":"web_search","input":{"query":"weather"}}]}`)
+	})
+
+	resp, err := client.Generate(context.Background(), &GenerateRequest{
+		Messages: []Message{
+			{Role: RoleUser, Content: "What's new?"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "toolu_1", Name: "web_search", Input: json.RawMessage(`{"query":"news"}`)}}},
+			{Role: RoleUser, ToolResults: []ToolResult{{ToolCallID: "toolu_1", Content: "no results", IsError: true}}},
+		},
+		Tools: []ToolDefinition{{Name: "web_search", InputSchema: map[string]interface{}{"type": "object"}}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, StopReasonToolUse, resp.StopReason)
+	assert.Equal(t, "Searching.", resp.Text)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "toolu_2", resp.ToolCalls[0].ID)
+	assert.JSONEq(t, `{"query":"weather"}`, string(resp.ToolCalls[0].Input))
+
+	// History is sent as content blocks
+	messages := body["messages"].([]interface{})
+	assert.Equal(t, "What's new?", messages[0].(map[string]interface{})["content"])
+	toolUse := messages[1].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_use", toolUse["type"])
+	toolResult := messages[2].(map[string]interface{})["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_result", toolResult["type"])
+	assert.Equal(t, "toolu_1", toolResult["tool_use_id"])
+	assert.Equal(t, true, toolResult["is_error"])
+	assert.Len(t, body["tools"], 1)
+}
+
+func TestGenerate_InvalidRole(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request should not be sent")
+	})
+
+	_, err := client.Generate(context.Background(), &GenerateRequest{
+		Messages: []Message{{Role: "system", Content: "nope"}},
+	})
+	require.Error(t, err)
+}
+
+func TestOptionsFromMap(t *testing.T) {
+	// YAML config decodes numbers as ints
+	opts := OptionsFromMap(map[string]interface{}{
+		"model":       "claude-3-opus-20240229",
+		"temperature": 0.2,
+		"max_tokens":  1024,
+	})
+	assert.Equal(t, "claude-3-opus-20240229", opts.Model)
+	require.NotNil(t, opts.Temperature)
+	assert.Equal(t, 0.2, *opts.Temperature)
+	assert.Equal(t, 1024, opts.MaxTokens)
+
+	merged := TextGenerationOptions{MaxTokens: 10}.WithDefaults(opts)
+	assert.Equal(t, 10, merged.MaxTokens)
+	assert.Equal(t, "claude-3-opus-20240229", merged.Model)
+	assert.Equal(t, 0.2, *merged.Temperature)
+
+	// Short names in older persona configs resolve to dated IDs
+	opts = OptionsFromMap(map[string]interface{}{
+		"model":           "claude-3-opus",
+		"fallback_models": []interface{}{"claude-3-haiku", "gpt-4o"},
+	})
+	assert.Equal(t, "claude-3-opus-20240229", opts.Model)
+	assert.Equal(t, []string{"claude-3-haiku-20240307", "gpt-4o"}, opts.FallbackModels)
+}
+
+func TestStreamText(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, true, body["stream"])
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-3-haiku-20240307\",\"usage\":{\"input_tokens\":12,\"output_tokens\":1}}}\n\n")
+		fmt.Fprint(w, ": ping\n\n")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n")
+		fmt.Fprint(w, "event: content_block_delta\r\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\", world\"}}\r\n\r\n")
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":4}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	})
+
+	stream, err := client.StreamText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+
+	var deltas []string
+	result, err := CollectStream(stream, func(delta string) { deltas = append(deltas, delta) })
+	require.NoError(t, err)
+	assert.Equal(t, "Hello, world", result.Text)
+	assert.Equal(t, []string{"Hello", ", world"}, deltas)
+	assert.Equal(t, Usage{InputTokens: 12, OutputTokens: 4}, result.Usage)
+	assert.Equal(t, "claude-3-haiku-20240307", result.Model)
+}
+
+func TestStreamText_OutlastsTimeout(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, eventStream, r.Header.Get("Accept"))
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"Hello\"}}\n\n")
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"output_tokens\":1}}\n\n")
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	})
+	client.httpClient = newBreakerClient("anthropic", &http.Client{Timeout: 100 * time.Millisecond})
+
+	stream, err := client.StreamText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+	result, err := CollectStream(stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", result.Text)
+}
+
+func TestStreamText_ErrorEvent(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"delta\":{\"type\":\"text_delta\",\"text\":\"partial\"}}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	})
+
+	stream, err := client.StreamText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+
+	result, err := CollectStream(stream, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overloaded_error")
+	assert.Equal(t, "partial", result.Text)
+}
+
+func TestStreamText_HTTPError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"bad request"}`, http.StatusBadRequest)
+	})
+
+	_, err := client.StreamText(context.Background(), "hi", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "400")
+}
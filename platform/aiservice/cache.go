@@ -0,0 +1,407 @@
+// FILE: platform/aiservice/cache.go
+package aiservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/observability"
+	"go.uber.org/zap"
+)
+
+// Response cache defaults
+const (
+	DefaultCacheTTL            = time.Hour
+	DefaultSimilarityThreshold = 0.95
+	DefaultMemoryCacheEntries  = 1000
+)
+
+// Cache lookup results, as recorded in AIResponseCacheLookups
+const (
+	cacheHit         = "hit"
+	cacheSemanticHit = "semantic_hit"
+	cacheMiss        = "miss"
+)
+
+// CachePolicy is a persona's response_cache setting
+type CachePolicy struct {
+	TTL       time.Duration
+	Semantic  bool    // Also reuse answers to prompts with a similar embedding
+	Threshold float64 // Least cosine similarity accepted as a semantic match
+
+	// Answers are only reused for the same client and persona instance. Set from the
+	// workflow headers by agentbase.MeteredAI; requests without a client are not cached.
+	ClientID        string
+	AgentInstanceID string
+}
+
+// cachePolicyFromMap reads response_cache, which is either true or an object with
+// enabled, ttl_seconds, semantic and similarity_threshold
+func cachePolicyFromMap(v interface{}) *CachePolicy {
+	policy := &CachePolicy{TTL: DefaultCacheTTL, Threshold: DefaultSimilarityThreshold}
+	switch c := v.(type) {
+	case bool:
+		if c {
+			return policy
+		}
+	case map[string]interface{}:
+		if enabled, ok := c["enabled"].(bool); ok && !enabled {
+			return nil
+		}
+		if ttl, ok := toFloat(c["ttl_seconds"]); ok && ttl > 0 {
+			policy.TTL = time.Duration(ttl * float64(time.Second))
+		}
+		policy.Semantic, _ = c["semantic"].(bool)
+		if t, ok := toFloat(c["similarity_threshold"]); ok && t > 0 && t <= 1 {
+			policy.Threshold = t
+		}
+		return policy
+	}
+	return nil
+}
+
+// CacheEntry is a stored response
+type CacheEntry struct {
+	ClientID  string
+	Key       string    // Exact match key: tenant, model, options, system prompt, messages and tools
+	Scope     string    // Requests whose answers are interchangeable when their prompts are similar
+	Prompt    string    // The last user message
+	Embedding []float32 // Of Prompt, for semantic matching; nil when not computed
+	Response  *GenerateResponse
+	ExpiresAt time.Time
+}
+
+// ResponseCache stores responses by exact key
+type ResponseCache interface {
+	// Get returns the unexpired response stored under key, or nil
+	Get(ctx context.Context, key string) (*GenerateResponse, error)
+	Put(ctx context.Context, entry *CacheEntry) error
+}
+
+// SemanticCache is a ResponseCache that can also find answers to similar prompts
+type SemanticCache interface {
+	ResponseCache
+	// Nearest returns the unexpired response in scope whose prompt embedding is most
+	// similar to embedding, or nil when none reaches threshold
+	Nearest(ctx context.Context, scope string, embedding []float32, threshold float64) (*GenerateResponse, error)
+}
+
+// CachingAI answers repeated requests from a cache. Only requests whose options carry a
+// CachePolicy with a client are cached, and only final answers are stored; tool calls
+// always reach the model. Answers are never shared between clients or persona instances.
+// Cache failures are logged and never fail a call.
+type CachingAI struct {
+	client   AIService
+	cache    ResponseCache
+	embedder EmbeddingService // Optional; needed for semantic matching
+	logger   *zap.Logger
+}
+
+// NewCachingAI wraps client with cache. Semantic matching needs an embedder and a
+// SemanticCache; without them policies that ask for it use exact matching only.
+func NewCachingAI(client AIService, cache ResponseCache, embedder EmbeddingService, logger *zap.Logger) *CachingAI {
+	return &CachingAI{client: client, cache: cache, embedder: embedder, logger: logger}
+}
+
+// cacheLookup is a request's cache identity and the response found for it, if any
+type cacheLookup struct {
+	policy    *CachePolicy
+	key       string
+	scope     string
+	prompt    string
+	embedding []float32
+	resp      *GenerateResponse
+}
+
+// Generate serves req from the cache, or calls the client and stores its answer
+func (c *CachingAI) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	if !cacheable(req) {
+		return c.client.Generate(ctx, req)
+	}
+	lookup := c.lookup(ctx, req)
+	if lookup.resp != nil {
+		return lookup.resp, nil
+	}
+
+	resp, err := c.client.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, lookup, resp)
+	return resp, nil
+}
+
+// GenerateStream replays a cached answer as a single chunk, or streams from the client
+// and stores the answer once the stream completes
+func (c *CachingAI) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	if !cacheable(req) {
+		return c.client.GenerateStream(ctx, req)
+	}
+	lookup := c.lookup(ctx, req)
+	if lookup.resp != nil {
+		stream := make(chan StreamChunk, 2)
+		stream <- StreamChunk{Text: lookup.resp.Text}
+		stream <- StreamChunk{Usage: &Usage{}, Model: lookup.resp.Model}
+		close(stream)
+		return stream, nil
+	}
+
+	stream, err := c.client.GenerateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		var text []byte
+		for chunk := range stream {
+			if chunk.Usage != nil && chunk.Err == nil {
+				// Stored before forwarding so the answer is cached when the caller sees the end
+				c.store(ctx, lookup, &GenerateResponse{Text: string(text) + chunk.Text, Model: chunk.Model, Usage: *chunk.Usage})
+			}
+			text = append(text, chunk.Text...)
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				discardStream(stream)
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// GenerateText sends a single prompt, cached when options set response_cache
+func (c *CachingAI) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	resp, err := c.Generate(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// StreamText streams a single prompt, cached when options set response_cache
+func (c *CachingAI) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return c.GenerateStream(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+}
+
+// GenerateEmbedding is not cached
+func (c *CachingAI) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return c.client.GenerateEmbedding(ctx, text)
+}
+
+// cacheable reports whether a request opted in to caching and says whose it is
+func cacheable(req *GenerateRequest) bool {
+	return req.Options.Cache != nil && req.Options.Cache.ClientID != ""
+}
+
+func (c *CachingAI) lookup(ctx context.Context, req *GenerateRequest) *cacheLookup {
+	lookup := &cacheLookup{
+		policy: req.Options.Cache,
+		key:    cacheKey(req),
+		scope:  cacheScope(req),
+		prompt: semanticPrompt(req),
+	}
+	model := req.Options.Model
+
+	resp, err := c.cache.Get(ctx, lookup.key)
+	if err != nil {
+		c.logger.Warn("Response cache lookup failed", zap.Error(err))
+	}
+	if resp != nil {
+		observability.AIResponseCacheLookups.WithLabelValues(model, cacheHit).Inc()
+		lookup.resp = cachedResponse(resp)
+		return lookup
+	}
+
+	semantic, ok := c.cache.(SemanticCache)
+	if lookup.policy.Semantic && ok && c.embedder != nil && lookup.prompt != "" {
+		lookup.embedding, err = c.embedder.GenerateEmbedding(ctx, lookup.prompt)
+		if err != nil {
+			c.logger.Warn("Failed to embed prompt for response cache", zap.Error(err))
+		} else {
+			resp, err = semantic.Nearest(ctx, lookup.scope, lookup.embedding, lookup.policy.Threshold)
+			if err != nil {
+				c.logger.Warn("Semantic response cache lookup failed", zap.Error(err))
+			}
+			if resp != nil {
+				observability.AIResponseCacheLookups.WithLabelValues(model, cacheSemanticHit).Inc()
+				lookup.resp = cachedResponse(resp)
+				return lookup
+			}
+		}
+	}
+
+	observability.AIResponseCacheLookups.WithLabelValues(model, cacheMiss).Inc()
+	return lookup
+}
+
+func (c *CachingAI) store(ctx context.Context, lookup *cacheLookup, resp *GenerateResponse) {
+	if resp.StopReason == StopReasonToolUse {
+		return
+	}
+	err := c.cache.Put(ctx, &CacheEntry{
+		ClientID:  lookup.policy.ClientID,
+		Key:       lookup.key,
+		Scope:     lookup.scope,
+		Prompt:    lookup.prompt,
+		Embedding: lookup.embedding,
+		Response:  resp,
+		ExpiresAt: time.Now().Add(lookup.policy.TTL),
+	})
+	if err != nil {
+		c.logger.Warn("Failed to store response in cache", zap.Error(err))
+	}
+}
+
+// cachedResponse marks a stored response as served from cache. No tokens were used.
+func cachedResponse(resp *GenerateResponse) *GenerateResponse {
+	hit := *resp
+	hit.Usage = Usage{}
+	hit.Cached = true
+	return &hit
+}
+
+// cacheKey hashes whose request it is and everything that affects the model's answer
+func cacheKey(req *GenerateRequest) string {
+	return hashJSON(struct {
+		ClientID        string           `json:"client_id"`
+		AgentInstanceID string           `json:"agent_instance_id"`
+		Model           string           `json:"model"`
+		Temperature     *float64         `json:"temperature"`
+		MaxTokens       int              `json:"max_tokens"`
+		StopSequences   []string         `json:"stop_sequences"`
+		System          string           `json:"system"`
+		Messages        []Message        `json:"messages"`
+		Tools           []ToolDefinition `json:"tools"`
+	}{req.Options.Cache.ClientID, req.Options.Cache.AgentInstanceID, req.Options.Model, req.Options.Temperature, req.Options.MaxTokens, req.Options.StopSequences,
+		req.System, req.Messages, req.Tools})
+}
+
+// cacheScope hashes everything but the last message, so semantic matches only pair
+// prompts the same tenant asked of the same model in the same context
+func cacheScope(req *GenerateRequest) string {
+	history := req.Messages
+	if len(history) > 0 {
+		history = history[:len(history)-1]
+	}
+	return hashJSON(struct {
+		ClientID        string           `json:"client_id"`
+		AgentInstanceID string           `json:"agent_instance_id"`
+		Model           string           `json:"model"`
+		System          string           `json:"system"`
+		Messages        []Message        `json:"messages"`
+		Tools           []ToolDefinition `json:"tools"`
+	}{req.Options.Cache.ClientID, req.Options.Cache.AgentInstanceID, req.Options.Model, req.System, history, req.Tools})
+}
+
+// semanticPrompt is the text compared by semantic matching: the last message, when it
+// is a plain user message
+func semanticPrompt(req *GenerateRequest) string {
+	if len(req.Messages) == 0 {
+		return ""
+	}
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role != RoleUser || len(last.ToolResults) > 0 {
+		return ""
+	}
+	return last.Content
+}
+
+func hashJSON(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryCache is an in-process ResponseCache with semantic matching. When full it
+// evicts the oldest entries.
+type MemoryCache struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*CacheEntry
+	order   []string // Keys in insertion order
+}
+
+// NewMemoryCache creates a cache holding up to maxEntries responses
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMemoryCacheEntries
+	}
+	return &MemoryCache{maxEntries: maxEntries, entries: make(map[string]*CacheEntry)}
+}
+
+// Get returns the response stored under key
+func (m *MemoryCache) Get(ctx context.Context, key string) (*GenerateResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Expired entries stay until evicted or replaced
+	entry, ok := m.entries[key]
+	if !ok || time.Now().After(entry.ExpiresAt) {
+		return nil, nil
+	}
+	return entry.Response, nil
+}
+
+// Put stores an entry, evicting the oldest when the cache is full
+func (m *MemoryCache) Put(ctx context.Context, entry *CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.entries[entry.Key]; !exists {
+		for len(m.entries) >= m.maxEntries && len(m.order) > 0 {
+			delete(m.entries, m.order[0])
+			m.order = m.order[1:]
+		}
+		m.order = append(m.order, entry.Key)
+	}
+	m.entries[entry.Key] = entry
+	return nil
+}
+
+// Nearest scans the entries in scope for the most similar prompt
+func (m *MemoryCache) Nearest(ctx context.Context, scope string, embedding []float32, threshold float64) (*GenerateResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var best *CacheEntry
+	bestSimilarity := threshold
+	for _, entry := range m.entries {
+		if entry.Scope != scope || entry.Embedding == nil || now.After(entry.ExpiresAt) {
+			continue
+		}
+		if similarity := CosineSimilarity(embedding, entry.Embedding); similarity >= bestSimilarity {
+			best, bestSimilarity = entry, similarity
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best.Response, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two embeddings, or 0 when they
+// cannot be compared
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
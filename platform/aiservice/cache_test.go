@@ -0,0 +1,233 @@
+// FILE: platform/aiservice/cache_test.go
+package aiservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fixedEmbedder returns the embedding listed for each text
+type fixedEmbedder map[string][]float32
+
+func (f fixedEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return f[text], nil
+}
+
+func cachedRequest(prompt string, policy *CachePolicy) *GenerateRequest {
+	if policy != nil && policy.ClientID == "" {
+		tenant := *policy
+		tenant.ClientID = "acme"
+		policy = &tenant
+	}
+	req := PromptRequest(prompt, TextGenerationOptions{Model: "echo-1", Cache: policy})
+	req.System = "You review copy."
+	return req
+}
+
+func TestCachingAIExactMatch(t *testing.T) {
+	client := &echoAI{}
+	ai := NewCachingAI(client, NewMemoryCache(10), nil, zap.NewNop())
+	ctx := context.Background()
+	policy := &CachePolicy{TTL: DefaultCacheTTL}
+
+	first, err := ai.Generate(ctx, cachedRequest("review this", policy))
+	require.NoError(t, err)
+	assert.False(t, first.Cached)
+
+	second, err := ai.Generate(ctx, cachedRequest("review this", policy))
+	require.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Equal(t, "REVIEW THIS", second.Text)
+	assert.Equal(t, Usage{}, second.Usage)
+	assert.Equal(t, 1, client.calls)
+
+	// Different options are a different request
+	other := cachedRequest("review this", policy)
+	other.Options.Temperature = Float(0.9)
+	_, err = ai.Generate(ctx, other)
+	require.NoError(t, err)
+	assert.Equal(t, 2, client.calls)
+
+	// Requests without a policy are never cached
+	_, err = ai.Generate(ctx, cachedRequest("review this", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 3, client.calls)
+}
+
+func TestCachingAIPartitionsByTenant(t *testing.T) {
+	client := &echoAI{}
+	embedder := fixedEmbedder{"review this": {1, 0, 0}}
+	ai := NewCachingAI(client, NewMemoryCache(10), embedder, zap.NewNop())
+	ctx := context.Background()
+	tenant := func(clientID, instanceID string) *CachePolicy {
+		return &CachePolicy{TTL: DefaultCacheTTL, Semantic: true, Threshold: 0.9, ClientID: clientID, AgentInstanceID: instanceID}
+	}
+
+	_, err := ai.Generate(ctx, cachedRequest("review this", tenant("acme", "a1")))
+	require.NoError(t, err)
+
+	// The same prompt from another client or persona instance reaches the model
+	resp, err := ai.Generate(ctx, cachedRequest("review this", tenant("globex", "a1")))
+	require.NoError(t, err)
+	assert.False(t, resp.Cached)
+	resp, err = ai.Generate(ctx, cachedRequest("review this", tenant("acme", "a2")))
+	require.NoError(t, err)
+	assert.False(t, resp.Cached)
+	assert.Equal(t, 3, client.calls)
+
+	resp, err = ai.Generate(ctx, cachedRequest("review this", tenant("globex", "a1")))
+	require.NoError(t, err)
+	assert.True(t, resp.Cached)
+
+	// Requests that do not say whose they are are never cached
+	_, err = ai.Generate(ctx, PromptRequest("review this", TextGenerationOptions{Model: "echo-1", Cache: &CachePolicy{TTL: DefaultCacheTTL}}))
+	require.NoError(t, err)
+	_, err = ai.Generate(ctx, PromptRequest("review this", TextGenerationOptions{Model: "echo-1", Cache: &CachePolicy{TTL: DefaultCacheTTL}}))
+	require.NoError(t, err)
+	assert.Equal(t, 5, client.calls)
+}
+
+func TestCachingAISemanticMatch(t *testing.T) {
+	client := &echoAI{}
+	embedder := fixedEmbedder{
+		"review this headline":     {1, 0, 0},
+		"please review the title":  {0.99, 0.1, 0},
+		"write a poem about rain":  {0, 0, 1},
+		"review this headline now": {1, 0, 0},
+	}
+	ai := NewCachingAI(client, NewMemoryCache(10), embedder, zap.NewNop())
+	ctx := context.Background()
+	policy := &CachePolicy{TTL: DefaultCacheTTL, Semantic: true, Threshold: 0.95}
+
+	_, err := ai.Generate(ctx, cachedRequest("review this headline", policy))
+	require.NoError(t, err)
+
+	resp, err := ai.Generate(ctx, cachedRequest("please review the title", policy))
+	require.NoError(t, err)
+	assert.True(t, resp.Cached)
+	assert.Equal(t, "REVIEW THIS HEADLINE", resp.Text)
+
+	resp, err = ai.Generate(ctx, cachedRequest("write a poem about rain", policy))
+	require.NoError(t, err)
+	assert.False(t, resp.Cached)
+
+	// Semantic matches stay within the same system prompt
+	req := cachedRequest("review this headline now", policy)
+	req.System = "You write slogans."
+	resp, err = ai.Generate(ctx, req)
+	require.NoError(t, err)
+	assert.False(t, resp.Cached)
+	assert.Equal(t, 3, client.calls)
+}
+
+func TestCachingAIStream(t *testing.T) {
+	client := &echoAI{}
+	ai := NewCachingAI(client, NewMemoryCache(10), nil, zap.NewNop())
+	ctx := context.Background()
+	policy := &CachePolicy{TTL: DefaultCacheTTL}
+
+	for i := 0; i < 2; i++ {
+		stream, err := ai.GenerateStream(ctx, cachedRequest("say hello", policy))
+		require.NoError(t, err)
+		result, err := CollectStream(stream, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "hello", result.Text)
+	}
+	assert.Equal(t, 1, client.calls)
+}
+
+// slowStreamAI streams chunks without watching the context, like a provider
+// blocked on a send, and closes done once it has sent them all
+type slowStreamAI struct {
+	echoAI
+	done chan struct{}
+}
+
+func (s *slowStreamAI) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	stream := make(chan StreamChunk)
+	go func() {
+		defer close(s.done)
+		defer close(stream)
+		for i := 0; i < 10; i++ {
+			stream <- StreamChunk{Text: "chunk "}
+		}
+		stream <- StreamChunk{Usage: &Usage{}, Model: "slow-1"}
+	}()
+	return stream, nil
+}
+
+// assertStreamAbandoned reads one chunk, cancels the caller's context and checks the
+// forwarder and the upstream stream both finish
+func assertStreamAbandoned(t *testing.T, wrap func(AIService) AIService) {
+	client := &slowStreamAI{done: make(chan struct{})}
+	ai := wrap(client)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stream, err := ai.GenerateStream(ctx, cachedRequest("stream slowly", &CachePolicy{TTL: DefaultCacheTTL}))
+	require.NoError(t, err)
+	<-stream
+	cancel()
+
+	select {
+	case <-client.done:
+	case <-time.After(time.Second):
+		t.Fatal("upstream stream was left blocked")
+	}
+	for {
+		select {
+		case _, ok := <-stream:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("forwarded stream was not closed")
+		}
+	}
+}
+
+func TestCachingAIStreamStopsWhenCallerLeaves(t *testing.T) {
+	cache := NewMemoryCache(10)
+	assertStreamAbandoned(t, func(client AIService) AIService {
+		return NewCachingAI(client, cache, nil, zap.NewNop())
+	})
+	assert.Empty(t, cache.entries, "unfinished streams are not cached")
+}
+
+func TestMemoryCacheEvictsOldest(t *testing.T) {
+	cache := NewMemoryCache(2)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Put(ctx, &CacheEntry{Key: key, Response: &GenerateResponse{Text: key},
+			ExpiresAt: time.Now().Add(time.Hour)}))
+	}
+
+	resp, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	assert.Nil(t, resp)
+	resp, err = cache.Get(ctx, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "c", resp.Text)
+}
+
+func TestOptionsFromMapResponseCache(t *testing.T) {
+	assert.Nil(t, OptionsFromMap(map[string]interface{}{}).Cache)
+	assert.Nil(t, OptionsFromMap(map[string]interface{}{"response_cache": false}).Cache)
+
+	policy := OptionsFromMap(map[string]interface{}{"response_cache": true}).Cache
+	require.NotNil(t, policy)
+	assert.Equal(t, DefaultCacheTTL, policy.TTL)
+	assert.False(t, policy.Semantic)
+
+	policy = OptionsFromMap(map[string]interface{}{"response_cache": map[string]interface{}{
+		"ttl_seconds": 60.0, "semantic": true, "similarity_threshold": 0.9,
+	}}).Cache
+	require.NotNil(t, policy)
+	assert.Equal(t, 60.0, policy.TTL.Seconds())
+	assert.True(t, policy.Semantic)
+	assert.Equal(t, 0.9, policy.Threshold)
+}
@@ -0,0 +1,503 @@
+// FILE: platform/aiservice/cassette.go
+package aiservice
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"go.uber.org/zap"
+)
+
+// ErrNoRecording is returned by a Replayer when the cassette has no matching interaction
+var ErrNoRecording = errors.New("no recorded interaction matches request")
+
+// Interaction kinds
+const (
+	kindGenerate  = "generate"
+	kindStream    = "stream"
+	kindEmbedding = "embedding"
+)
+
+// MatchMode controls how a Replayer finds recorded interactions
+type MatchMode string
+
+const (
+	// MatchStrict requires the same system prompt, messages, tools and options
+	MatchStrict MatchMode = "strict"
+	// MatchFuzzy also accepts requests that differ in options, whitespace or case,
+	// then falls back to the recording whose last message shares the most words
+	MatchFuzzy MatchMode = "fuzzy"
+)
+
+// fuzzyThreshold is the least word overlap accepted for a fuzzy match
+const fuzzyThreshold = 0.5
+
+// Cassette is a file of recorded AI interactions
+type Cassette struct {
+	Version      int           `json:"version"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded call
+type Interaction struct {
+	Kind     string           `json:"kind"`
+	Key      string           `json:"key"` // Informational; keys are recomputed on load
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request used for matching
+type RecordedRequest struct {
+	System        string           `json:"system,omitempty"`
+	Messages      []Message        `json:"messages,omitempty"`
+	Tools         []ToolDefinition `json:"tools,omitempty"`
+	Model         string           `json:"model,omitempty"`
+	Temperature   *float64         `json:"temperature,omitempty"`
+	MaxTokens     int              `json:"max_tokens,omitempty"`
+	StopSequences []string         `json:"stop_sequences,omitempty"`
+	Text          string           `json:"text,omitempty"` // Embedding input
+}
+
+// RecordedResponse is what the provider returned
+type RecordedResponse struct {
+	Text       string     `json:"text,omitempty"`
+	Model      string     `json:"model,omitempty"`
+	StopReason string     `json:"stop_reason,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	Usage      Usage      `json:"usage"`
+	Chunks     []string   `json:"chunks,omitempty"` // Stream deltas in order
+	Embedding  []float32  `json:"embedding,omitempty"`
+	Error      string     `json:"error,omitempty"`
+
+	// DomainError keeps the code and retryability of platform errors, so replayed
+	// failures are retried and reported like live ones
+	DomainError *RecordedError `json:"domain_error,omitempty"`
+}
+
+// RecordedError is a recorded platform error
+type RecordedError struct {
+	Code       platformerrors.ErrorCode `json:"code"`
+	Message    string                   `json:"message"`
+	Retryable  bool                     `json:"retryable,omitempty"`
+	RetryAfter string                   `json:"retry_after,omitempty"` // A time.Duration string
+}
+
+// recordError returns the recorded form of err
+func recordError(err error) RecordedResponse {
+	recorded := RecordedResponse{Error: err.Error()}
+	var domainErr *platformerrors.DomainError
+	if errors.As(err, &domainErr) {
+		recorded.DomainError = &RecordedError{
+			Code:      domainErr.Code,
+			Message:   domainErr.Message,
+			Retryable: domainErr.Retryable,
+		}
+		if domainErr.RetryAfter != nil {
+			recorded.DomainError.RetryAfter = domainErr.RetryAfter.String()
+		}
+	}
+	return recorded
+}
+
+// err rebuilds the recorded error
+func (r RecordedResponse) err() error {
+	if r.DomainError == nil {
+		return errors.New(r.Error)
+	}
+	builder := platformerrors.New(r.DomainError.Code, r.DomainError.Message)
+	if r.DomainError.Retryable {
+		var retryAfter *time.Duration
+		if d, err := time.ParseDuration(r.DomainError.RetryAfter); err == nil {
+			retryAfter = &d
+		}
+		builder = builder.AsRetryable(retryAfter)
+	}
+	return builder.Build()
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to path
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func recordRequest(req *GenerateRequest) RecordedRequest {
+	return RecordedRequest{
+		System:        req.System,
+		Messages:      req.Messages,
+		Tools:         req.Tools,
+		Model:         req.Options.Model,
+		Temperature:   req.Options.Temperature,
+		MaxTokens:     req.Options.MaxTokens,
+		StopSequences: req.Options.StopSequences,
+	}
+}
+
+// strictKey hashes everything that affects the provider's answer
+func strictKey(kind string, req RecordedRequest) string {
+	data, _ := json.Marshal(struct {
+		Kind    string          `json:"kind"`
+		Request RecordedRequest `json:"request"`
+	}{kind, req})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fuzzyKey hashes the conversation text only, normalised for whitespace and case
+func fuzzyKey(kind string, req RecordedRequest) string {
+	var b strings.Builder
+	b.WriteString(kind)
+	b.WriteString("\x00")
+	b.WriteString(normalize(req.System))
+	for _, msg := range req.Messages {
+		b.WriteString("\x00" + msg.Role + ":" + normalize(msg.Content))
+	}
+	for _, tool := range req.Tools {
+		b.WriteString("\x00tool:" + tool.Name)
+	}
+	b.WriteString("\x00" + normalize(req.Text))
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// lastText is the text a fuzzy match compares: the last message, or the embedding input
+func (r RecordedRequest) lastText() string {
+	if r.Text != "" {
+		return r.Text
+	}
+	if len(r.Messages) == 0 {
+		return r.System
+	}
+	return r.Messages[len(r.Messages)-1].Content
+}
+
+// Recorder passes calls through to a live client and appends each one to a cassette file
+type Recorder struct {
+	client AIService
+	path   string
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records client's interactions to path, replacing any existing cassette
+func NewRecorder(client AIService, path string) *Recorder {
+	return &Recorder{client: client, path: path, cassette: Cassette{Version: 1}}
+}
+
+func (r *Recorder) record(kind string, req RecordedRequest, resp RecordedResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Kind:     kind,
+		Key:      strictKey(kind, req),
+		Request:  req,
+		Response: resp,
+	})
+	return r.cassette.Save(r.path)
+}
+
+// Generate calls the client and records the exchange
+func (r *Recorder) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	resp, err := r.client.Generate(ctx, req)
+	var recorded RecordedResponse
+	if err != nil {
+		recorded = recordError(err)
+	} else {
+		recorded = RecordedResponse{
+			Text:       resp.Text,
+			Model:      resp.Model,
+			StopReason: resp.StopReason,
+			ToolCalls:  resp.ToolCalls,
+			Usage:      resp.Usage,
+		}
+	}
+	if recErr := r.record(kindGenerate, recordRequest(req), recorded); recErr != nil {
+		return nil, recErr
+	}
+	return resp, err
+}
+
+// GenerateStream forwards the client's stream and records it once it ends
+func (r *Recorder) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	recordedReq := recordRequest(req)
+	stream, err := r.client.GenerateStream(ctx, req)
+	if err != nil {
+		if recErr := r.record(kindStream, recordedReq, recordError(err)); recErr != nil {
+			return nil, recErr
+		}
+		return nil, err
+	}
+
+	out := make(chan StreamChunk)
+	go func() {
+		defer close(out)
+		var recorded RecordedResponse
+		for chunk := range stream {
+			switch {
+			case chunk.Err != nil:
+				failure := recordError(chunk.Err)
+				recorded.Error, recorded.DomainError = failure.Error, failure.DomainError
+			case chunk.Usage != nil:
+				recorded.Usage = *chunk.Usage
+				recorded.Model = chunk.Model
+			default:
+				recorded.Chunks = append(recorded.Chunks, chunk.Text)
+				recorded.Text += chunk.Text
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// The caller left; an unfinished stream is not recorded
+				discardStream(stream)
+				return
+			}
+		}
+		if err := r.record(kindStream, recordedReq, recorded); err != nil {
+			zap.L().Warn("Failed to record stream to cassette", zap.String("path", r.path), zap.Error(err))
+		}
+	}()
+	return out, nil
+}
+
+// GenerateText records a single prompt call
+func (r *Recorder) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	resp, err := r.Generate(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// StreamText records a single prompt stream
+func (r *Recorder) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return r.GenerateStream(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+}
+
+// GenerateEmbedding calls the client and records the embedding
+func (r *Recorder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embedding, err := r.client.GenerateEmbedding(ctx, text)
+	recorded := RecordedResponse{Embedding: embedding}
+	if err != nil {
+		recorded = recordError(err)
+	}
+	if recErr := r.record(kindEmbedding, RecordedRequest{Text: text}, recorded); recErr != nil {
+		return nil, recErr
+	}
+	return embedding, err
+}
+
+// Replayer serves recorded interactions without a provider. Identical requests
+// recorded more than once are answered in recording order, repeating the last.
+type Replayer struct {
+	mode MatchMode
+
+	mu           sync.Mutex
+	interactions []Interaction
+	strict       map[string][]int
+	fuzzy        map[string][]int
+	served       map[int]bool
+}
+
+// NewReplayer serves the interactions in cassette
+func NewReplayer(cassette *Cassette, mode MatchMode) *Replayer {
+	r := &Replayer{
+		mode:         mode,
+		interactions: cassette.Interactions,
+		strict:       make(map[string][]int),
+		fuzzy:        make(map[string][]int),
+		served:       make(map[int]bool),
+	}
+	for i, interaction := range cassette.Interactions {
+		strict := strictKey(interaction.Kind, interaction.Request)
+		fuzzy := fuzzyKey(interaction.Kind, interaction.Request)
+		r.strict[strict] = append(r.strict[strict], i)
+		r.fuzzy[fuzzy] = append(r.fuzzy[fuzzy], i)
+	}
+	return r
+}
+
+// NewReplayerFromFile loads a cassette and serves it
+func NewReplayerFromFile(path string, mode MatchMode) (*Replayer, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(cassette, mode), nil
+}
+
+// find returns the recorded response for a request
+func (r *Replayer) find(kind string, req RecordedRequest) (RecordedResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := strictKey(kind, req)
+	if i, ok := r.next(r.strict[key]); ok {
+		return r.interactions[i].Response, nil
+	}
+	if r.mode == MatchFuzzy {
+		if i, ok := r.next(r.fuzzy[fuzzyKey(kind, req)]); ok {
+			return r.interactions[i].Response, nil
+		}
+		if i, ok := r.closest(kind, req); ok {
+			return r.interactions[i].Response, nil
+		}
+	}
+	return RecordedResponse{}, fmt.Errorf("%w (%s request %s)", ErrNoRecording, kind, key[:12])
+}
+
+// next picks the first unserved candidate, or the last one when all have been served
+func (r *Replayer) next(candidates []int) (int, bool) {
+	if len(candidates) == 0 {
+		return 0, false
+	}
+	for _, i := range candidates {
+		if !r.served[i] {
+			r.served[i] = true
+			return i, true
+		}
+	}
+	return candidates[len(candidates)-1], true
+}
+
+// closest finds the interaction of the same kind whose last message shares the most words
+func (r *Replayer) closest(kind string, req RecordedRequest) (int, bool) {
+	want := wordSet(req.lastText())
+	best, bestScore := -1, 0.0
+	for i, interaction := range r.interactions {
+		if interaction.Kind != kind {
+			continue
+		}
+		score := jaccard(want, wordSet(interaction.Request.lastText()))
+		if score > bestScore || (score == bestScore && best >= 0 && r.served[best] && !r.served[i]) {
+			best, bestScore = i, score
+		}
+	}
+	if best < 0 || bestScore < fuzzyThreshold {
+		return 0, false
+	}
+	r.served[best] = true
+	return best, true
+}
+
+func wordSet(s string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		words[strings.Trim(word, ".,;:!?\"'()[]{}")] = true
+	}
+	return words
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// Generate answers from the cassette
+func (r *Replayer) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	resp, err := r.find(kindGenerate, recordRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, resp.err()
+	}
+	return &GenerateResponse{
+		Text:       resp.Text,
+		Model:      resp.Model,
+		StopReason: resp.StopReason,
+		ToolCalls:  resp.ToolCalls,
+		Usage:      resp.Usage,
+	}, nil
+}
+
+// GenerateStream replays recorded deltas, ending with the recorded usage or error
+func (r *Replayer) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	resp, err := r.find(kindStream, recordRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" && len(resp.Chunks) == 0 && resp.Usage == (Usage{}) {
+		return nil, resp.err()
+	}
+
+	stream := make(chan StreamChunk, len(resp.Chunks)+1)
+	for _, text := range resp.Chunks {
+		stream <- StreamChunk{Text: text}
+	}
+	if resp.Error != "" {
+		stream <- StreamChunk{Err: resp.err()}
+	} else {
+		usage := resp.Usage
+		stream <- StreamChunk{Usage: &usage, Model: resp.Model}
+	}
+	close(stream)
+	return stream, nil
+}
+
+// GenerateText answers a single prompt from the cassette
+func (r *Replayer) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	resp, err := r.Generate(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// StreamText replays a single prompt stream
+func (r *Replayer) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return r.GenerateStream(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+}
+
+// GenerateEmbedding returns a recorded embedding
+func (r *Replayer) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	resp, err := r.find(kindEmbedding, RecordedRequest{Text: text})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, resp.err()
+	}
+	return resp.Embedding, nil
+}
@@ -0,0 +1,189 @@
+// FILE: platform/aiservice/cassette_test.go
+package aiservice
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoAI answers with the last message upper-cased and counts calls
+type echoAI struct {
+	calls int
+}
+
+func (e *echoAI) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	e.calls++
+	last := req.Messages[len(req.Messages)-1].Content
+	return &GenerateResponse{Text: strings.ToUpper(last), Model: "echo-1", Usage: Usage{InputTokens: 3, OutputTokens: 2}}, nil
+}
+
+func (e *echoAI) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	e.calls++
+	stream := make(chan StreamChunk, 3)
+	stream <- StreamChunk{Text: "hel"}
+	stream <- StreamChunk{Text: "lo"}
+	stream <- StreamChunk{Usage: &Usage{InputTokens: 1, OutputTokens: 2}, Model: "echo-1"}
+	close(stream)
+	return stream, nil
+}
+
+func (e *echoAI) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	resp, err := e.Generate(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+func (e *echoAI) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return e.GenerateStream(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+}
+
+func (e *echoAI) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	return []float32{float32(len(text)), 0.5}, nil
+}
+
+func recordCassette(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "cassettes", "echo.json")
+	recorder := NewRecorder(&echoAI{}, path)
+	ctx := context.Background()
+
+	text, err := recorder.GenerateText(ctx, "Summarise the quarterly sales report", map[string]interface{}{"model": "echo-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMARISE THE QUARTERLY SALES REPORT", text)
+
+	stream, err := recorder.StreamText(ctx, "say hello", nil)
+	require.NoError(t, err)
+	result, err := CollectStream(stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Text)
+
+	_, err = recorder.GenerateEmbedding(ctx, "abc")
+	require.NoError(t, err)
+	return path
+}
+
+func TestReplayerStrict(t *testing.T) {
+	replayer, err := NewReplayerFromFile(recordCassette(t), MatchStrict)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	text, err := replayer.GenerateText(ctx, "Summarise the quarterly sales report", map[string]interface{}{"model": "echo-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMARISE THE QUARTERLY SALES REPORT", text)
+
+	stream, err := replayer.StreamText(ctx, "say hello", nil)
+	require.NoError(t, err)
+	result, err := CollectStream(stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", result.Text)
+	assert.Equal(t, Usage{InputTokens: 1, OutputTokens: 2}, result.Usage)
+
+	embedding, err := replayer.GenerateEmbedding(ctx, "abc")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{3, 0.5}, embedding)
+
+	// A different model is a different request in strict mode
+	_, err = replayer.GenerateText(ctx, "Summarise the quarterly sales report", map[string]interface{}{"model": "echo-2"})
+	assert.ErrorIs(t, err, ErrNoRecording)
+}
+
+func TestReplayerFuzzy(t *testing.T) {
+	replayer, err := NewReplayerFromFile(recordCassette(t), MatchFuzzy)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	// Options, case and whitespace are ignored
+	text, err := replayer.GenerateText(ctx, "summarise  the quarterly\nsales report", map[string]interface{}{"model": "echo-2"})
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMARISE THE QUARTERLY SALES REPORT", text)
+
+	// Close enough wording still matches
+	text, err = replayer.GenerateText(ctx, "Summarise the quarterly sales report please", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMARISE THE QUARTERLY SALES REPORT", text)
+
+	_, err = replayer.GenerateText(ctx, "Write a haiku about autumn", nil)
+	assert.ErrorIs(t, err, ErrNoRecording)
+}
+
+func TestReplayerServesRepeatsInOrder(t *testing.T) {
+	cassette := &Cassette{Version: 1}
+	for _, answer := range []string{"first", "second"} {
+		cassette.Interactions = append(cassette.Interactions, Interaction{
+			Kind:     kindGenerate,
+			Request:  recordRequest(PromptRequest("again", TextGenerationOptions{})),
+			Response: RecordedResponse{Text: answer},
+		})
+	}
+	replayer := NewReplayer(cassette, MatchStrict)
+
+	var answers []string
+	for i := 0; i < 3; i++ {
+		text, err := replayer.GenerateText(context.Background(), "again", nil)
+		require.NoError(t, err)
+		answers = append(answers, text)
+	}
+	assert.Equal(t, []string{"first", "second", "second"}, answers)
+}
+
+// overloadedAI fails every generation like a provider returning 529
+type overloadedAI struct {
+	echoAI
+}
+
+func (o *overloadedAI) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	retryAfter := 2 * time.Second
+	return nil, platformerrors.New(platformerrors.ErrAIServiceError, "anthropic request failed with status 529").
+		AsRetryable(&retryAfter).
+		Build()
+}
+
+func TestReplayerKeepsErrorRetryability(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overloaded.json")
+	_, err := NewRecorder(&overloadedAI{}, path).GenerateText(context.Background(), "busy", nil)
+	require.Error(t, err)
+
+	replayer, err := NewReplayerFromFile(path, MatchStrict)
+	require.NoError(t, err)
+	_, err = replayer.GenerateText(context.Background(), "busy", nil)
+	require.Error(t, err)
+	assert.True(t, platformerrors.IsRetryable(err))
+	assert.Equal(t, 2*time.Second, *platformerrors.GetRetryAfter(err))
+	var domainErr *platformerrors.DomainError
+	require.True(t, errors.As(err, &domainErr))
+	assert.Equal(t, platformerrors.ErrAIServiceError, domainErr.Code)
+}
+
+func TestRecorderStreamStopsWhenCallerLeaves(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slow.json")
+	assertStreamAbandoned(t, func(client AIService) AIService {
+		return NewRecorder(client, path)
+	})
+	assert.NoFileExists(t, path, "unfinished streams are not recorded")
+}
+
+func TestNewFromConfigRecordsAndReplays(t *testing.T) {
+	path := recordCassette(t)
+	client, err := NewFromConfig(context.Background(), map[string]interface{}{
+		"provider": "replay",
+		"cassette": path,
+		"match":    "fuzzy",
+	})
+	require.NoError(t, err)
+	text, err := client.GenerateText(context.Background(), "summarise the quarterly sales report", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "SUMMARISE THE QUARTERLY SALES REPORT", text)
+
+	_, err = NewFromConfig(context.Background(), map[string]interface{}{"provider": "replay", "cassette": path, "match": "loose"})
+	assert.Error(t, err)
+}
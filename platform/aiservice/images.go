@@ -0,0 +1,150 @@
+// FILE: platform/aiservice/images.go
+package aiservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// MaxImageBytes is the largest image sent to a provider
+const MaxImageBytes = 5 << 20
+
+// imageTokenEstimate is roughly what one image costs in input tokens
+const imageTokenEstimate = 1600
+
+// ImagePart is an image attached to a message, given either inline or by URI.
+// http(s) URIs are fetched by the provider; other URIs, such as s3://, must be loaded
+// into Data before the request is sent, see ImageResolvingAI.
+type ImagePart struct {
+	URI       string `json:"uri,omitempty"`
+	MediaType string `json:"media_type,omitempty"` // Detected from Data when empty
+	Data      []byte `json:"data,omitempty"`
+}
+
+// ImageFetcher loads one of a client's images by URI, returning its bytes and media type.
+// Implementations refuse URIs of other clients' images.
+type ImageFetcher interface {
+	FetchImage(ctx context.Context, clientID, uri string) ([]byte, string, error)
+}
+
+// isRemoteURL reports whether a provider can fetch uri itself
+func isRemoteURL(uri string) bool {
+	return strings.HasPrefix(uri, "https://") || strings.HasPrefix(uri, "http://")
+}
+
+// inline returns the image's media type and bytes, checking that it can be sent inline
+func (img ImagePart) inline() (string, []byte, error) {
+	if len(img.Data) == 0 {
+		return "", nil, fmt.Errorf("image %s has not been loaded", img.URI)
+	}
+	if len(img.Data) > MaxImageBytes {
+		return "", nil, fmt.Errorf("image %s is %d bytes, the limit is %d", img.URI, len(img.Data), MaxImageBytes)
+	}
+	mediaType := img.MediaType
+	if mediaType == "" {
+		mediaType = http.DetectContentType(img.Data)
+	}
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return mediaType, img.Data, nil
+	}
+	return "", nil, fmt.Errorf("unsupported image type %s", mediaType)
+}
+
+// EstimateImageTokens estimates the input tokens used by the images in a request
+func EstimateImageTokens(req *GenerateRequest) int {
+	count := 0
+	for _, msg := range req.Messages {
+		count += len(msg.Images)
+	}
+	return count * imageTokenEstimate
+}
+
+// ResolveImages loads every image the provider cannot fetch itself, as images of
+// req.ClientID. It returns req unchanged when there is nothing to load, and a copy otherwise.
+func ResolveImages(ctx context.Context, req *GenerateRequest, fetcher ImageFetcher) (*GenerateRequest, error) {
+	var resolved *GenerateRequest
+	copied := make(map[int]bool) // Messages whose image slice belongs to resolved
+	for i, msg := range req.Messages {
+		for j, img := range msg.Images {
+			if len(img.Data) > 0 || isRemoteURL(img.URI) {
+				continue
+			}
+			if img.URI == "" {
+				return nil, fmt.Errorf("message %d has an image with neither data nor a URI", i)
+			}
+			if fetcher == nil {
+				return nil, fmt.Errorf("cannot load image %s: no image storage configured", img.URI)
+			}
+
+			data, mediaType, err := fetcher.FetchImage(ctx, req.ClientID, img.URI)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load image %s: %w", img.URI, err)
+			}
+
+			if resolved == nil {
+				reqCopy := *req
+				reqCopy.Messages = append([]Message(nil), req.Messages...)
+				resolved = &reqCopy
+			}
+			if !copied[i] {
+				resolved.Messages[i].Images = append([]ImagePart(nil), msg.Images...)
+				copied[i] = true
+			}
+			resolved.Messages[i].Images[j].Data = data
+			if resolved.Messages[i].Images[j].MediaType == "" {
+				resolved.Messages[i].Images[j].MediaType = mediaType
+			}
+		}
+	}
+	if resolved == nil {
+		return req, nil
+	}
+	return resolved, nil
+}
+
+// ImageResolvingAI loads images referenced by URI before passing requests on
+type ImageResolvingAI struct {
+	client  AIService
+	fetcher ImageFetcher
+}
+
+// NewImageResolvingAI wraps client so images are loaded through fetcher
+func NewImageResolvingAI(client AIService, fetcher ImageFetcher) *ImageResolvingAI {
+	return &ImageResolvingAI{client: client, fetcher: fetcher}
+}
+
+// Generate loads the request's images and calls the client
+func (r *ImageResolvingAI) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	resolved, err := ResolveImages(ctx, req, r.fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.Generate(ctx, resolved)
+}
+
+// GenerateStream loads the request's images and streams from the client
+func (r *ImageResolvingAI) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	resolved, err := ResolveImages(ctx, req, r.fetcher)
+	if err != nil {
+		return nil, err
+	}
+	return r.client.GenerateStream(ctx, resolved)
+}
+
+// GenerateText has no images to load
+func (r *ImageResolvingAI) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	return r.client.GenerateText(ctx, prompt, options)
+}
+
+// StreamText has no images to load
+func (r *ImageResolvingAI) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return r.client.StreamText(ctx, prompt, options)
+}
+
+// GenerateEmbedding is passed straight to the client
+func (r *ImageResolvingAI) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return r.client.GenerateEmbedding(ctx, text)
+}
@@ -0,0 +1,99 @@
+// FILE: platform/aiservice/images_test.go
+package aiservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pngHeader is enough of a PNG for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+// mapFetcher serves images keyed by URI
+type mapFetcher map[string][]byte
+
+func (f mapFetcher) FetchImage(ctx context.Context, clientID, uri string) ([]byte, string, error) {
+	data, ok := f[uri]
+	if !ok {
+		return nil, "", fmt.Errorf("no such image")
+	}
+	return data, "image/png", nil
+}
+
+func imageRequest(images ...ImagePart) *GenerateRequest {
+	return &GenerateRequest{Messages: []Message{{Role: RoleUser, Content: "Critique this", Images: images}}}
+}
+
+func TestResolveImages(t *testing.T) {
+	ctx := context.Background()
+	fetcher := mapFetcher{"s3://assets/hero.png": pngHeader}
+
+	// Nothing to load
+	req := imageRequest(ImagePart{URI: "https://example.com/hero.png"})
+	resolved, err := ResolveImages(ctx, req, nil)
+	require.NoError(t, err)
+	assert.Same(t, req, resolved)
+
+	req = imageRequest(ImagePart{URI: "https://example.com/a.png"}, ImagePart{URI: "s3://assets/hero.png"})
+	resolved, err = ResolveImages(ctx, req, fetcher)
+	require.NoError(t, err)
+	assert.Equal(t, pngHeader, resolved.Messages[0].Images[1].Data)
+	assert.Equal(t, "image/png", resolved.Messages[0].Images[1].MediaType)
+	assert.Empty(t, req.Messages[0].Images[1].Data, "the caller's request is not modified")
+
+	_, err = ResolveImages(ctx, imageRequest(ImagePart{URI: "s3://assets/missing.png"}), fetcher)
+	assert.Error(t, err)
+	_, err = ResolveImages(ctx, imageRequest(ImagePart{URI: "s3://assets/hero.png"}), nil)
+	assert.Error(t, err)
+}
+
+func TestAnthropicImageBlocks(t *testing.T) {
+	var body map[string]interface{}
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"stop_reason":"end_turn","content":[{"type":"text","text":"Too dark."}]}`)
+	})
+
+	_, err := client.Generate(context.Background(), imageRequest(
+		ImagePart{Data: pngHeader},
+		ImagePart{URI: "https://example.com/hero.png"},
+	))
+	require.NoError(t, err)
+
+	content := body["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+	require.Len(t, content, 3)
+	assert.Equal(t, map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgoAAAANSUhEUg=="},
+		content[0].(map[string]interface{})["source"])
+	assert.Equal(t, map[string]interface{}{"type": "url", "url": "https://example.com/hero.png"},
+		content[1].(map[string]interface{})["source"])
+	assert.Equal(t, "Critique this", content[2].(map[string]interface{})["text"])
+
+	// Unloaded and unsupported images fail before the request is sent
+	_, err = client.Generate(context.Background(), imageRequest(ImagePart{URI: "s3://assets/hero.png"}))
+	assert.Error(t, err)
+	_, err = client.Generate(context.Background(), imageRequest(ImagePart{Data: []byte("plain text")}))
+	assert.Error(t, err)
+}
+
+func TestOpenAIImageParts(t *testing.T) {
+	var body map[string]interface{}
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"choices":[{"message":{"content":"Too dark."},"finish_reason":"stop"}]}`)
+	})
+
+	_, err := client.Generate(context.Background(), imageRequest(ImagePart{Data: pngHeader}))
+	require.NoError(t, err)
+
+	content := body["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+	require.Len(t, content, 2)
+	assert.Equal(t, "data:image/png;base64,iVBORw0KGgoAAAANSUhEUg==",
+		content[0].(map[string]interface{})["image_url"].(map[string]interface{})["url"])
+	assert.Equal(t, "Critique this", content[1].(map[string]interface{})["text"])
+}
@@ -1,17 +1,145 @@
 // FILE: platform/aiservice/interface.go
 package aiservice
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
 
 // AIService defines the interface for AI providers
 type AIService interface {
+	// Generate runs a full request with system prompt and message history
+	Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+	// GenerateStream is Generate with incremental output. The channel is closed when
+	// generation ends; a chunk with Err set is always the last one sent.
+	GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error)
+
+	// GenerateText and StreamText send a single user prompt. Options use the
+	// core logic keys understood by OptionsFromMap.
 	GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error)
+	StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error)
+
 	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
 }
 
-// TextGenerationOptions contains common options for text generation
-type TextGenerationOptions struct {
-	Temperature float64
-	MaxTokens   int
-	Model       string
+// Message roles
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// StopReasonToolUse is the stop reason when the model is waiting for tool results
+const StopReasonToolUse = "tool_use"
+
+// Message is one turn of a conversation. Assistant turns may request tools;
+// the user turn that follows carries their results. User turns may carry images.
+type Message struct {
+	Role        string       `json:"role"`
+	Content     string       `json:"content"`
+	Images      []ImagePart  `json:"images,omitempty"`
+	ToolCalls   []ToolCall   `json:"tool_calls,omitempty"`
+	ToolResults []ToolResult `json:"tool_results,omitempty"`
+}
+
+// ToolDefinition describes a tool the model may call
+type ToolDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"` // JSON schema of the tool input
+}
+
+// ToolCall is a model's request to run a tool
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResult is the outcome of a tool call, sent back to the model
+type ToolResult struct {
+	ToolCallID string `json:"tool_call_id"`
+	Content    string `json:"content"`
+	IsError    bool   `json:"is_error,omitempty"`
+}
+
+// GenerateRequest is a provider-neutral text generation request
+type GenerateRequest struct {
+	System   string
+	Messages []Message
+	Tools    []ToolDefinition
+	Options  TextGenerationOptions
+	ClientID string // Tenant the request is made for; images are only loaded from its storage
+}
+
+// GenerateResponse is the result of a text generation request
+type GenerateResponse struct {
+	Text       string
+	Model      string
+	StopReason string
+	ToolCalls  []ToolCall // Set when StopReason is StopReasonToolUse
+	Usage      Usage
+	Cached     bool // Served by CachingAI without calling the model; Usage is zero
+}
+
+// Usage is the token count reported by the provider for one call
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// StreamChunk is one piece of streamed output. The final chunk of a successful
+// stream carries the usage and the model that served it, and no text.
+type StreamChunk struct {
+	Text  string
+	Usage *Usage
+	Model string
+	Err   error
+}
+
+// StreamResult is a drained stream
+type StreamResult struct {
+	Text  string
+	Model string
+	Usage Usage
+}
+
+// PromptRequest builds a request for a single user prompt
+func PromptRequest(prompt string, options TextGenerationOptions) *GenerateRequest {
+	return &GenerateRequest{
+		Messages: []Message{{Role: RoleUser, Content: prompt}},
+		Options:  options,
+	}
+}
+
+// discardStream reads what is left of a stream in the background, so a producer
+// blocked on a send can finish after its reader has gone
+func discardStream(stream <-chan StreamChunk) {
+	go func() {
+		for range stream {
+		}
+	}()
+}
+
+// CollectStream drains a stream, calling onDelta for every text chunk.
+// On error the result holds whatever was received before it.
+func CollectStream(stream <-chan StreamChunk, onDelta func(string)) (*StreamResult, error) {
+	result := &StreamResult{}
+	var b strings.Builder
+	for chunk := range stream {
+		if chunk.Err != nil {
+			result.Text = b.String()
+			return result, chunk.Err
+		}
+		if chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+			result.Model = chunk.Model
+		}
+		b.WriteString(chunk.Text)
+		if onDelta != nil && chunk.Text != "" {
+			onDelta(chunk.Text)
+		}
+	}
+	result.Text = b.String()
+	return result, nil
 }
@@ -0,0 +1,464 @@
+// FILE: platform/aiservice/openai.go
+package aiservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gqls/agentchassis/platform/resilience"
+)
+
+const (
+	openAIBaseURL               = "https://api.openai.com/v1"
+	defaultOpenAIEmbeddingModel = "text-embedding-3-small"
+)
+
+// OpenAIClient implements AIService against the OpenAI API or any server that speaks
+// the same protocol (vLLM, Ollama, LM Studio, ...)
+type OpenAIClient struct {
+	apiKey         string
+	baseURL        string
+	defaults       TextGenerationOptions
+	embeddingModel string
+	dimensions     int
+	httpClient     resilience.HTTPDoer
+	limiter        *tokenBucket
+	retry          RetryConfig
+}
+
+// NewOpenAIClient creates an OpenAI-compatible client.
+// base_url points it at another server; api_key_env_var may be omitted for local servers.
+// model is only required for text generation and embedding_model only for embeddings.
+// timeout_seconds, requests_per_minute and max_retries tune the HTTP client.
+func NewOpenAIClient(ctx context.Context, config map[string]interface{}) (*OpenAIClient, error) {
+	var apiKey string
+	if apiKeyEnvVar, _ := config["api_key_env_var"].(string); apiKeyEnvVar != "" {
+		apiKey = os.Getenv(apiKeyEnvVar)
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key not found in environment variable %s", apiKeyEnvVar)
+		}
+	}
+
+	baseURL := openAIBaseURL
+	if u, ok := config["base_url"].(string); ok && u != "" {
+		baseURL = strings.TrimRight(u, "/")
+	}
+
+	embeddingModel := defaultOpenAIEmbeddingModel
+	if m, ok := config["embedding_model"].(string); ok && m != "" {
+		embeddingModel = m
+	}
+	dimensions, _ := toFloat(config["dimensions"])
+
+	timeout := defaultRequestTimeout
+	if v, ok := toFloat(config["timeout_seconds"]); ok && v > 0 {
+		timeout = time.Duration(v * float64(time.Second))
+	}
+	requestsPerMinute := defaultRequestsPerMinute
+	if v, ok := toFloat(config["requests_per_minute"]); ok && v > 0 {
+		requestsPerMinute = int(v)
+	}
+
+	return &OpenAIClient{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		defaults: OptionsFromMap(config).WithDefaults(TextGenerationOptions{
+			Temperature: Float(DefaultTemperature),
+			MaxTokens:   DefaultMaxTokens,
+		}),
+		embeddingModel: embeddingModel,
+		dimensions:     int(dimensions),
+		httpClient:     newBreakerClient("openai", &http.Client{Timeout: timeout}),
+		// Keyless local servers share no budget, so the URL is part of the limiter key
+		limiter: limiterFor(baseURL+" "+apiKey, requestsPerMinute),
+		retry:   retryConfigFromMap(config),
+	}, nil
+}
+
+// openAIChatRequest is the chat completions request body
+type openAIChatRequest struct {
+	Model         string               `json:"model"`
+	Messages      []openAIMessage      `json:"messages"`
+	MaxTokens     int                  `json:"max_tokens,omitempty"`
+	Temperature   *float64             `json:"temperature,omitempty"`
+	Stop          []string             `json:"stop,omitempty"`
+	Tools         []openAITool         `json:"tools,omitempty"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage is the usage block of chat completion responses
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u openAIUsage) toUsage() Usage {
+	return Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    interface{}      `json:"content"` // *string, or []openAIContentPart for images
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// openAIContentPart is a text or image part of a user message
+type openAIContentPart struct {
+	Type     string          `json:"type"` // text or image_url
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL string `json:"url"` // http(s) or data: URL
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIToolFunction `json:"function"`
+}
+
+type openAIToolFunction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON encoded as a string
+	} `json:"function"`
+}
+
+// Generate generates text with the chat completions API
+func (c *OpenAIClient) Generate(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+	body, err := c.buildChatRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := c.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Close()
+
+	var response struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content   *string          `json:"content"`
+				ToolCalls []openAIToolCall `json:"tool_calls"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage openAIUsage `json:"usage"`
+	}
+	if err := json.NewDecoder(respBody).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return nil, fmt.Errorf("no choices in response")
+	}
+
+	choice := response.Choices[0]
+	result := &GenerateResponse{
+		Model:      response.Model,
+		StopReason: choice.FinishReason,
+		Usage:      response.Usage.toUsage(),
+	}
+	if choice.Message.Content != nil {
+		result.Text = *choice.Message.Content
+	}
+	for _, call := range choice.Message.ToolCalls {
+		input := json.RawMessage(call.Function.Arguments)
+		if len(input) == 0 {
+			input = json.RawMessage("{}")
+		}
+		result.ToolCalls = append(result.ToolCalls, ToolCall{ID: call.ID, Name: call.Function.Name, Input: input})
+	}
+	if len(result.ToolCalls) > 0 {
+		result.StopReason = StopReasonToolUse
+	}
+
+	return result, nil
+}
+
+// GenerateStream streams text deltas from the chat completions API.
+// Tool calls are not supported on streams; use Generate for tool use.
+func (c *OpenAIClient) GenerateStream(ctx context.Context, req *GenerateRequest) (<-chan StreamChunk, error) {
+	if req != nil && len(req.Tools) > 0 {
+		return nil, fmt.Errorf("tools are not supported when streaming")
+	}
+	body, err := c.buildChatRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+
+	respBody, err := c.post(ctx, "/chat/completions", body)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := make(chan StreamChunk)
+	go func() {
+		defer close(stream)
+		defer respBody.Close()
+
+		send := func(chunk StreamChunk) bool {
+			select {
+			case stream <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var usage Usage
+		var model string
+		events := newSSEReader(respBody)
+		for {
+			event, err := events.Next()
+			if err == io.EOF {
+				send(StreamChunk{Err: fmt.Errorf("stream ended before [DONE]")})
+				return
+			}
+			if err != nil {
+				send(StreamChunk{Err: fmt.Errorf("failed to read stream: %w", err)})
+				return
+			}
+			if event.Data == "[DONE]" {
+				send(StreamChunk{Usage: &usage, Model: model})
+				return
+			}
+
+			var payload struct {
+				Model   string       `json:"model"`
+				Usage   *openAIUsage `json:"usage"`
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+				} `json:"choices"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal([]byte(event.Data), &payload); err != nil {
+				send(StreamChunk{Err: fmt.Errorf("failed to parse stream event: %w", err)})
+				return
+			}
+			if payload.Error != nil {
+				send(StreamChunk{Err: fmt.Errorf("stream error: %s", payload.Error.Message)})
+				return
+			}
+			if payload.Model != "" {
+				model = payload.Model
+			}
+			// Only the last chunk carries usage
+			if payload.Usage != nil {
+				usage = payload.Usage.toUsage()
+			}
+			if len(payload.Choices) > 0 && payload.Choices[0].Delta.Content != "" {
+				if !send(StreamChunk{Text: payload.Choices[0].Delta.Content}) {
+					return
+				}
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+// GenerateText generates text for a single prompt
+func (c *OpenAIClient) GenerateText(ctx context.Context, prompt string, options map[string]interface{}) (string, error) {
+	resp, err := c.Generate(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// StreamText streams text for a single prompt
+func (c *OpenAIClient) StreamText(ctx context.Context, prompt string, options map[string]interface{}) (<-chan StreamChunk, error) {
+	return c.GenerateStream(ctx, PromptRequest(prompt, OptionsFromMap(options)))
+}
+
+// GenerateEmbedding generates an embedding with the embeddings API
+func (c *OpenAIClient) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	body := map[string]interface{}{
+		"model": c.embeddingModel,
+		"input": text,
+	}
+	if c.dimensions > 0 {
+		body["dimensions"] = c.dimensions
+	}
+
+	respBody, err := c.post(ctx, "/embeddings", body)
+	if err != nil {
+		return nil, err
+	}
+	defer respBody.Close()
+
+	var response struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(respBody).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to parse embedding response: %w", err)
+	}
+	if len(response.Data) == 0 || len(response.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("no embedding in response")
+	}
+
+	return response.Data[0].Embedding, nil
+}
+
+// buildChatRequest converts a GenerateRequest into a chat completions request
+func (c *OpenAIClient) buildChatRequest(req *GenerateRequest) (*openAIChatRequest, error) {
+	if req == nil || len(req.Messages) == 0 {
+		return nil, fmt.Errorf("at least one message is required")
+	}
+
+	opts := req.Options.WithDefaults(c.defaults)
+	if opts.Model == "" {
+		return nil, fmt.Errorf("no model configured for text generation")
+	}
+
+	messages := make([]openAIMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, openAIMessage{Role: "system", Content: stringPtr(req.System)})
+	}
+	for i, msg := range req.Messages {
+		if msg.Role != RoleUser && msg.Role != RoleAssistant {
+			return nil, fmt.Errorf("message %d has invalid role %q", i, msg.Role)
+		}
+
+		// Tool results are separate "tool" messages in this protocol
+		for _, result := range msg.ToolResults {
+			content := result.Content
+			if result.IsError {
+				content = "Error: " + content
+			}
+			messages = append(messages, openAIMessage{Role: "tool", Content: stringPtr(content), ToolCallID: result.ToolCallID})
+		}
+		if len(msg.ToolResults) > 0 && msg.Content == "" {
+			continue
+		}
+
+		out := openAIMessage{Role: msg.Role}
+		if len(msg.Images) > 0 {
+			parts, err := openAIImageParts(msg)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			out.Content = parts
+		} else if msg.Content != "" || len(msg.ToolCalls) == 0 {
+			out.Content = stringPtr(msg.Content)
+		}
+		for _, call := range msg.ToolCalls {
+			tc := openAIToolCall{ID: call.ID, Type: "function"}
+			tc.Function.Name = call.Name
+			tc.Function.Arguments = string(call.Input)
+			if tc.Function.Arguments == "" {
+				tc.Function.Arguments = "{}"
+			}
+			out.ToolCalls = append(out.ToolCalls, tc)
+		}
+		messages = append(messages, out)
+	}
+
+	tools := make([]openAITool, 0, len(req.Tools))
+	for _, tool := range req.Tools {
+		tools = append(tools, openAITool{
+			Type: "function",
+			Function: openAIToolFunction{
+				Name:        tool.Name,
+				Description: tool.Description,
+				Parameters:  tool.InputSchema,
+			},
+		})
+	}
+
+	return &openAIChatRequest{
+		Model:       opts.Model,
+		Messages:    messages,
+		MaxTokens:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		Stop:        opts.StopSequences,
+		Tools:       tools,
+	}, nil
+}
+
+// post sends a JSON request and returns the body of a successful response, retrying
+// rate limits and server errors. Non-200 responses are returned as DomainErrors.
+func (c *OpenAIClient) post(ctx context.Context, path string, requestBody interface{}) (io.ReadCloser, error) {
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	chat, _ := requestBody.(*openAIChatRequest)
+	resp, err := doWithRetry(ctx, c.httpClient, c.limiter, c.retry, "openai", func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+path, bytes.NewReader(jsonBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if c.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		}
+		if chat != nil && chat.Stream {
+			req.Header.Set("Accept", eventStream)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// openAIImageParts sends a message's images as parts ahead of its text
+func openAIImageParts(msg Message) ([]openAIContentPart, error) {
+	parts := make([]openAIContentPart, 0, len(msg.Images)+1)
+	for _, img := range msg.Images {
+		url := img.URI
+		if !isRemoteURL(url) || len(img.Data) > 0 {
+			mediaType, data, err := img.inline()
+			if err != nil {
+				return nil, err
+			}
+			url = "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+		}
+		parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+	}
+	if msg.Content != "" {
+		parts = append(parts, openAIContentPart{Type: "text", Text: msg.Content})
+	}
+	return parts, nil
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,199 @@
+// FILE: platform/aiservice/openai_test.go
+package aiservice
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOpenAIClient(t *testing.T, handler http.HandlerFunc) *OpenAIClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewOpenAIClient(context.Background(), map[string]interface{}{
+		"base_url":            server.URL + "/v1/",
+		"model":               "local-model",
+		"retry_base_delay_ms": 1,
+	})
+	require.NoError(t, err)
+	return client
+}
+
+func TestOpenAIGenerate(t *testing.T) {
+	var body map[string]interface{}
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Authorization"), "local servers need no key")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		fmt.Fprint(w, `{"model":"local-model","choices":[{"message":{"content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"web_search","arguments":"{\"query\":\"go\"}"}}]},"finish_reason":"tool_calls"}]}`)
+	})
+
+	resp, err := client.Generate(context.Background(), &GenerateRequest{
+		System: "Be brief.",
+		Messages: []Message{
+			{Role: RoleUser, Content: "Search for go"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_0", Name: "web_search", Input: json.RawMessage(`{"query":"golang"}`)}}},
+			{Role: RoleUser, ToolResults: []ToolResult{{ToolCallID: "call_0", Content: "timeout", IsError: true}}},
+		},
+		Tools: []ToolDefinition{{Name: "web_search", InputSchema: map[string]interface{}{"type": "object"}}},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, StopReasonToolUse, resp.StopReason)
+	require.Len(t, resp.ToolCalls, 1)
+	assert.Equal(t, "web_search", resp.ToolCalls[0].Name)
+	assert.JSONEq(t, `{"query":"go"}`, string(resp.ToolCalls[0].Input))
+
+	messages := body["messages"].([]interface{})
+	require.Len(t, messages, 4)
+	assert.Equal(t, "system", messages[0].(map[string]interface{})["role"])
+	assert.Equal(t, "assistant", messages[2].(map[string]interface{})["role"])
+	toolMsg := messages[3].(map[string]interface{})
+	assert.Equal(t, "tool", toolMsg["role"])
+	assert.Equal(t, "call_0", toolMsg["tool_call_id"])
+	assert.Equal(t, "local-model", body["model"])
+}
+
+func TestOpenAIGenerate_RetriesRateLimits(t *testing.T) {
+	attempts := 0
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("retry-after", "0")
+			http.Error(w, `{"error":{"type":"rate_limit_exceeded"}}`, http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, `{"model":"local-model","choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`)
+	})
+
+	text, err := client.GenerateText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", text)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestOpenAIGenerate_ErrorsAreDomainErrors(t *testing.T) {
+	attempts := 0
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, `{"error":{"type":"invalid_request_error"}}`, http.StatusBadRequest)
+	})
+
+	_, err := client.GenerateText(context.Background(), "hi", nil)
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts)
+	domainErr, ok := err.(*platformerrors.DomainError)
+	require.True(t, ok)
+	assert.Equal(t, platformerrors.ErrAIServiceError, domainErr.Code)
+	assert.False(t, domainErr.Retryable)
+
+	_, err = client.GenerateEmbedding(context.Background(), "hi")
+	assert.False(t, platformerrors.IsRetryable(err))
+}
+
+func TestOpenAIGenerateStream(t *testing.T) {
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"local-model\",\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	stream, err := client.StreamText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+	result, err := CollectStream(stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", result.Text)
+	assert.Equal(t, Usage{InputTokens: 9, OutputTokens: 2}, result.Usage)
+}
+
+func TestOpenAIGenerateStream_OutlastsTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != eventStream {
+			time.Sleep(300 * time.Millisecond)
+			return
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		w.(http.Flusher).Flush()
+		time.Sleep(300 * time.Millisecond)
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"model\":\"local-model\",\"choices\":[],\"usage\":{\"prompt_tokens\":9,\"completion_tokens\":2}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	client, err := NewOpenAIClient(context.Background(), map[string]interface{}{
+		"base_url":        server.URL + "/v1",
+		"model":           "local-model",
+		"timeout_seconds": 0.1,
+		"max_retries":     0,
+	})
+	require.NoError(t, err)
+
+	// The timeout bounds whole requests, but only the start of a stream
+	_, err = client.GenerateText(context.Background(), "hi", nil)
+	require.Error(t, err)
+
+	stream, err := client.StreamText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+	result, err := CollectStream(stream, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", result.Text)
+}
+
+func TestOpenAIGenerateEmbedding(t *testing.T) {
+	client := newTestOpenAIClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, defaultOpenAIEmbeddingModel, body["model"])
+		assert.Equal(t, "remember this", body["input"])
+		fmt.Fprint(w, `{"data":[{"embedding":[0.1,0.2,0.3]}]}`)
+	})
+
+	embedding, err := client.GenerateEmbedding(context.Background(), "remember this")
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.1, 0.2, 0.3}, embedding)
+}
+
+func TestNewEmbeddingFromConfig(t *testing.T) {
+	t.Setenv("TEST_ANTHROPIC_KEY", "key")
+
+	// The embedding section is used on its own, with its model as the embedding model
+	svc, err := NewEmbeddingFromConfig(context.Background(), map[string]interface{}{
+		"provider":        "anthropic",
+		"model":           "claude-3-haiku-20240307",
+		"api_key_env_var": "TEST_ANTHROPIC_KEY",
+		"embedding": map[string]interface{}{
+			"provider": "openai",
+			"model":    "nomic-embed-text",
+			"base_url": "http://localhost:11434/v1",
+		},
+	})
+	require.NoError(t, err)
+	client, ok := svc.(*OpenAIClient)
+	require.True(t, ok)
+	assert.Equal(t, "nomic-embed-text", client.embeddingModel)
+	assert.Equal(t, "http://localhost:11434/v1", client.baseURL)
+
+	// Anthropic has no embedding API, with or without an embedding section
+	anthropic := map[string]interface{}{"provider": "anthropic", "api_key_env_var": "TEST_ANTHROPIC_KEY"}
+	_, err = NewEmbeddingFromConfig(context.Background(), anthropic)
+	assert.ErrorIs(t, err, ErrEmbeddingsUnsupported)
+	_, err = NewEmbeddingFromConfig(context.Background(), map[string]interface{}{"embedding": anthropic})
+	assert.ErrorIs(t, err, ErrEmbeddingsUnsupported)
+	_, err = NewEmbeddingFromConfig(context.Background(), map[string]interface{}{"api_key_env_var": "TEST_ANTHROPIC_KEY"})
+	assert.ErrorIs(t, err, ErrEmbeddingsUnsupported, "anthropic is the default provider")
+
+	_, err = NewFromConfig(context.Background(), map[string]interface{}{"provider": "nope"})
+	assert.Error(t, err)
+}
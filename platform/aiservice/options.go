@@ -0,0 +1,137 @@
+// FILE: platform/aiservice/options.go
+package aiservice
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Used when neither the request, the persona nor the service config sets a value
+const (
+	DefaultMaxTokens   = 2048
+	DefaultTemperature = 0.7
+)
+
+// TextGenerationOptions contains common options for text generation.
+// Zero values mean "not set" so options can be layered with WithDefaults.
+type TextGenerationOptions struct {
+	Model         string
+	Temperature   *float64
+	MaxTokens     int
+	StopSequences []string
+
+	// FallbackModels are tried in order when Model is unavailable or unaffordable.
+	// Clients ignore them; see agentbase.MeteredAI.
+	FallbackModels []string
+
+	// Cache opts the request in to response caching by CachingAI. It is set per
+	// persona and is not inherited by WithDefaults.
+	Cache *CachePolicy
+}
+
+// OptionsFromMap reads model, temperature, max_tokens, stop_sequences, fallback_models and
+// response_cache from a config map such as an ai_service section or an agent's core logic
+func OptionsFromMap(m map[string]interface{}) TextGenerationOptions {
+	var opts TextGenerationOptions
+	if m == nil {
+		return opts
+	}
+
+	if model, ok := m["model"].(string); ok {
+		opts.Model = ResolveModel(model)
+	}
+	if t, ok := toFloat(m["temperature"]); ok {
+		opts.Temperature = &t
+	}
+	if n, ok := toFloat(m["max_tokens"]); ok {
+		opts.MaxTokens = int(n)
+	}
+	opts.StopSequences = stringSlice(m["stop_sequences"])
+	for _, model := range stringSlice(m["fallback_models"]) {
+		opts.FallbackModels = append(opts.FallbackModels, ResolveModel(model))
+	}
+	opts.Cache = cachePolicyFromMap(m["response_cache"])
+
+	return opts
+}
+
+// ModelAliases maps the short model names used by older persona configs to the dated
+// IDs the provider APIs accept
+var ModelAliases = map[string]string{
+	"claude-3-haiku":    "claude-3-haiku-20240307",
+	"claude-3-sonnet":   "claude-3-5-sonnet-20240620",
+	"claude-3-opus":     "claude-3-opus-20240229",
+	"claude-3-5-haiku":  "claude-3-5-haiku-20241022",
+	"claude-3-5-sonnet": "claude-3-5-sonnet-20240620",
+}
+
+// ResolveModel returns the model ID to send for a configured model name
+func ResolveModel(model string) string {
+	if id, ok := ModelAliases[model]; ok {
+		return id
+	}
+	return model
+}
+
+// stringSlice reads a list of strings from JSON or YAML config
+func stringSlice(v interface{}) []string {
+	switch items := v.(type) {
+	case []string:
+		return items
+	case []interface{}:
+		var result []string
+		for _, item := range items {
+			if str, ok := item.(string); ok {
+				result = append(result, str)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// WithDefaults fills every unset option from defaults
+func (o TextGenerationOptions) WithDefaults(defaults TextGenerationOptions) TextGenerationOptions {
+	if o.Model == "" {
+		o.Model = defaults.Model
+	}
+	if o.Temperature == nil {
+		o.Temperature = defaults.Temperature
+	}
+	if o.MaxTokens == 0 {
+		o.MaxTokens = defaults.MaxTokens
+	}
+	if len(o.StopSequences) == 0 {
+		o.StopSequences = defaults.StopSequences
+	}
+	if len(o.FallbackModels) == 0 {
+		o.FallbackModels = defaults.FallbackModels
+	}
+	return o
+}
+
+// Float returns a pointer to v, for setting Temperature
+func Float(v float64) *float64 {
+	return &v
+}
+
+// toFloat accepts the number types produced by JSON, YAML and viper
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	case fmt.Stringer:
+		f, err := strconv.ParseFloat(n.String(), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,149 @@
+// FILE: platform/aiservice/registry.go
+package aiservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// DefaultProvider is used when ai_service has no provider
+const DefaultProvider = "anthropic"
+
+// ErrEmbeddingsUnsupported is returned by NewEmbeddingFromConfig when the configured
+// provider has no embedding API
+var ErrEmbeddingsUnsupported = errors.New("AI provider does not support embeddings; configure ai_service.embedding")
+
+// withoutEmbeddings lists the providers that cannot generate embeddings
+var withoutEmbeddings = map[string]bool{"anthropic": true}
+
+// EmbeddingService generates embeddings. Every AIService is one, but the embedding
+// provider can be configured separately from the text provider.
+type EmbeddingService interface {
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+}
+
+// ProviderFactory creates a client from an ai_service config section
+type ProviderFactory func(ctx context.Context, config map[string]interface{}) (AIService, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]ProviderFactory{
+		"anthropic": func(ctx context.Context, config map[string]interface{}) (AIService, error) {
+			return NewAnthropicClient(ctx, config)
+		},
+		"openai": func(ctx context.Context, config map[string]interface{}) (AIService, error) {
+			return NewOpenAIClient(ctx, config)
+		},
+		"replay": newReplayerFromConfig,
+	}
+)
+
+// RegisterProvider makes a provider available to NewFromConfig
+func RegisterProvider(name string, factory ProviderFactory) error {
+	if name == "" || factory == nil {
+		return fmt.Errorf("provider name and factory are required")
+	}
+
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if _, exists := providers[name]; exists {
+		return fmt.Errorf("provider '%s' is already registered", name)
+	}
+	providers[name] = factory
+	return nil
+}
+
+// Providers returns the registered provider names, sorted
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewFromConfig creates the text provider named by config["provider"]
+func NewFromConfig(ctx context.Context, config map[string]interface{}) (AIService, error) {
+	provider, _ := config["provider"].(string)
+	if provider == "" {
+		provider = DefaultProvider
+	}
+
+	providersMu.RLock()
+	factory, ok := providers[provider]
+	providersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider '%s' (available: %v)", provider, Providers())
+	}
+
+	client, err := factory(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s client: %w", provider, err)
+	}
+	if path, _ := config["record_cassette"].(string); path != "" {
+		return NewRecorder(client, path), nil
+	}
+	return client, nil
+}
+
+// newReplayerFromConfig serves the cassette named by config["cassette"]. config["match"]
+// is "strict" (the default) or "fuzzy".
+func newReplayerFromConfig(ctx context.Context, config map[string]interface{}) (AIService, error) {
+	path, _ := config["cassette"].(string)
+	if path == "" {
+		return nil, fmt.Errorf("replay provider requires a cassette path")
+	}
+	mode := MatchStrict
+	if match, _ := config["match"].(string); match != "" {
+		mode = MatchMode(match)
+		if mode != MatchStrict && mode != MatchFuzzy {
+			return nil, fmt.Errorf("unknown cassette match mode '%s'", match)
+		}
+	}
+	return NewReplayerFromFile(path, mode)
+}
+
+// NewEmbeddingFromConfig creates the embedding provider. An "embedding" subsection of
+// ai_service configures it independently, with its model as the embedding model;
+// without one the text provider is used. Providers without an embedding API return
+// ErrEmbeddingsUnsupported rather than a client that fails on every call.
+func NewEmbeddingFromConfig(ctx context.Context, config map[string]interface{}) (EmbeddingService, error) {
+	section, ok := config["embedding"].(map[string]interface{})
+	if !ok {
+		if err := supportsEmbeddings(config); err != nil {
+			return nil, err
+		}
+		return NewFromConfig(ctx, config)
+	}
+	if err := supportsEmbeddings(section); err != nil {
+		return nil, err
+	}
+
+	embeddingConfig := make(map[string]interface{}, len(section)+1)
+	for k, v := range section {
+		embeddingConfig[k] = v
+	}
+	if _, ok := embeddingConfig["embedding_model"]; !ok {
+		embeddingConfig["embedding_model"] = embeddingConfig["model"]
+	}
+	return NewFromConfig(ctx, embeddingConfig)
+}
+
+// supportsEmbeddings returns ErrEmbeddingsUnsupported for providers without an embedding API
+func supportsEmbeddings(config map[string]interface{}) error {
+	provider, _ := config["provider"].(string)
+	if provider == "" {
+		provider = DefaultProvider
+	}
+	if withoutEmbeddings[provider] {
+		return fmt.Errorf("%w (provider '%s')", ErrEmbeddingsUnsupported, provider)
+	}
+	return nil
+}
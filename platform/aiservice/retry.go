@@ -0,0 +1,331 @@
+// FILE: platform/aiservice/retry.go
+package aiservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/resilience"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRequestTimeout    = 120 * time.Second
+	defaultMaxRetries        = 3
+	defaultBaseDelay         = 500 * time.Millisecond
+	defaultMaxDelay          = 30 * time.Second
+	defaultRequestsPerMinute = 50
+
+	// statusOverloaded is Anthropic's "overloaded" status
+	statusOverloaded = 529
+)
+
+// RetryConfig controls retries of failed provider calls
+type RetryConfig struct {
+	MaxRetries int
+	BaseDelay  time.Duration // Doubled on each attempt, with jitter
+	MaxDelay   time.Duration // Longest wait between attempts; longer retry-after values fail fast
+}
+
+// retryConfigFromMap reads max_retries, retry_base_delay_ms and retry_max_delay_seconds
+func retryConfigFromMap(config map[string]interface{}) RetryConfig {
+	cfg := RetryConfig{MaxRetries: defaultMaxRetries, BaseDelay: defaultBaseDelay, MaxDelay: defaultMaxDelay}
+	if v, ok := toFloat(config["max_retries"]); ok && v >= 0 {
+		cfg.MaxRetries = int(v)
+	}
+	if v, ok := toFloat(config["retry_base_delay_ms"]); ok && v > 0 {
+		cfg.BaseDelay = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := toFloat(config["retry_max_delay_seconds"]); ok && v > 0 {
+		cfg.MaxDelay = time.Duration(v) * time.Second
+	}
+	return cfg
+}
+
+// backoff returns the wait before retry number attempt (0-based). A provider
+// retry-after takes precedence over the computed delay.
+func (r RetryConfig) backoff(attempt int, retryAfter *time.Duration) time.Duration {
+	if retryAfter != nil {
+		return *retryAfter
+	}
+	delay := r.BaseDelay << attempt
+	if delay <= 0 || delay > r.MaxDelay {
+		delay = r.MaxDelay
+	}
+	// Full jitter over the upper half so concurrent callers spread out
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// tokenBucket is a client-side request rate limiter
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // Tokens per second
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(requestsPerMinute int) *tokenBucket {
+	capacity := float64(requestsPerMinute) / 6 // Allow ten seconds' worth in a burst
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &tokenBucket{
+		rate:     float64(requestsPerMinute) / 60,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*tokenBucket)
+)
+
+// limiterFor returns the shared limiter for an API key, so every client in the
+// process using the same key draws from one budget. The first caller sets the rate.
+func limiterFor(apiKey string, requestsPerMinute int) *tokenBucket {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	if limiter, ok := limiters[apiKey]; ok {
+		return limiter
+	}
+	limiter := newTokenBucket(requestsPerMinute)
+	limiters[apiKey] = limiter
+	return limiter
+}
+
+// statusError is a non-200 provider response
+type statusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("API request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// eventStream is the Accept header of streaming requests
+const eventStream = "text/event-stream"
+
+// statusDoer turns non-200 responses into errors, so the circuit breaker sees them.
+// Streaming requests go through stream, which has no overall timeout.
+type statusDoer struct {
+	client *http.Client
+	stream *http.Client
+}
+
+func (d statusDoer) Do(req *http.Request) (*http.Response, error) {
+	client := d.client
+	if req.Header.Get("Accept") == eventStream {
+		client = d.stream
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return nil, &statusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)}
+}
+
+// newBreakerClient wraps client so that provider outages trip a circuit breaker.
+// Rate limiting and request errors are the caller's problem and don't count as failures.
+// Streams may run longer than client's timeout: they get that long to start responding
+// and then run until their context ends.
+func newBreakerClient(name string, client *http.Client) *resilience.HTTPClientWithBreaker {
+	cbConfig := resilience.DefaultCircuitBreakerConfig(name)
+	cbConfig.IsSuccessful = func(err error) bool {
+		if err == nil || errors.Is(err, context.Canceled) {
+			return true
+		}
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			return !isRetryableStatus(statusErr.StatusCode) || statusErr.StatusCode == http.StatusTooManyRequests
+		}
+		return false
+	}
+	return resilience.NewHTTPClientWithBreaker(statusDoer{client: client, stream: streamingClient(client)}, cbConfig, zap.L())
+}
+
+// streamingClient returns a copy of client whose timeout only covers the wait for
+// response headers
+func streamingClient(client *http.Client) *http.Client {
+	stream := *client
+	stream.Timeout = 0
+	if client.Timeout <= 0 {
+		return &stream
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if client.Transport == nil {
+		transport, ok = http.DefaultTransport.(*http.Transport)
+	}
+	if ok {
+		transport = transport.Clone()
+		transport.ResponseHeaderTimeout = client.Timeout
+		stream.Transport = transport
+	}
+	return &stream
+}
+
+// doWithRetry waits for the rate limiter, sends the request built by newRequest and
+// retries rate limits, overloads and server errors with backoff. Failures are returned
+// as DomainErrors: ErrRateLimited for 429s, ErrAIServiceError otherwise.
+func doWithRetry(ctx context.Context, client resilience.HTTPDoer, limiter *tokenBucket, retry RetryConfig, provider string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, toAIError(provider, err)
+		}
+
+		req, err := newRequest()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err == nil {
+			return resp, nil
+		}
+
+		domainErr := toAIError(provider, err)
+		if !domainErr.Retryable || attempt >= retry.MaxRetries || ctx.Err() != nil {
+			return nil, domainErr
+		}
+		delay := retry.backoff(attempt, domainErr.RetryAfter)
+		if delay > retry.MaxDelay {
+			// The provider wants longer than we are willing to block; let the caller reschedule
+			return nil, domainErr
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return nil, toAIError(provider, err)
+		}
+	}
+}
+
+// toAIError maps a request failure onto the platform error model
+func toAIError(provider string, err error) *platformerrors.DomainError {
+	var statusErr *statusError
+	switch {
+	case errors.As(err, &statusErr):
+		retryAfter := parseRetryAfter(statusErr.Header)
+		code := platformerrors.ErrAIServiceError
+		message := fmt.Sprintf("%s request failed with status %d", provider, statusErr.StatusCode)
+		if statusErr.StatusCode == http.StatusTooManyRequests {
+			code = platformerrors.ErrRateLimited
+			message = fmt.Sprintf("%s rate limit exceeded", provider)
+		}
+		builder := platformerrors.New(code, message).
+			WithCause(err).
+			WithDetail("provider", provider).
+			WithDetail("status", statusErr.StatusCode)
+		if isRetryableStatus(statusErr.StatusCode) {
+			builder = builder.AsRetryable(retryAfter)
+		}
+		return builder.Build()
+
+	case resilience.IsCircuitBreakerError(err):
+		retryAfter := resilience.DefaultCircuitBreakerConfig(provider).Timeout
+		return platformerrors.New(platformerrors.ErrAIServiceError, fmt.Sprintf("%s is temporarily unavailable", provider)).
+			WithCause(err).
+			WithDetail("provider", provider).
+			AsRetryable(&retryAfter).
+			Build()
+
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return platformerrors.New(platformerrors.ErrAIServiceError, fmt.Sprintf("%s request cancelled", provider)).
+			WithCause(err).
+			WithDetail("provider", provider).
+			Build()
+
+	default:
+		// Connection failures and client timeouts
+		return platformerrors.New(platformerrors.ErrAIServiceError, fmt.Sprintf("%s request failed", provider)).
+			WithCause(err).
+			WithDetail("provider", provider).
+			AsRetryable(nil).
+			Build()
+	}
+}
+
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusRequestTimeout, statusOverloaded:
+		return true
+	}
+	return status >= 500
+}
+
+// parseRetryAfter reads retry-after-ms or retry-after (seconds or an HTTP date)
+func parseRetryAfter(header http.Header) *time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms >= 0 {
+		d := time.Duration(ms * float64(time.Millisecond))
+		return &d
+	}
+	value := header.Get("retry-after")
+	if value == "" {
+		return nil
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		d := time.Duration(seconds * float64(time.Second))
+		return &d
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		d := time.Until(at)
+		if d < 0 {
+			d = 0
+		}
+		return &d
+	}
+	return nil
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
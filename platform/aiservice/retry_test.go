@@ -0,0 +1,114 @@
+// FILE: platform/aiservice/retry_test.go
+package aiservice
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	platformerrors "github.com/gqls/agentchassis/platform/errors"
+	"github.com/gqls/agentchassis/platform/resilience"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_RetriesOverloaded(t *testing.T) {
+	attempts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("retry-after", "0")
+			http.Error(w, `{"type":"error","error":{"type":"overloaded_error"}}`, statusOverloaded)
+			return
+		}
+		fmt.Fprint(w, `{"model":"test-model","content":[{"type":"text","text":"ok"}]}`)
+	})
+
+	text, err := client.GenerateText(context.Background(), "hi", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", text)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestGenerate_RateLimitedBeyondMaxDelay(t *testing.T) {
+	attempts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("retry-after", "45")
+		http.Error(w, `{"type":"error","error":{"type":"rate_limit_error"}}`, http.StatusTooManyRequests)
+	})
+
+	_, err := client.GenerateText(context.Background(), "hi", nil)
+	require.Error(t, err)
+
+	// The wait is longer than the client will block, so the caller gets it back to reschedule
+	assert.Equal(t, 1, attempts)
+	domainErr, ok := err.(*platformerrors.DomainError)
+	require.True(t, ok)
+	assert.Equal(t, platformerrors.ErrRateLimited, domainErr.Code)
+	assert.True(t, domainErr.Retryable)
+	require.NotNil(t, domainErr.RetryAfter)
+	assert.Equal(t, 45*time.Second, *domainErr.RetryAfter)
+
+	// Rate limits don't count against the breaker
+	assert.Equal(t, uint32(0), client.httpClient.(*resilience.HTTPClientWithBreaker).Counts().TotalFailures)
+}
+
+func TestGenerate_ClientErrorNotRetried(t *testing.T) {
+	attempts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, `{"type":"error","error":{"type":"invalid_request_error"}}`, http.StatusBadRequest)
+	})
+
+	_, err := client.GenerateText(context.Background(), "hi", nil)
+	require.Error(t, err)
+	assert.Equal(t, 1, attempts)
+	assert.False(t, platformerrors.IsRetryable(err))
+}
+
+func TestGenerate_ServerErrorsExhaustRetries(t *testing.T) {
+	attempts := 0
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "upstream failure", http.StatusBadGateway)
+	})
+
+	_, err := client.GenerateText(context.Background(), "hi", nil)
+	require.Error(t, err)
+	assert.Equal(t, 3, attempts, "first attempt plus two retries")
+	domainErr := err.(*platformerrors.DomainError)
+	assert.Equal(t, platformerrors.ErrAIServiceError, domainErr.Code)
+	assert.True(t, domainErr.Retryable)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	header := http.Header{}
+	assert.Nil(t, parseRetryAfter(header))
+
+	header.Set("retry-after", "2")
+	assert.Equal(t, 2*time.Second, *parseRetryAfter(header))
+
+	header.Set("retry-after-ms", "1500")
+	assert.Equal(t, 1500*time.Millisecond, *parseRetryAfter(header))
+
+	header = http.Header{}
+	header.Set("retry-after", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, time.Duration(0), *parseRetryAfter(header))
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	bucket := newTokenBucket(60) // One per second, burst of ten
+	for i := 0; i < 10; i++ {
+		require.NoError(t, bucket.Wait(context.Background()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, bucket.Wait(ctx), context.DeadlineExceeded)
+
+	assert.Same(t, limiterFor("key-a", 60), limiterFor("key-a", 600))
+	assert.NotSame(t, limiterFor("key-a", 60), limiterFor("key-b", 60))
+}
@@ -0,0 +1,170 @@
+// FILE: platform/aiservice/schema.go
+package aiservice
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateSchema checks a decoded JSON value against a JSON Schema and returns every
+// violation found. It supports the subset models are asked to produce: type, properties,
+// required, additionalProperties, items, enum, minimum/maximum, minLength/maxLength
+// and minItems/maxItems.
+func ValidateSchema(schema map[string]interface{}, value interface{}) []string {
+	var problems []string
+	validateValue(schema, value, "$", &problems)
+	return problems
+}
+
+func validateValue(schema map[string]interface{}, value interface{}, path string, problems *[]string) {
+	if schema == nil {
+		return
+	}
+	add := func(format string, args ...interface{}) {
+		*problems = append(*problems, path+": "+fmt.Sprintf(format, args...))
+	}
+
+	if t, ok := schema["type"]; ok {
+		types := schemaTypes(t)
+		if !matchesAnyType(types, value) {
+			add("expected %s, got %s", strings.Join(types, " or "), jsonType(value))
+			return
+		}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if jsonEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed, _ := json.Marshal(enum)
+			add("must be one of %s", allowed)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				add("missing required property '%s'", name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propSchema, ok := properties[name].(map[string]interface{}); ok {
+				validateValue(propSchema, v[name], path+"."+name, problems)
+			} else if additional, ok := schema["additionalProperties"].(bool); ok && !additional {
+				add("unexpected property '%s'", name)
+			}
+		}
+
+	case []interface{}:
+		if min, ok := toFloat(schema["minItems"]); ok && float64(len(v)) < min {
+			add("must have at least %d items", int(min))
+		}
+		if max, ok := toFloat(schema["maxItems"]); ok && float64(len(v)) > max {
+			add("must have at most %d items", int(max))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateValue(items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+
+	case string:
+		length := utf8.RuneCountInString(v)
+		if min, ok := toFloat(schema["minLength"]); ok && float64(length) < min {
+			add("must be at least %d characters", int(min))
+		}
+		if max, ok := toFloat(schema["maxLength"]); ok && float64(length) > max {
+			add("must be at most %d characters", int(max))
+		}
+
+	case float64:
+		if min, ok := toFloat(schema["minimum"]); ok && v < min {
+			add("must be >= %v", min)
+		}
+		if max, ok := toFloat(schema["maximum"]); ok && v > max {
+			add("must be <= %v", max)
+		}
+	}
+}
+
+func schemaTypes(t interface{}) []string {
+	switch v := t.(type) {
+	case string:
+		return []string{v}
+	default:
+		return schemaStrings(v)
+	}
+}
+
+func schemaStrings(v interface{}) []string {
+	switch items := v.(type) {
+	case []string:
+		return items
+	case []interface{}:
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func matchesAnyType(types []string, value interface{}) bool {
+	actual := jsonType(value)
+	for _, t := range types {
+		if t == actual {
+			return true
+		}
+		if t == "number" && actual == "integer" {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonType names the JSON type of a value decoded by encoding/json
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func jsonEqual(a, b interface{}) bool {
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	return string(aj) == string(bj)
+}
@@ -0,0 +1,67 @@
+// FILE: platform/aiservice/sse.go
+package aiservice
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sseEvent is a single server-sent event
+type sseEvent struct {
+	Event string
+	Data  string
+}
+
+// sseReader reads server-sent events from a response body
+type sseReader struct {
+	reader *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{reader: bufio.NewReader(r)}
+}
+
+// Next returns the next event, or io.EOF once the stream is exhausted
+func (r *sseReader) Next() (*sseEvent, error) {
+	var event sseEvent
+	var data []string
+	seen := false
+
+	for {
+		line, err := r.reader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			if err == io.EOF && seen {
+				event.Data = strings.Join(data, "\n")
+				return &event, nil
+			}
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		// A blank line ends the event
+		if line == "" {
+			if !seen {
+				continue
+			}
+			event.Data = strings.Join(data, "\n")
+			return &event, nil
+		}
+
+		// Comment lines are keep-alives
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+			seen = true
+		case "data":
+			data = append(data, value)
+			seen = true
+		}
+	}
+}
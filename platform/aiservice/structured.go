@@ -0,0 +1,155 @@
+// FILE: platform/aiservice/structured.go
+package aiservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultMaxRepairs is how many times a model is re-prompted to fix invalid output
+const DefaultMaxRepairs = 2
+
+// ErrInvalidStructuredOutput is returned when the model never produced valid output
+var ErrInvalidStructuredOutput = errors.New("model did not produce valid structured output")
+
+// StructuredOutputError describes the last failed attempt. It matches
+// ErrInvalidStructuredOutput with errors.Is.
+type StructuredOutputError struct {
+	Attempts int
+	Problems []string
+	Raw      string // The last response text
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("%v after %d attempts: %s", ErrInvalidStructuredOutput, e.Attempts, strings.Join(e.Problems, "; "))
+}
+
+func (e *StructuredOutputError) Unwrap() error {
+	return ErrInvalidStructuredOutput
+}
+
+// GenerateFunc makes one model call. It lets callers add metering or fallbacks around
+// the client; AIService.Generate satisfies it.
+type GenerateFunc func(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error)
+
+// StructuredResult is validated output and what it took to get it
+type StructuredResult struct {
+	JSON     json.RawMessage
+	Attempts int
+	Usage    Usage // Summed over all attempts
+}
+
+// StructuredOutput asks a model for JSON matching a schema. Answers wrapped in code
+// fences or prose are accepted; invalid answers are sent back with the validation
+// errors up to MaxRepairs times.
+type StructuredOutput struct {
+	Schema     map[string]interface{}
+	MaxRepairs int
+}
+
+// Generate runs req until the answer validates and decodes it into out, which may be nil.
+// The caller's request is not modified.
+func (s StructuredOutput) Generate(ctx context.Context, generate GenerateFunc, req *GenerateRequest, out interface{}) (*StructuredResult, error) {
+	maxRepairs := s.MaxRepairs
+	if maxRepairs < 0 {
+		maxRepairs = 0
+	}
+
+	schemaJSON, err := json.MarshalIndent(s.Schema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	instructions := "Respond with only a JSON value that matches this JSON Schema, without commentary:\n" + string(schemaJSON)
+
+	attemptReq := *req
+	attemptReq.Messages = append([]Message(nil), req.Messages...)
+	if attemptReq.System != "" {
+		attemptReq.System += "\n\n" + instructions
+	} else {
+		attemptReq.System = instructions
+	}
+
+	result := &StructuredResult{}
+	var problems []string
+	var raw string
+	for result.Attempts <= maxRepairs {
+		result.Attempts++
+
+		resp, err := generate(ctx, &attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		result.Usage.InputTokens += resp.Usage.InputTokens
+		result.Usage.OutputTokens += resp.Usage.OutputTokens
+		raw = resp.Text
+
+		var value json.RawMessage
+		value, problems = s.check(raw)
+		if len(problems) == 0 {
+			if out != nil {
+				if err := json.Unmarshal(value, out); err != nil {
+					return nil, fmt.Errorf("failed to decode structured output: %w", err)
+				}
+			}
+			result.JSON = value
+			return result, nil
+		}
+
+		attemptReq.Messages = append(attemptReq.Messages,
+			Message{Role: RoleAssistant, Content: raw},
+			Message{Role: RoleUser, Content: "That response is invalid:\n- " + strings.Join(problems, "\n- ") +
+				"\n\nReply with only the corrected JSON."},
+		)
+	}
+
+	return nil, &StructuredOutputError{Attempts: result.Attempts, Problems: problems, Raw: raw}
+}
+
+// check extracts JSON from a response and validates it against the schema
+func (s StructuredOutput) check(text string) (json.RawMessage, []string) {
+	value, err := ExtractJSON(text)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		return nil, []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	return value, ValidateSchema(s.Schema, decoded)
+}
+
+var fencePattern = regexp.MustCompile("(?s)```(?:json|JSON)?\\s*\\n?(.*?)```")
+
+// ExtractJSON finds the JSON value in a model response. It accepts bare JSON, JSON in
+// a code fence and JSON surrounded by prose, returning the first complete object or array.
+func ExtractJSON(text string) (json.RawMessage, error) {
+	trimmed := strings.TrimSpace(text)
+	if json.Valid([]byte(trimmed)) && trimmed != "" {
+		return json.RawMessage(trimmed), nil
+	}
+
+	for _, match := range fencePattern.FindAllStringSubmatch(text, -1) {
+		candidate := strings.TrimSpace(match[1])
+		if json.Valid([]byte(candidate)) {
+			return json.RawMessage(candidate), nil
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		if text[i] != '{' && text[i] != '[' {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(text[i:]))
+		var value json.RawMessage
+		if err := dec.Decode(&value); err == nil {
+			return bytes.TrimSpace(value), nil
+		}
+	}
+
+	return nil, fmt.Errorf("response contains no JSON object")
+}
@@ -0,0 +1,118 @@
+// FILE: platform/aiservice/structured_test.go
+package aiservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reviewSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"passed", "score"},
+	"properties": map[string]interface{}{
+		"passed": map[string]interface{}{"type": "boolean"},
+		"score":  map[string]interface{}{"type": "integer", "minimum": 0, "maximum": 10},
+		"tags":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}}},
+	},
+	"additionalProperties": false,
+}
+
+func TestExtractJSON(t *testing.T) {
+	cases := map[string]string{
+		`{"a":1}`: `{"a":1}`,
+		"Here you go:\n```json\n{\"a\": 1}\n```\nAnything else?":     `{"a": 1}`,
+		`Sure! The answer is {"a": {"b": [1, 2]}} — hope that helps`: `{"a": {"b": [1, 2]}}`,
+		`Result: [1, 2, 3]`: `[1, 2, 3]`,
+	}
+	for input, expected := range cases {
+		value, err := ExtractJSON(input)
+		require.NoError(t, err, input)
+		assert.JSONEq(t, expected, string(value), input)
+	}
+
+	_, err := ExtractJSON("I cannot answer that.")
+	assert.Error(t, err)
+}
+
+func TestValidateSchema(t *testing.T) {
+	var value interface{} = map[string]interface{}{
+		"score": 11.5,
+		"tags":  []interface{}{"a", "c"},
+		"extra": true,
+	}
+	problems := ValidateSchema(reviewSchema, value)
+	assert.ElementsMatch(t, []string{
+		"$: missing required property 'passed'",
+		"$.score: expected integer, got number",
+		`$.tags[1]: must be one of ["a","b"]`,
+		"$: unexpected property 'extra'",
+	}, problems)
+
+	assert.Empty(t, ValidateSchema(reviewSchema, map[string]interface{}{"passed": true, "score": 7.0}))
+}
+
+// scripted returns its answers in order and records every request
+func scripted(answers ...string) (GenerateFunc, *[]GenerateRequest) {
+	var seen []GenerateRequest
+	return func(ctx context.Context, req *GenerateRequest) (*GenerateResponse, error) {
+		seen = append(seen, *req)
+		if len(answers) == 0 {
+			return nil, errors.New("no more answers")
+		}
+		answer := answers[0]
+		answers = answers[1:]
+		return &GenerateResponse{Text: answer, Usage: Usage{InputTokens: 10, OutputTokens: 5}}, nil
+	}, &seen
+}
+
+func TestStructuredOutput_Repairs(t *testing.T) {
+	generate, seen := scripted(
+		"Here is my review: {\"passed\": \"yes\", \"score\": 8}",
+		"```json\n{\"passed\": true, \"score\": 8}\n```",
+	)
+	req := PromptRequest("Review this", TextGenerationOptions{})
+	req.System = "You are a reviewer."
+
+	var out struct {
+		Passed bool `json:"passed"`
+		Score  int  `json:"score"`
+	}
+	result, err := StructuredOutput{Schema: reviewSchema, MaxRepairs: 2}.Generate(context.Background(), generate, req, &out)
+	require.NoError(t, err)
+
+	assert.True(t, out.Passed)
+	assert.Equal(t, 8, out.Score)
+	assert.Equal(t, 2, result.Attempts)
+	assert.Equal(t, Usage{InputTokens: 20, OutputTokens: 10}, result.Usage)
+
+	// The schema is added to the system prompt and the repair quotes the problem
+	require.Len(t, *seen, 2)
+	assert.Contains(t, (*seen)[0].System, "You are a reviewer.")
+	assert.Contains(t, (*seen)[0].System, `"required"`)
+	repair := (*seen)[1].Messages
+	require.Len(t, repair, 3)
+	assert.Equal(t, RoleAssistant, repair[1].Role)
+	assert.Contains(t, repair[2].Content, "$.passed: expected boolean, got string")
+
+	// The caller's request is untouched
+	assert.Len(t, req.Messages, 1)
+	assert.Equal(t, "You are a reviewer.", req.System)
+}
+
+func TestStructuredOutput_GivesUp(t *testing.T) {
+	generate, seen := scripted("no", "still no", "never")
+
+	_, err := StructuredOutput{Schema: reviewSchema, MaxRepairs: 1}.Generate(context.Background(), generate,
+		PromptRequest("Review this", TextGenerationOptions{}), nil)
+	require.ErrorIs(t, err, ErrInvalidStructuredOutput)
+
+	var outputErr *StructuredOutputError
+	require.True(t, errors.As(err, &outputErr))
+	assert.Equal(t, 2, outputErr.Attempts)
+	assert.Equal(t, "still no", outputErr.Raw)
+	assert.Len(t, *seen, 2)
+}
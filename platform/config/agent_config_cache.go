@@ -0,0 +1,136 @@
+// FILE: platform/config/agent_config_cache.go
+package config
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gqls/agentchassis/pkg/models"
+)
+
+const (
+	// AgentConfigChangedTopic carries agent_config_changed events from core-manager
+	AgentConfigChangedTopic = "system.events.agent_config_changed"
+
+	// AgentConfigChangedEvent is the event_type of config change notifications
+	AgentConfigChangedEvent = "agent_config_changed"
+
+	// DefaultAgentConfigTTL bounds how stale a cached config can get if an event is missed
+	DefaultAgentConfigTTL = 5 * time.Minute
+)
+
+// AgentConfigChanged is published whenever an instance or template config changes.
+// Template events carry no instance ID because any instance may derive from the template.
+type AgentConfigChanged struct {
+	EventType  string    `json:"event_type"`
+	ClientID   string    `json:"client_id,omitempty"`
+	InstanceID string    `json:"instance_id,omitempty"`
+	TemplateID string    `json:"template_id,omitempty"`
+	Change     string    `json:"change"` // updated, deleted
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// AgentConfigLoadFunc loads an agent configuration from the source of truth
+type AgentConfigLoadFunc func(ctx context.Context, clientID, agentInstanceID, agentType string) (*models.AgentConfig, error)
+
+type cachedAgentConfig struct {
+	config    *models.AgentConfig
+	expiresAt time.Time
+}
+
+// AgentConfigCache keeps recently used agent configs in memory for a bounded time
+type AgentConfigCache struct {
+	load AgentConfigLoadFunc
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.RWMutex
+	entries map[string]cachedAgentConfig
+}
+
+// NewAgentConfigCache creates a cache in front of load. A zero ttl uses DefaultAgentConfigTTL.
+func NewAgentConfigCache(load AgentConfigLoadFunc, ttl time.Duration) *AgentConfigCache {
+	if ttl <= 0 {
+		ttl = DefaultAgentConfigTTL
+	}
+	return &AgentConfigCache{
+		load:    load,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedAgentConfig),
+	}
+}
+
+// Get returns the cached config for an instance, loading it on a miss or after expiry
+func (c *AgentConfigCache) Get(ctx context.Context, clientID, agentInstanceID, agentType string) (*models.AgentConfig, error) {
+	key := cacheKey(clientID, agentInstanceID)
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && c.now().Before(entry.expiresAt) {
+		return entry.config, nil
+	}
+
+	cfg, err := c.load(ctx, clientID, agentInstanceID, agentType)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cachedAgentConfig{config: cfg, expiresAt: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return cfg, nil
+}
+
+// Invalidate drops the cached config for a single instance
+func (c *AgentConfigCache) Invalidate(clientID, agentInstanceID string) {
+	c.mu.Lock()
+	delete(c.entries, cacheKey(clientID, agentInstanceID))
+	c.mu.Unlock()
+}
+
+// InvalidateClient drops every cached config belonging to a client
+func (c *AgentConfigCache) InvalidateClient(clientID string) {
+	prefix := clientID + "/"
+	c.mu.Lock()
+	for key := range c.entries {
+		if strings.HasPrefix(key, prefix) {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// InvalidateAll empties the cache
+func (c *AgentConfigCache) InvalidateAll() {
+	c.mu.Lock()
+	c.entries = make(map[string]cachedAgentConfig)
+	c.mu.Unlock()
+}
+
+// Apply invalidates whatever a change event affects
+func (c *AgentConfigCache) Apply(event AgentConfigChanged) {
+	switch {
+	case event.InstanceID != "" && event.ClientID != "":
+		c.Invalidate(event.ClientID, event.InstanceID)
+	case event.ClientID != "" && event.TemplateID == "":
+		c.InvalidateClient(event.ClientID)
+	default:
+		c.InvalidateAll()
+	}
+}
+
+// Len returns the number of cached entries
+func (c *AgentConfigCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
+
+func cacheKey(clientID, agentInstanceID string) string {
+	return clientID + "/" + agentInstanceID
+}
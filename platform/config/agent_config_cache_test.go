@@ -0,0 +1,63 @@
+// FILE: platform/config/agent_config_cache_test.go
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countingLoader(calls *int) AgentConfigLoadFunc {
+	return func(ctx context.Context, clientID, agentInstanceID, agentType string) (*models.AgentConfig, error) {
+		*calls++
+		return &models.AgentConfig{AgentID: agentInstanceID, AgentType: agentType, Version: *calls}, nil
+	}
+}
+
+func TestAgentConfigCache_HitsAndExpiry(t *testing.T) {
+	calls := 0
+	cache := NewAgentConfigCache(countingLoader(&calls), time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	cfg, err := cache.Get(ctx, "client_a", "inst-1", "generic")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cfg.Version)
+
+	// Second read is served from memory
+	cfg, err = cache.Get(ctx, "client_a", "inst-1", "generic")
+	require.NoError(t, err)
+	assert.Equal(t, 1, cfg.Version)
+	assert.Equal(t, 1, calls)
+
+	// After the TTL the config is reloaded
+	now = now.Add(2 * time.Minute)
+	cfg, err = cache.Get(ctx, "client_a", "inst-1", "generic")
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.Version)
+}
+
+func TestAgentConfigCache_Apply(t *testing.T) {
+	calls := 0
+	cache := NewAgentConfigCache(countingLoader(&calls), time.Minute)
+	ctx := context.Background()
+
+	for _, key := range [][2]string{{"client_a", "inst-1"}, {"client_a", "inst-2"}, {"client_b", "inst-3"}} {
+		_, err := cache.Get(ctx, key[0], key[1], "generic")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 3, cache.Len())
+
+	// Instance update only drops that instance
+	cache.Apply(AgentConfigChanged{EventType: AgentConfigChangedEvent, ClientID: "client_a", InstanceID: "inst-1"})
+	assert.Equal(t, 2, cache.Len())
+
+	// Template update may affect any instance
+	cache.Apply(AgentConfigChanged{EventType: AgentConfigChangedEvent, TemplateID: "tmpl-1"})
+	assert.Equal(t, 0, cache.Len())
+}
@@ -25,7 +25,7 @@ func NewAgentConfigLoader(logger *zap.Logger) *AgentConfigLoader {
 // LoadFromDatabase fetches agent configuration from a client-specific schema
 func (l *AgentConfigLoader) LoadFromDatabase(ctx context.Context, db *pgxpool.Pool, clientID, agentInstanceID, agentType string) (*models.AgentConfig, error) {
 	query := fmt.Sprintf(`
-		SELECT name, config, template_id 
+		SELECT name, config, template_id, config_version
 		FROM client_%s.agent_instances 
 		WHERE id = $1 AND is_active = true
 	`, clientID)
@@ -33,8 +33,9 @@ func (l *AgentConfigLoader) LoadFromDatabase(ctx context.Context, db *pgxpool.Po
 	var name string
 	var configJSON []byte
 	var templateID string
+	var version int
 
-	err := db.QueryRow(ctx, query, agentInstanceID).Scan(&name, &configJSON, &templateID)
+	err := db.QueryRow(ctx, query, agentInstanceID).Scan(&name, &configJSON, &templateID, &version)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			l.logger.Warn("Agent instance not found, using default configuration",
@@ -45,12 +46,12 @@ func (l *AgentConfigLoader) LoadFromDatabase(ctx context.Context, db *pgxpool.Po
 	}
 
 	// Parse and build config
-	return l.parseConfig(configJSON, agentInstanceID, agentType)
+	return l.parseConfig(configJSON, agentInstanceID, agentType, version)
 }
 
 // LoadFromJSON loads agent configuration from JSON data
 func (l *AgentConfigLoader) LoadFromJSON(data []byte, agentInstanceID, agentType string) (*models.AgentConfig, error) {
-	return l.parseConfig(data, agentInstanceID, agentType)
+	return l.parseConfig(data, agentInstanceID, agentType, 1)
 }
 
 // GetDefaultConfig returns a default configuration for an agent type
@@ -59,12 +60,12 @@ func (l *AgentConfigLoader) GetDefaultConfig(agentInstanceID, agentType string)
 		AgentID:   agentInstanceID,
 		AgentType: agentType,
 		Version:   1,
-		CoreLogic: l.getDefaultCoreLogic(agentType),
+		CoreLogic: DefaultCoreLogic(agentType),
 		Workflow:  l.getDefaultWorkflow(agentType),
 	}
 }
 
-func (l *AgentConfigLoader) parseConfig(configJSON []byte, agentInstanceID, agentType string) (*models.AgentConfig, error) {
+func (l *AgentConfigLoader) parseConfig(configJSON []byte, agentInstanceID, agentType string, version int) (*models.AgentConfig, error) {
 	var config map[string]interface{}
 	if err := json.Unmarshal(configJSON, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse agent config: %w", err)
@@ -92,31 +93,43 @@ func (l *AgentConfigLoader) parseConfig(configJSON []byte, agentInstanceID, agen
 	return &models.AgentConfig{
 		AgentID:      agentInstanceID,
 		AgentType:    agentType,
-		Version:      1,
+		Version:      version,
 		CoreLogic:    config,
 		Workflow:     workflow,
 		MemoryConfig: memoryConfig,
 	}, nil
 }
 
-func (l *AgentConfigLoader) getDefaultCoreLogic(agentType string) map[string]interface{} {
+// DefaultCoreLogic is the core logic of personas of agentType that have no stored config
+func DefaultCoreLogic(agentType string) map[string]interface{} {
 	// Different defaults for different agent types
 	switch agentType {
 	case "copywriter":
 		return map[string]interface{}{
-			"model":       "claude-3-sonnet",
+			"model":       "claude-3-5-sonnet-20240620",
 			"temperature": 0.7,
 			"max_tokens":  2000,
 		}
+	case "reasoning":
+		return map[string]interface{}{
+			"model":       "claude-3-opus-20240229",
+			"temperature": 0.2,
+			"max_tokens":  2048,
+			"review_criteria": []interface{}{
+				"logical consistency",
+				"factual accuracy",
+				"clarity",
+			},
+		}
 	case "researcher":
 		return map[string]interface{}{
-			"model":       "claude-3-opus",
+			"model":       "claude-3-opus-20240229",
 			"temperature": 0.3,
 			"max_tokens":  4000,
 		}
 	default:
 		return map[string]interface{}{
-			"model":       "claude-3-haiku",
+			"model":       "claude-3-haiku-20240307",
 			"temperature": 0.5,
 			"max_tokens":  1000,
 		}
@@ -124,7 +137,23 @@ func (l *AgentConfigLoader) getDefaultCoreLogic(agentType string) map[string]int
 }
 
 func (l *AgentConfigLoader) getDefaultWorkflow(agentType string) models.WorkflowPlan {
-	// Could have type-specific default workflows
+	if agentType == "reasoning" {
+		return models.WorkflowPlan{
+			StartStep: "review",
+			Steps: map[string]models.Step{
+				"review": {
+					Action:      "reasoning_review",
+					Description: "Review the content against the criteria",
+					NextStep:    "complete",
+				},
+				"complete": {
+					Action:      "complete_workflow",
+					Description: "Mark workflow as complete",
+				},
+			},
+		}
+	}
+
 	return models.WorkflowPlan{
 		StartStep: "process",
 		Steps: map[string]models.Step{
@@ -0,0 +1,222 @@
+// FILE: platform/database/memory_retention.go
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/pgvector/pgvector-go"
+)
+
+// ClientsWithMemory returns the IDs of clients whose schema has an agent_memory table
+func (r *MemoryRepository) ClientsWithMemory(ctx context.Context) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `
+        SELECT table_schema FROM information_schema.tables
+        WHERE table_schema LIKE 'client\_%' AND table_name = 'agent_memory'
+        ORDER BY table_schema
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list client schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var clients []string
+	for rows.Next() {
+		var schema string
+		if err := rows.Scan(&schema); err != nil {
+			return nil, fmt.Errorf("failed to scan client schema: %w", err)
+		}
+		clients = append(clients, strings.TrimPrefix(schema, "client_"))
+	}
+	return clients, rows.Err()
+}
+
+// InstanceMemoryConfigs returns the raw memory_config of each active instance of a
+// client that has one
+func (r *MemoryRepository) InstanceMemoryConfigs(ctx context.Context, clientID string) (map[uuid.UUID]json.RawMessage, error) {
+	query := fmt.Sprintf(`
+        SELECT id, config->'memory_config'
+        FROM %s
+        WHERE is_active = true AND config ? 'memory_config'
+    `, pgx.Identifier{"client_" + clientID, "agent_instances"}.Sanitize())
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list instance memory configs: %w", err)
+	}
+	defer rows.Close()
+
+	configs := make(map[uuid.UUID]json.RawMessage)
+	for rows.Next() {
+		var id uuid.UUID
+		var config json.RawMessage
+		if err := rows.Scan(&id, &config); err != nil {
+			return nil, fmt.Errorf("failed to scan instance memory config: %w", err)
+		}
+		configs[id] = config
+	}
+	return configs, rows.Err()
+}
+
+// ExpireMemories deletes unpinned memories created before their type's cutoff, or before
+// defaultCutoff for types without one. A nil defaultCutoff keeps those types.
+// Only the instance's own memories are covered; project and client memories are shared
+// and follow ExpireSharedMemories instead.
+func (r *MemoryRepository) ExpireMemories(ctx context.Context, clientID string, agentID uuid.UUID, defaultCutoff *time.Time, typeCutoffs map[string]time.Time) (int64, error) {
+	types := make([]string, 0, len(typeCutoffs))
+	for t := range typeCutoffs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	args := []interface{}{agentID, defaultCutoff}
+	cutoff := "$2::timestamptz"
+	if len(types) > 0 {
+		var b strings.Builder
+		b.WriteString("CASE metadata->>'type'")
+		for _, t := range types {
+			args = append(args, t, typeCutoffs[t])
+			fmt.Fprintf(&b, " WHEN $%d THEN $%d::timestamptz", len(args)-1, len(args))
+		}
+		b.WriteString(" ELSE $2::timestamptz END")
+		cutoff = b.String()
+	}
+
+	query := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE agent_instance_id = $1 AND scope = 'instance' AND NOT pinned AND created_at < %s
+    `, memoryTable(clientID), cutoff)
+	tag, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire memories: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// TrimMemories deletes the oldest unpinned instance memories until the agent has at most
+// max. Pinned memories count towards max but are never deleted.
+func (r *MemoryRepository) TrimMemories(ctx context.Context, clientID string, agentID uuid.UUID, max int) (int64, error) {
+	table := memoryTable(clientID)
+	query := fmt.Sprintf(`
+        DELETE FROM %[1]s
+        WHERE id IN (
+            SELECT id FROM %[1]s
+            WHERE agent_instance_id = $1 AND scope = 'instance' AND NOT pinned
+            ORDER BY created_at DESC
+            OFFSET GREATEST($2 - (SELECT COUNT(*) FROM %[1]s WHERE agent_instance_id = $1 AND scope = 'instance' AND pinned), 0)
+        )
+    `, table)
+	tag, err := r.pool.Exec(ctx, query, agentID, max)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim memories: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ConsolidationCandidates returns up to limit of the agent's newest unpinned instance
+// memories, with their embeddings
+func (r *MemoryRepository) ConsolidationCandidates(ctx context.Context, clientID string, agentID uuid.UUID, limit int) ([]MemoryRecord, error) {
+	query := fmt.Sprintf(`
+        SELECT %s, embedding
+        FROM %s
+        WHERE agent_instance_id = $1 AND scope = 'instance' AND NOT pinned
+        ORDER BY created_at DESC
+        LIMIT $2
+    `, memoryColumns, memoryTable(clientID))
+	rows, err := r.pool.Query(ctx, query, agentID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load memories: %w", err)
+	}
+	defer rows.Close()
+
+	var records []MemoryRecord
+	for rows.Next() {
+		var embedding pgvector.Vector
+		record, err := scanMemory(rows, &embedding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		record.Embedding = embedding.Slice()
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// ReplaceMemories stores a memory in place of the source memories, in one transaction.
+// Nothing changes if any source was deleted or pinned in the meantime.
+func (r *MemoryRepository) ReplaceMemories(ctx context.Context, clientID string, agentID uuid.UUID, sourceIDs []uuid.UUID, content string, embedding []float32, metadata map[string]interface{}) (uuid.UUID, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	table := memoryTable(clientID)
+	tag, err := tx.Exec(ctx, fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE id = ANY($1) AND agent_instance_id = $2 AND scope = 'instance' AND NOT pinned
+    `, table), sourceIDs, agentID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to delete source memories: %w", err)
+	}
+	if tag.RowsAffected() != int64(len(sourceIDs)) {
+		return uuid.Nil, fmt.Errorf("%d of %d source memories changed: %w", int64(len(sourceIDs))-tag.RowsAffected(), len(sourceIDs), ErrMemoryNotFound)
+	}
+
+	var id uuid.UUID
+	err = tx.QueryRow(ctx, fmt.Sprintf(`
+        INSERT INTO %s (agent_instance_id, content, embedding, metadata)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id
+    `, table), agentID, content, pgvector.NewVector(embedding), metadata).Scan(&id)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to insert consolidated memory: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return uuid.Nil, fmt.Errorf("failed to commit consolidation: %w", err)
+	}
+	return id, nil
+}
+
+// ExpireSharedMemories deletes unpinned memories of a shared scope, project or client,
+// created before cutoff
+func (r *MemoryRepository) ExpireSharedMemories(ctx context.Context, clientID, scope string, cutoff time.Time) (int64, error) {
+	query := fmt.Sprintf(`
+        DELETE FROM %s
+        WHERE scope = $1 AND NOT pinned AND created_at < $2
+    `, memoryTable(clientID))
+	tag, err := r.pool.Exec(ctx, query, scope, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire %s memories: %w", scope, err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// TrimSharedMemories deletes the oldest unpinned memories of a shared scope until each
+// project, or the client, has at most max. Pinned memories count towards max but are
+// never deleted.
+func (r *MemoryRepository) TrimSharedMemories(ctx context.Context, clientID, scope string, max int) (int64, error) {
+	table := memoryTable(clientID)
+	query := fmt.Sprintf(`
+        DELETE FROM %[1]s
+        WHERE id IN (
+            SELECT id FROM (
+                SELECT id, pinned, ROW_NUMBER() OVER (PARTITION BY project_id ORDER BY pinned DESC, created_at DESC) AS position
+                FROM %[1]s
+                WHERE scope = $1
+            ) ranked
+            WHERE NOT pinned AND position > $2
+        )
+    `, table)
+	tag, err := r.pool.Exec(ctx, query, scope, max)
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim %s memories: %w", scope, err)
+	}
+	return tag.RowsAffected(), nil
+}
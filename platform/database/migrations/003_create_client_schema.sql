@@ -30,6 +30,11 @@ CREATE TABLE IF NOT EXISTS orchestrator_state (
     awaited_steps JSONB DEFAULT '[]',
     collected_data JSONB DEFAULT '{}',
     initial_request_data JSONB,
+    config_version INTEGER,
+    config_snapshot JSONB,
+    fuel_consumed INTEGER NOT NULL DEFAULT 0,
+    request_headers JSONB,
+    dispatched_steps JSONB,
     final_result JSONB,
     error TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
@@ -58,6 +63,7 @@ EXECUTE format('
             owner_user_id VARCHAR(255) NOT NULL,
             name VARCHAR(255) NOT NULL,
             config JSONB NOT NULL DEFAULT ''{}''::jsonb,
+            config_version INTEGER NOT NULL DEFAULT 1,
             is_active BOOLEAN DEFAULT true,
             created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
             updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
@@ -80,7 +86,12 @@ EXECUTE format('
             content TEXT NOT NULL,
             embedding vector(1536) NOT NULL,
             metadata JSONB DEFAULT ''{}''::jsonb,
-            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+            pinned BOOLEAN NOT NULL DEFAULT false,
+            scope VARCHAR(20) NOT NULL DEFAULT ''instance'',
+            project_id UUID,
+            created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+            CONSTRAINT agent_memory_scope_check CHECK (scope IN (''instance'', ''project'', ''client'') AND (scope <> ''project'' OR project_id IS NOT NULL))
         )', schema_name, schema_name);
 
 -- Vector index for similarity search
@@ -93,6 +104,20 @@ EXECUTE format('
         CREATE INDEX IF NOT EXISTS idx_memory_agent_created
         ON %I.agent_memory(agent_instance_id, created_at DESC)', schema_name);
 
+-- Indexes for keyword and metadata filters in memory search
+EXECUTE format('
+        CREATE INDEX IF NOT EXISTS idx_memory_content_fts
+        ON %I.agent_memory USING gin (to_tsvector(''english'', content))', schema_name);
+
+EXECUTE format('
+        CREATE INDEX IF NOT EXISTS idx_memory_metadata
+        ON %I.agent_memory USING gin (metadata jsonb_path_ops)', schema_name);
+
+-- Index for project and client shared memory queries
+EXECUTE format('
+        CREATE INDEX IF NOT EXISTS idx_memory_scope_project
+        ON %I.agent_memory(scope, project_id, created_at DESC)', schema_name);
+
 -- Projects table for this client
 EXECUTE format('
         CREATE TABLE IF NOT EXISTS %I.projects (
@@ -168,9 +193,9 @@ $$ LANGUAGE plpgsql;
 
 -- Insert default agent definitions
 INSERT INTO agent_definitions (type, display_name, description, category, default_config) VALUES
-                                                                                              ('copywriter', 'Copywriter', 'Creates compelling marketing and content copy', 'data-driven', '{"model": "claude-3-sonnet", "temperature": 0.7}'),
-                                                                                              ('researcher', 'Research Assistant', 'Conducts thorough research and analysis', 'data-driven', '{"model": "claude-3-opus", "temperature": 0.3}'),
-                                                                                              ('reasoning', 'Reasoning Agent', 'Performs logical analysis and decision making', 'code-driven', '{"model": "claude-3-opus", "temperature": 0.2}'),
+                                                                                              ('copywriter', 'Copywriter', 'Creates compelling marketing and content copy', 'data-driven', '{"model": "claude-3-5-sonnet-20240620", "temperature": 0.7}'),
+                                                                                              ('researcher', 'Research Assistant', 'Conducts thorough research and analysis', 'data-driven', '{"model": "claude-3-opus-20240229", "temperature": 0.3}'),
+                                                                                              ('reasoning', 'Reasoning Agent', 'Performs logical analysis and decision making', 'code-driven', '{"model": "claude-3-opus-20240229", "temperature": 0.2}'),
                                                                                               ('image-generator', 'Image Generator', 'Creates images using AI generation', 'adapter', '{"provider": "stability_ai", "model": "sdxl"}'),
                                                                                               ('web-search', 'Web Search', 'Searches the internet for information', 'adapter', '{"provider": "serpapi", "max_results": 10}')
     ON CONFLICT (type) DO UPDATE SET
@@ -196,6 +221,7 @@ CREATE TABLE IF NOT EXISTS client_{client_id}.agent_instances (
     owner_user_id VARCHAR(255) NOT NULL,
     name VARCHAR(255) NOT NULL,
     config JSONB NOT NULL DEFAULT '{}',
+    config_version INTEGER NOT NULL DEFAULT 1,
     is_active BOOLEAN DEFAULT true,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
@@ -211,7 +237,12 @@ CREATE TABLE IF NOT EXISTS client_{client_id}.agent_memory (
     content TEXT NOT NULL,
     embedding vector(1536) NOT NULL,
     metadata JSONB DEFAULT '{}',
-    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+    pinned BOOLEAN NOT NULL DEFAULT false,
+    scope VARCHAR(20) NOT NULL DEFAULT 'instance',
+    project_id UUID,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT agent_memory_scope_check CHECK (scope IN ('instance', 'project', 'client') AND (scope <> 'project' OR project_id IS NOT NULL))
     );
 
 -- Create vector index for similarity search
@@ -225,6 +256,11 @@ CREATE TABLE IF NOT EXISTS client_{client_id}.orchestrator_state (
     awaited_steps JSONB DEFAULT '[]',
     collected_data JSONB DEFAULT '{}',
     initial_request_data JSONB,
+    config_version INTEGER,
+    config_snapshot JSONB,
+    fuel_consumed INTEGER NOT NULL DEFAULT 0,
+    request_headers JSONB,
+    dispatched_steps JSONB,
     final_result JSONB,
     error TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
@@ -234,3 +270,5 @@ CREATE TABLE IF NOT EXISTS client_{client_id}.orchestrator_state (
 CREATE INDEX idx_orchestrator_status ON client_{client_id}.orchestrator_state(status);
 
 CREATE INDEX idx_memory_agent_created ON client_{client_id}.agent_memory(agent_instance_id, created_at DESC);
+CREATE INDEX idx_memory_content_fts ON client_{client_id}.agent_memory USING gin (to_tsvector('english', content));
+CREATE INDEX idx_memory_metadata ON client_{client_id}.agent_memory USING gin (metadata jsonb_path_ops);
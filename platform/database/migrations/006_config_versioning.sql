@@ -0,0 +1,26 @@
+-- FILE: platform/database/migrations/006_config_versioning.sql
+-- Config versioning for agent instances and pinned config snapshots for workflows
+
+-- Workflows keep the config they started with so mid-run edits cannot change their plan
+ALTER TABLE orchestrator_state ADD COLUMN IF NOT EXISTS config_version INTEGER;
+ALTER TABLE orchestrator_state ADD COLUMN IF NOT EXISTS config_snapshot JSONB;
+
+-- Existing client schemas get a config version on every agent instance
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE 'client\_%'
+    LOOP
+        EXECUTE format('ALTER TABLE %I.agent_instances ADD COLUMN IF NOT EXISTS config_version INTEGER NOT NULL DEFAULT 1', client_schema);
+
+        IF EXISTS (
+            SELECT 1 FROM information_schema.tables
+            WHERE table_schema = client_schema AND table_name = 'orchestrator_state'
+        ) THEN
+            EXECUTE format('ALTER TABLE %I.orchestrator_state ADD COLUMN IF NOT EXISTS config_version INTEGER', client_schema);
+            EXECUTE format('ALTER TABLE %I.orchestrator_state ADD COLUMN IF NOT EXISTS config_snapshot JSONB', client_schema);
+        END IF;
+    END LOOP;
+END $$;
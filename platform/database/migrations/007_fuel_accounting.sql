@@ -0,0 +1,16 @@
+-- FILE: platform/database/migrations/007_fuel_accounting.sql
+-- Records the fuel each workflow has consumed, including token-metered AI calls
+
+ALTER TABLE orchestrator_state ADD COLUMN IF NOT EXISTS fuel_consumed INTEGER NOT NULL DEFAULT 0;
+
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT table_schema FROM information_schema.tables
+        WHERE table_name = 'orchestrator_state' AND table_schema LIKE 'client\_%'
+    LOOP
+        EXECUTE format('ALTER TABLE %I.orchestrator_state ADD COLUMN IF NOT EXISTS fuel_consumed INTEGER NOT NULL DEFAULT 0', client_schema);
+    END LOOP;
+END $$;
@@ -0,0 +1,29 @@
+-- FILE: platform/database/migrations/008_prompt_templates.sql
+-- Versioned prompt templates in the templates database.
+-- Versions are immutable; editing a template adds a new version.
+CREATE TABLE IF NOT EXISTS prompt_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    latest_version INTEGER NOT NULL DEFAULT 1,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS prompt_template_versions (
+    template_id UUID NOT NULL REFERENCES prompt_templates(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    system_template TEXT NOT NULL DEFAULT '',
+    body TEXT NOT NULL,
+    variables JSONB NOT NULL DEFAULT '[]',
+    created_by VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (template_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_prompt_templates_active ON prompt_templates(is_active);
+
+-- Names are unique among active templates, so a deleted template's name can be reused
+ALTER TABLE prompt_templates DROP CONSTRAINT IF EXISTS prompt_templates_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_templates_active_name ON prompt_templates(name) WHERE is_active;
@@ -0,0 +1,27 @@
+-- FILE: platform/database/migrations/009_ai_response_cache.sql
+-- Cached AI responses for personas that opt in with response_cache. Keys and scopes hash
+-- the client and persona instance, so answers are never reused across tenants.
+-- Requires the pgvector extension (001_enable_pgvector.sql) for semantic matching.
+
+CREATE TABLE IF NOT EXISTS ai_response_cache (
+    cache_key CHAR(64) PRIMARY KEY,
+    client_id VARCHAR(255) NOT NULL,
+    scope CHAR(64) NOT NULL,
+    prompt TEXT NOT NULL,
+    embedding vector(1536),
+    response JSONB NOT NULL,
+    hits INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_response_cache_scope ON ai_response_cache(scope, expires_at);
+CREATE INDEX IF NOT EXISTS idx_ai_response_cache_expires ON ai_response_cache(expires_at);
+CREATE INDEX IF NOT EXISTS idx_ai_response_cache_embedding
+    ON ai_response_cache USING ivfflat (embedding vector_cosine_ops) WITH (lists = 100);
+
+-- Tables created before entries were partitioned by client. Those entries could be
+-- served to any client, so they are dropped.
+ALTER TABLE ai_response_cache ADD COLUMN IF NOT EXISTS client_id VARCHAR(255);
+DELETE FROM ai_response_cache WHERE client_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_ai_response_cache_client ON ai_response_cache(client_id);
@@ -0,0 +1,21 @@
+-- FILE: platform/database/migrations/010_memory_management.sql
+-- Lets users pin and edit what their personas remember
+
+-- Existing client schemas get the columns already in create_client_schema
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE 'client\_%'
+    LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.tables
+            WHERE table_schema = client_schema AND table_name = 'agent_memory'
+        ) THEN
+            -- Pinned memories are kept by retention and offered to the agent ahead of the relevant ones
+            EXECUTE format('ALTER TABLE %I.agent_memory ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT false', client_schema);
+            EXECUTE format('ALTER TABLE %I.agent_memory ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()', client_schema);
+        END IF;
+    END LOOP;
+END $$;
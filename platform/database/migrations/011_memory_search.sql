@@ -0,0 +1,20 @@
+-- FILE: platform/database/migrations/011_memory_search.sql
+-- Indexes for hybrid memory search: full-text matching on content and metadata filters
+
+-- Existing client schemas get the indexes already in create_client_schema
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE 'client\_%'
+    LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.tables
+            WHERE table_schema = client_schema AND table_name = 'agent_memory'
+        ) THEN
+            EXECUTE format('CREATE INDEX IF NOT EXISTS idx_memory_content_fts ON %I.agent_memory USING gin (to_tsvector(''english'', content))', client_schema);
+            EXECUTE format('CREATE INDEX IF NOT EXISTS idx_memory_metadata ON %I.agent_memory USING gin (metadata jsonb_path_ops)', client_schema);
+        END IF;
+    END LOOP;
+END $$;
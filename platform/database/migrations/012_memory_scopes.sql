@@ -0,0 +1,25 @@
+-- FILE: platform/database/migrations/012_memory_scopes.sql
+-- Lets personas share memories with their project or their whole client
+
+-- Existing client schemas get the columns already in create_client_schema
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT schema_name FROM information_schema.schemata WHERE schema_name LIKE 'client\_%'
+    LOOP
+        IF EXISTS (
+            SELECT 1 FROM information_schema.tables
+            WHERE table_schema = client_schema AND table_name = 'agent_memory'
+        ) THEN
+            -- instance memories belong to the storing instance, project memories to every
+            -- instance on the project and client memories to every instance of the client
+            EXECUTE format('ALTER TABLE %I.agent_memory ADD COLUMN IF NOT EXISTS scope VARCHAR(20) NOT NULL DEFAULT ''instance''', client_schema);
+            EXECUTE format('ALTER TABLE %I.agent_memory ADD COLUMN IF NOT EXISTS project_id UUID', client_schema);
+            EXECUTE format('ALTER TABLE %I.agent_memory DROP CONSTRAINT IF EXISTS agent_memory_scope_check', client_schema);
+            EXECUTE format('ALTER TABLE %I.agent_memory ADD CONSTRAINT agent_memory_scope_check CHECK (scope IN (''instance'', ''project'', ''client'') AND (scope <> ''project'' OR project_id IS NOT NULL))', client_schema);
+            EXECUTE format('CREATE INDEX IF NOT EXISTS idx_memory_scope_project ON %I.agent_memory(scope, project_id, created_at DESC)', client_schema);
+        END IF;
+    END LOOP;
+END $$;
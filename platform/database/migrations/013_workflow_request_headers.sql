@@ -0,0 +1,17 @@
+-- FILE: platform/database/migrations/013_workflow_request_headers.sql
+-- Keeps the headers of the request that started a workflow, so it can continue
+-- and reply to its caller when remote steps respond
+
+ALTER TABLE orchestrator_state ADD COLUMN IF NOT EXISTS request_headers JSONB;
+
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT table_schema FROM information_schema.tables
+        WHERE table_name = 'orchestrator_state' AND table_schema LIKE 'client\_%'
+    LOOP
+        EXECUTE format('ALTER TABLE %I.orchestrator_state ADD COLUMN IF NOT EXISTS request_headers JSONB', client_schema);
+    END LOOP;
+END $$;
@@ -0,0 +1,17 @@
+-- FILE: platform/database/migrations/014_workflow_dispatched_steps.sql
+-- Records which workflow step sent each remote request, so responses are
+-- collected under that step
+
+ALTER TABLE orchestrator_state ADD COLUMN IF NOT EXISTS dispatched_steps JSONB;
+
+DO $$
+DECLARE
+    client_schema TEXT;
+BEGIN
+    FOR client_schema IN
+        SELECT table_schema FROM information_schema.tables
+        WHERE table_name = 'orchestrator_state' AND table_schema LIKE 'client\_%'
+    LOOP
+        EXECUTE format('ALTER TABLE %I.orchestrator_state ADD COLUMN IF NOT EXISTS dispatched_steps JSONB', client_schema);
+    END LOOP;
+END $$;
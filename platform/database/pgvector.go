@@ -3,24 +3,71 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
 	"go.uber.org/zap"
 )
 
-// MemoryRecord represents a single entry in the agent_memory table
+// ErrMemoryNotFound is returned when a memory does not exist for the agent instance
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// Memory scopes. Instance memories are seen only by the instance that stored them,
+// project memories by every instance working on the project and client memories by
+// every instance of the client.
+const (
+	ScopeInstance = "instance"
+	ScopeProject  = "project"
+	ScopeClient   = "client"
+)
+
+// ValidScope reports whether scope is a memory scope
+func ValidScope(scope string) bool {
+	return scope == ScopeInstance || scope == ScopeProject || scope == ScopeClient
+}
+
+// MemoryScope says who shares a stored memory. The zero value is the instance scope.
+type MemoryScope struct {
+	Level     string    // ScopeInstance, ScopeProject or ScopeClient
+	ProjectID uuid.UUID // Required for ScopeProject
+}
+
+// MemoryRecord represents a single entry in a client's agent_memory table
 type MemoryRecord struct {
-	ID              uuid.UUID
-	AgentInstanceID uuid.UUID
-	Content         string
-	Embedding       []float32
-	Metadata        map[string]interface{}
+	ID              uuid.UUID              `json:"id"`
+	AgentInstanceID uuid.UUID              `json:"agent_instance_id"`
+	Content         string                 `json:"content"`
+	Embedding       []float32              `json:"-"`
+	Metadata        map[string]interface{} `json:"metadata"`
+	Pinned          bool                   `json:"pinned"`
+	Scope           string                 `json:"scope"`
+	ProjectID       *uuid.UUID             `json:"project_id,omitempty"`
+	Similarity      float64                `json:"similarity,omitempty"` // Cosine similarity to the search, set by searches
+	Score           float64                `json:"score,omitempty"`      // Ranking score, set by searches
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+// MemoryFilter narrows a memory listing. Without a scope, the listing holds every memory
+// the instance stored; with one, it holds the memories of that scope visible to the
+// instance, whichever instance stored them.
+type MemoryFilter struct {
+	Type      string // metadata type, e.g. workflow_step
+	Pinned    *bool
+	Scope     string
+	ProjectID uuid.UUID // Project listed for ScopeProject
+	Limit     int
+	Offset    int
 }
 
-// MemoryRepository provides methods for storing and retrieving agent memories
+// MemoryRepository provides methods for storing and retrieving agent memories.
+// Memories live in each client's schema, so every method takes the client ID.
 type MemoryRepository struct {
 	pool   *pgxpool.Pool
 	logger *zap.Logger
@@ -31,38 +78,87 @@ func NewMemoryRepository(pool *pgxpool.Pool, logger *zap.Logger) *MemoryReposito
 	return &MemoryRepository{pool: pool, logger: logger}
 }
 
-// StoreMemory saves a new memory record to the database for a specific agent
-func (r *MemoryRepository) StoreMemory(ctx context.Context, agentID uuid.UUID, content string, embedding []float32, metadata map[string]interface{}) error {
-	l := r.logger.With(zap.String("agent_id", agentID.String()))
-	l.Info("Storing new memory")
+// memoryTable returns the quoted agent_memory table of a client's schema
+func memoryTable(clientID string) string {
+	return pgx.Identifier{"client_" + clientID, "agent_memory"}.Sanitize()
+}
+
+const memoryColumns = `id, agent_instance_id, content, metadata, pinned, scope, project_id, created_at, updated_at`
 
-	query := `
-        INSERT INTO agent_memory (agent_instance_id, content, embedding, metadata)
-        VALUES ($1, $2, $3, $4)
-    `
-	_, err := r.pool.Exec(ctx, query, agentID, content, pgvector.NewVector(embedding), metadata)
+func scanMemory(row pgx.Row, extra ...interface{}) (*MemoryRecord, error) {
+	var record MemoryRecord
+	dest := append([]interface{}{
+		&record.ID, &record.AgentInstanceID, &record.Content, &record.Metadata,
+		&record.Pinned, &record.Scope, &record.ProjectID, &record.CreatedAt, &record.UpdatedAt,
+	}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// StoreMemory saves a new memory record to the database for a specific agent, shared
+// as far as scope says
+func (r *MemoryRepository) StoreMemory(ctx context.Context, clientID string, agentID uuid.UUID, scope MemoryScope, content string, embedding []float32, metadata map[string]interface{}) (uuid.UUID, error) {
+	level := scope.Level
+	if level == "" {
+		level = ScopeInstance
+	}
+	var projectID *uuid.UUID
+	if level == ScopeProject {
+		projectID = &scope.ProjectID
+	}
+
+	l := r.logger.With(zap.String("client_id", clientID), zap.String("agent_id", agentID.String()))
+	l.Info("Storing new memory", zap.String("scope", level))
+
+	query := fmt.Sprintf(`
+        INSERT INTO %s (agent_instance_id, content, embedding, metadata, scope, project_id)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING id
+    `, memoryTable(clientID))
+	var id uuid.UUID
+	err := r.pool.QueryRow(ctx, query, agentID, content, pgvector.NewVector(embedding), metadata, level, projectID).Scan(&id)
 	if err != nil {
 		l.Error("Failed to store agent memory", zap.Error(err))
-		return fmt.Errorf("failed to insert memory record: %w", err)
+		return uuid.Nil, fmt.Errorf("failed to insert memory record: %w", err)
 	}
 
-	l.Debug("Successfully stored memory record")
-	return nil
+	l.Debug("Successfully stored memory record", zap.String("memory_id", id.String()))
+	return id, nil
+}
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion
+const rrfK = 60
+
+// MemorySearch describes a memory search. Memories are ranked by similarity to
+// Embedding; when Text is set, full-text matches are fused in by reciprocal rank.
+// Weights picks the scopes searched and multiplies each memory's score by the weight of
+// its scope, so an instance's own memories can outrank equally relevant shared ones.
+type MemorySearch struct {
+	Embedding     []float32
+	Text          string                 // Full-text query; empty searches by vector alone
+	Types         []string               // metadata types to include; empty includes all
+	Since         time.Time              // Created at or after, when set
+	Until         time.Time              // Created before, when set
+	Metadata      map[string]interface{} // Memories whose metadata contains these keys and values
+	MinSimilarity float64                // Memories less similar than this are dropped, full-text matches included
+	Weights       map[string]float64     // Weight by scope; empty searches the instance's own memories
+	ProjectID     uuid.UUID              // Project searched by the project scope
+	Limit         int
 }
 
-// SearchMemory performs a semantic similarity search to find the most relevant memories
-func (r *MemoryRepository) SearchMemory(ctx context.Context, agentID uuid.UUID, queryEmbedding []float32, limit int) ([]MemoryRecord, error) {
-	l := r.logger.With(zap.String("agent_id", agentID.String()))
-	l.Info("Searching for relevant memories", zap.Int("limit", limit))
+// DefaultScopeWeights searches only the instance's own memories
+var DefaultScopeWeights = map[string]float64{ScopeInstance: 1}
 
-	query := `
-        SELECT id, content, metadata
-        FROM agent_memory
-        WHERE agent_instance_id = $1
-        ORDER BY embedding <=> $2
-        LIMIT $3
-    `
-	rows, err := r.pool.Query(ctx, query, agentID, pgvector.NewVector(queryEmbedding), limit)
+// SearchMemory finds the memories most relevant to a search. Results carry their cosine
+// similarity; hybrid results also carry their fused score.
+func (r *MemoryRepository) SearchMemory(ctx context.Context, clientID string, agentID uuid.UUID, search MemorySearch) ([]MemoryRecord, error) {
+	l := r.logger.With(zap.String("client_id", clientID), zap.String("agent_id", agentID.String()))
+	l.Info("Searching for relevant memories", zap.Int("limit", search.Limit), zap.Bool("hybrid", search.Text != ""))
+
+	query, args := memorySearchQuery(memoryTable(clientID), agentID, search)
+	rows, err := r.pool.Query(ctx, query, args...)
 	if err != nil {
 		l.Error("Failed to execute memory search query", zap.Error(err))
 		return nil, fmt.Errorf("failed to search memory: %w", err)
@@ -71,15 +167,292 @@ func (r *MemoryRepository) SearchMemory(ctx context.Context, agentID uuid.UUID,
 
 	var results []MemoryRecord
 	for rows.Next() {
-		var record MemoryRecord
-		record.AgentInstanceID = agentID
-		if err := rows.Scan(&record.ID, &record.Content, &record.Metadata); err != nil {
+		var similarity, score float64
+		record, err := scanMemory(rows, &similarity, &score)
+		if err != nil {
 			l.Error("Failed to scan memory search result", zap.Error(err))
 			continue
 		}
-		results = append(results, record)
+		record.Similarity = similarity
+		record.Score = score
+		results = append(results, *record)
 	}
 
 	l.Info("Memory search completed", zap.Int("results_found", len(results)))
-	return results, nil
+	return results, rows.Err()
+}
+
+// memorySearchQuery builds the search SQL. Filters, MinSimilarity among them, apply to
+// both the vector and the full-text candidates, which are fused as sum(1 / (rrfK + rank))
+// and weighted by scope.
+func memorySearchQuery(table string, agentID uuid.UUID, search MemorySearch) (string, []interface{}) {
+	args := []interface{}{pgvector.NewVector(search.Embedding)}
+	visible, weight := scopeTerms(agentID, search, &args)
+	conditions := []string{visible}
+	if len(search.Types) > 0 {
+		args = append(args, search.Types)
+		conditions = append(conditions, fmt.Sprintf("metadata->>'type' = ANY($%d)", len(args)))
+	}
+	if !search.Since.IsZero() {
+		args = append(args, search.Since)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !search.Until.IsZero() {
+		args = append(args, search.Until)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if len(search.Metadata) > 0 {
+		args = append(args, search.Metadata)
+		conditions = append(conditions, fmt.Sprintf("metadata @> $%d::jsonb", len(args)))
+	}
+	if search.MinSimilarity > 0 {
+		args = append(args, search.MinSimilarity)
+		conditions = append(conditions, fmt.Sprintf("1 - (embedding <=> $1) >= $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	// Each ranking contributes a few times as many candidates as are returned
+	candidates := max(search.Limit*4, 50)
+	if search.Text == "" {
+		args = append(args, candidates, search.Limit)
+		return fmt.Sprintf(`
+        WITH vector_matches AS (
+            SELECT id, 1 - (embedding <=> $1) AS similarity
+            FROM %[1]s
+            WHERE %[2]s
+            ORDER BY embedding <=> $1
+            LIMIT $%[3]d
+        )
+        SELECT %[5]s, v.similarity, v.similarity * %[6]s AS score
+        FROM vector_matches v
+        JOIN %[1]s m ON m.id = v.id
+        ORDER BY score DESC
+        LIMIT $%[4]d
+    `, table, where, len(args)-1, len(args), prefixedMemoryColumns("m"), weight), args
+	}
+
+	args = append(args, search.Text, candidates, search.Limit)
+	textArg, candidatesArg, limitArg := len(args)-2, len(args)-1, len(args)
+	return fmt.Sprintf(`
+        WITH vector_matches AS (
+            SELECT id, ROW_NUMBER() OVER (ORDER BY embedding <=> $1) AS rank
+            FROM %[1]s
+            WHERE %[2]s
+            ORDER BY embedding <=> $1
+            LIMIT $%[4]d
+        ), text_matches AS (
+            SELECT id, ROW_NUMBER() OVER (
+                ORDER BY ts_rank_cd(to_tsvector('english', content), plainto_tsquery('english', $%[3]d)) DESC
+            ) AS rank
+            FROM %[1]s
+            WHERE %[2]s AND to_tsvector('english', content) @@ plainto_tsquery('english', $%[3]d)
+            ORDER BY rank
+            LIMIT $%[4]d
+        )
+        SELECT %[6]s, 1 - (m.embedding <=> $1),
+            (COALESCE(1.0 / (%[7]d + v.rank), 0) + COALESCE(1.0 / (%[7]d + t.rank), 0)) * %[8]s AS score
+        FROM vector_matches v
+        FULL OUTER JOIN text_matches t ON t.id = v.id
+        JOIN %[1]s m ON m.id = COALESCE(v.id, t.id)
+        ORDER BY score DESC
+        LIMIT $%[5]d
+    `, table, where, textArg, candidatesArg, limitArg, prefixedMemoryColumns("m"), rrfK, weight), args
+}
+
+// scopeTerms returns the condition for the memories a search can see and the weight of
+// a memory "m" by its scope. The instance scope covers the instance's own memories, the
+// project scope those of search.ProjectID and the client scope every shared memory of
+// the client. Scopes without a positive weight are not searched.
+func scopeTerms(agentID uuid.UUID, search MemorySearch, args *[]interface{}) (string, string) {
+	weights := search.Weights
+	if len(weights) == 0 {
+		weights = DefaultScopeWeights
+	}
+
+	var visible, cases []string
+	for _, scope := range []string{ScopeInstance, ScopeProject, ScopeClient} {
+		w := weights[scope]
+		if w <= 0 || (scope == ScopeProject && search.ProjectID == uuid.Nil) {
+			continue
+		}
+		switch scope {
+		case ScopeInstance:
+			*args = append(*args, agentID)
+			visible = append(visible, fmt.Sprintf("(scope = 'instance' AND agent_instance_id = $%d)", len(*args)))
+		case ScopeProject:
+			*args = append(*args, search.ProjectID)
+			visible = append(visible, fmt.Sprintf("(scope = 'project' AND project_id = $%d)", len(*args)))
+		case ScopeClient:
+			visible = append(visible, "scope = 'client'")
+		}
+		*args = append(*args, w)
+		cases = append(cases, fmt.Sprintf("WHEN '%s' THEN $%d::float8", scope, len(*args)))
+	}
+	if len(visible) == 0 {
+		return "false", "0"
+	}
+	return "(" + strings.Join(visible, " OR ") + ")",
+		"CASE m.scope " + strings.Join(cases, " ") + " ELSE 0 END"
+}
+
+// prefixedMemoryColumns qualifies memoryColumns with a table alias
+func prefixedMemoryColumns(alias string) string {
+	columns := strings.Split(memoryColumns, ", ")
+	for i, column := range columns {
+		columns[i] = alias + "." + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// ListMemories returns an agent's memories, pinned first and then newest first, with
+// the total number matching the filter
+func (r *MemoryRepository) ListMemories(ctx context.Context, clientID string, agentID uuid.UUID, filter MemoryFilter) ([]MemoryRecord, int, error) {
+	var conditions []string
+	var args []interface{}
+	switch filter.Scope {
+	case "":
+		args = append(args, agentID)
+		conditions = append(conditions, "agent_instance_id = $1")
+	case ScopeInstance:
+		args = append(args, agentID)
+		conditions = append(conditions, "scope = 'instance' AND agent_instance_id = $1")
+	case ScopeProject:
+		args = append(args, filter.ProjectID)
+		conditions = append(conditions, fmt.Sprintf("scope = 'project' AND project_id = $%d", len(args)))
+	case ScopeClient:
+		conditions = append(conditions, "scope = 'client'")
+	default:
+		return nil, 0, fmt.Errorf("unknown memory scope %q", filter.Scope)
+	}
+	if filter.Type != "" {
+		args = append(args, filter.Type)
+		conditions = append(conditions, fmt.Sprintf("metadata->>'type' = $%d", len(args)))
+	}
+	if filter.Pinned != nil {
+		args = append(args, *filter.Pinned)
+		conditions = append(conditions, fmt.Sprintf("pinned = $%d", len(args)))
+	}
+	args = append(args, filter.Limit, filter.Offset)
+
+	query := fmt.Sprintf(`
+        SELECT %s, COUNT(*) OVER()
+        FROM %s
+        WHERE %s
+        ORDER BY pinned DESC, created_at DESC
+        LIMIT $%d OFFSET $%d
+    `, memoryColumns, memoryTable(clientID), strings.Join(conditions, " AND "), len(args)-1, len(args))
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list memories: %w", err)
+	}
+	defer rows.Close()
+
+	records := []MemoryRecord{}
+	total := 0
+	for rows.Next() {
+		record, err := scanMemory(rows, &total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan memory: %w", err)
+		}
+		records = append(records, *record)
+	}
+	return records, total, rows.Err()
+}
+
+// GetMemory returns one of an agent's memories
+func (r *MemoryRepository) GetMemory(ctx context.Context, clientID string, agentID, id uuid.UUID) (*MemoryRecord, error) {
+	query := fmt.Sprintf(`
+        SELECT %s FROM %s
+        WHERE id = $1 AND agent_instance_id = $2
+    `, memoryColumns, memoryTable(clientID))
+	record, err := scanMemory(r.pool.QueryRow(ctx, query, id, agentID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMemoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory: %w", err)
+	}
+	return record, nil
+}
+
+// UpdateMemory replaces a memory's content and metadata. The embedding is replaced
+// when the record has one and kept otherwise.
+func (r *MemoryRepository) UpdateMemory(ctx context.Context, clientID string, agentID uuid.UUID, record *MemoryRecord) (*MemoryRecord, error) {
+	var embedding interface{}
+	if record.Embedding != nil {
+		embedding = pgvector.NewVector(record.Embedding)
+	}
+	query := fmt.Sprintf(`
+        UPDATE %s
+        SET content = $3, embedding = COALESCE($4, embedding), metadata = $5, updated_at = NOW()
+        WHERE id = $1 AND agent_instance_id = $2
+        RETURNING %s
+    `, memoryTable(clientID), memoryColumns)
+	updated, err := scanMemory(r.pool.QueryRow(ctx, query, record.ID, agentID,
+		record.Content, embedding, record.Metadata))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMemoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update memory: %w", err)
+	}
+	return updated, nil
+}
+
+// SetPinned pins or unpins a memory
+func (r *MemoryRepository) SetPinned(ctx context.Context, clientID string, agentID, id uuid.UUID, pinned bool) (*MemoryRecord, error) {
+	query := fmt.Sprintf(`
+        UPDATE %s
+        SET pinned = $3, updated_at = NOW()
+        WHERE id = $1 AND agent_instance_id = $2
+        RETURNING %s
+    `, memoryTable(clientID), memoryColumns)
+	updated, err := scanMemory(r.pool.QueryRow(ctx, query, id, agentID, pinned))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrMemoryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pin memory: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteMemory permanently removes a memory
+func (r *MemoryRepository) DeleteMemory(ctx context.Context, clientID string, agentID, id uuid.UUID) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id = $1 AND agent_instance_id = $2`, memoryTable(clientID))
+	tag, err := r.pool.Exec(ctx, query, id, agentID)
+	if err != nil {
+		return fmt.Errorf("failed to delete memory: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMemoryNotFound
+	}
+	return nil
+}
+
+// ProjectExists reports whether an active project exists in the client's schema
+func (r *MemoryRepository) ProjectExists(ctx context.Context, clientID string, projectID uuid.UUID) (bool, error) {
+	query := fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1 AND is_active)`,
+		pgx.Identifier{"client_" + clientID, "projects"}.Sanitize())
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, projectID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to look up project: %w", err)
+	}
+	return exists, nil
+}
+
+// DeleteMatchingMemories deletes the agent's memories whose metadata contains match but
+// not except, pinned or not, and returns how many were deleted. except may be nil.
+func (r *MemoryRepository) DeleteMatchingMemories(ctx context.Context, clientID string, agentID uuid.UUID, match, except map[string]interface{}) (int64, error) {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE agent_instance_id = $1 AND metadata @> $2::jsonb`, memoryTable(clientID))
+	args := []interface{}{agentID, match}
+	if len(except) > 0 {
+		query += ` AND NOT metadata @> $3::jsonb`
+		args = append(args, except)
+	}
+	tag, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete memories: %w", err)
+	}
+	return tag.RowsAffected(), nil
 }
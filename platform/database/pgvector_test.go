@@ -0,0 +1,83 @@
+// FILE: platform/database/pgvector_test.go
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fields collapses whitespace so queries compare line by line
+func fields(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func TestMemorySearchQuery_Vector(t *testing.T) {
+	agentID := uuid.New()
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	query, args := memorySearchQuery(`"client_acme"."agent_memory"`, agentID, MemorySearch{
+		Embedding:     []float32{1, 0},
+		Types:         []string{"preference"},
+		Since:         since,
+		MinSimilarity: 0.7,
+		Limit:         5,
+	})
+	query = fields(query)
+
+	assert.Contains(t, query, "WHERE ((scope = 'instance' AND agent_instance_id = $2)) AND metadata->>'type' = ANY($4) AND created_at >= $5 AND 1 - (embedding <=> $1) >= $6")
+	assert.Contains(t, query, "LIMIT $7")
+	assert.Contains(t, query, "v.similarity * CASE m.scope WHEN 'instance' THEN $3::float8 ELSE 0 END AS score")
+	assert.True(t, strings.HasSuffix(query, "LIMIT $8"))
+	assert.NotContains(t, query, "text_matches")
+
+	require.Len(t, args, 8)
+	assert.Equal(t, agentID, args[1])
+	assert.Equal(t, 1.0, args[2])
+	assert.Equal(t, []string{"preference"}, args[3])
+	assert.Equal(t, since, args[4])
+	assert.Equal(t, 0.7, args[5])
+	assert.Equal(t, 50, args[6]) // At least 50 candidates
+	assert.Equal(t, 5, args[7])
+}
+
+func TestMemorySearchQuery_HybridAppliesMinSimilarityToTextMatches(t *testing.T) {
+	agentID, projectID := uuid.New(), uuid.New()
+
+	query, args := memorySearchQuery(`"client_acme"."agent_memory"`, agentID, MemorySearch{
+		Embedding:     []float32{1, 0},
+		Text:          "brand voice",
+		MinSimilarity: 0.5,
+		Weights:       map[string]float64{ScopeInstance: 1, ScopeProject: 0.8, ScopeClient: 0},
+		ProjectID:     projectID,
+		Limit:         20,
+	})
+	query = fields(query)
+
+	visible := "((scope = 'instance' AND agent_instance_id = $2) OR (scope = 'project' AND project_id = $4)) AND 1 - (embedding <=> $1) >= $6"
+	assert.Equal(t, 2, strings.Count(query, "WHERE "+visible), "both rankings are filtered")
+	assert.Contains(t, query, "WHERE "+visible+" AND to_tsvector('english', content) @@ plainto_tsquery('english', $7)")
+	assert.Contains(t, query, "CASE m.scope WHEN 'instance' THEN $3::float8 WHEN 'project' THEN $5::float8 ELSE 0 END")
+	assert.NotContains(t, query, "scope = 'client'", "zero weights are not searched")
+	assert.True(t, strings.HasSuffix(query, "LIMIT $9"))
+
+	require.Len(t, args, 9)
+	assert.Equal(t, projectID, args[3])
+	assert.Equal(t, "brand voice", args[6])
+	assert.Equal(t, 80, args[7])
+	assert.Equal(t, 20, args[8])
+}
+
+func TestMemorySearchQuery_NoVisibleScope(t *testing.T) {
+	// The project scope needs a project; without one nothing is visible
+	query, _ := memorySearchQuery(`"client_acme"."agent_memory"`, uuid.New(), MemorySearch{
+		Embedding: []float32{1, 0},
+		Weights:   map[string]float64{ScopeProject: 1},
+		Limit:     5,
+	})
+	assert.Contains(t, fields(query), "WHERE false")
+}
@@ -0,0 +1,117 @@
+// FILE: platform/database/response_cache.go
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+	"go.uber.org/zap"
+)
+
+// ResponseCacheRepository stores cached AI responses in the ai_response_cache table,
+// using pgvector for semantic matching. It implements aiservice.SemanticCache. Keys and
+// scopes hash the client and persona instance, so entries are never shared between them.
+type ResponseCacheRepository struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+// NewResponseCacheRepository creates a response cache on the clients database
+func NewResponseCacheRepository(pool *pgxpool.Pool, logger *zap.Logger) *ResponseCacheRepository {
+	return &ResponseCacheRepository{pool: pool, logger: logger}
+}
+
+// Get returns the unexpired response stored under key, counting the hit
+func (r *ResponseCacheRepository) Get(ctx context.Context, key string) (*aiservice.GenerateResponse, error) {
+	query := `
+        UPDATE ai_response_cache SET hits = hits + 1
+        WHERE cache_key = $1 AND expires_at > NOW()
+        RETURNING response
+    `
+	var data []byte
+	if err := r.pool.QueryRow(ctx, query, key).Scan(&data); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read cached response: %w", err)
+	}
+	return decodeResponse(data)
+}
+
+// Put stores an entry, replacing any earlier response for the same key
+func (r *ResponseCacheRepository) Put(ctx context.Context, entry *aiservice.CacheEntry) error {
+	data, err := json.Marshal(entry.Response)
+	if err != nil {
+		return fmt.Errorf("failed to marshal response: %w", err)
+	}
+	var embedding interface{}
+	if len(entry.Embedding) > 0 {
+		embedding = pgvector.NewVector(entry.Embedding)
+	}
+
+	query := `
+        INSERT INTO ai_response_cache (cache_key, client_id, scope, prompt, embedding, response, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        ON CONFLICT (cache_key) DO UPDATE SET
+            response = EXCLUDED.response,
+            embedding = COALESCE(EXCLUDED.embedding, ai_response_cache.embedding),
+            expires_at = EXCLUDED.expires_at,
+            created_at = NOW()
+    `
+	if _, err := r.pool.Exec(ctx, query, entry.Key, entry.ClientID, entry.Scope, entry.Prompt, embedding, data, entry.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to store cached response: %w", err)
+	}
+	return nil
+}
+
+// Nearest returns the unexpired response in scope with the most similar prompt embedding
+func (r *ResponseCacheRepository) Nearest(ctx context.Context, scope string, embedding []float32, threshold float64) (*aiservice.GenerateResponse, error) {
+	query := `
+        SELECT cache_key, response, 1 - (embedding <=> $2) AS similarity
+        FROM ai_response_cache
+        WHERE scope = $1 AND embedding IS NOT NULL AND expires_at > NOW()
+        ORDER BY embedding <=> $2
+        LIMIT 1
+    `
+	var key string
+	var data []byte
+	var similarity float64
+	err := r.pool.QueryRow(ctx, query, scope, pgvector.NewVector(embedding)).Scan(&key, &data, &similarity)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to search cached responses: %w", err)
+	}
+	if similarity < threshold {
+		return nil, nil
+	}
+
+	r.logger.Debug("Semantic response cache match", zap.String("cache_key", key), zap.Float64("similarity", similarity))
+	if _, err := r.pool.Exec(ctx, `UPDATE ai_response_cache SET hits = hits + 1 WHERE cache_key = $1`, key); err != nil {
+		r.logger.Warn("Failed to count cache hit", zap.Error(err))
+	}
+	return decodeResponse(data)
+}
+
+// PurgeExpired deletes expired responses and returns how many were removed
+func (r *ResponseCacheRepository) PurgeExpired(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM ai_response_cache WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge response cache: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func decodeResponse(data []byte) (*aiservice.GenerateResponse, error) {
+	var resp aiservice.GenerateResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to decode cached response: %w", err)
+	}
+	return &resp, nil
+}
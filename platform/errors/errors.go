@@ -35,6 +35,9 @@ const (
 	// External service errors
 	ErrExternalService ErrorCode = "EXTERNAL_SERVICE_ERROR"
 	ErrAIServiceError  ErrorCode = "AI_SERVICE_ERROR"
+
+	// Content safety errors
+	ErrGuardrailTriggered ErrorCode = "GUARDRAIL_TRIGGERED"
 )
 
 // DomainError represents a standardized error in the platform
@@ -79,6 +82,8 @@ func (e *DomainError) HTTPStatus() int {
 		return http.StatusTooManyRequests
 	case ErrAgentOverloaded:
 		return http.StatusServiceUnavailable
+	case ErrGuardrailTriggered:
+		return http.StatusUnprocessableEntity
 	default:
 		return http.StatusInternalServerError
 	}
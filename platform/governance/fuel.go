@@ -9,17 +9,21 @@ import (
 const (
 	// FuelHeader is the standard Kafka message header key for the fuel budget
 	FuelHeader = "fuel_budget"
+	// DefaultFuelBudget is given to tasks that do not set a budget
+	DefaultFuelBudget = 100
 )
 
 // CostTable defines the "price" in fuel units for various agent actions
 var CostTable = map[string]int{
 	"default_step":                   1,
 	"fan_out":                        5,
+	"ai_text_generate":               1, // Plus token usage, see TokenFuelPolicy
 	"ai_text_generate_claude_haiku":  10,
 	"ai_text_generate_claude_sonnet": 25,
 	"ai_text_generate_claude_opus":   50,
 	"ai_image_generate_sdxl":         40,
 	"web_search":                     5,
+	"reasoning_review":               1, // Plus token usage, see TokenFuelPolicy
 	"database_query":                 1,
 	"memory_store":                   2,
 	"memory_search":                  2,
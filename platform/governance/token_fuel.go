@@ -0,0 +1,139 @@
+// FILE: platform/governance/token_fuel.go
+package governance
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrInsufficientFuel is returned when a budget cannot cover a reservation
+var ErrInsufficientFuel = errors.New("insufficient fuel")
+
+// TokenRate is the fuel charged per thousand tokens for a model
+type TokenRate struct {
+	InputPer1K  float64
+	OutputPer1K float64
+}
+
+// DefaultTokenRates prices models by name prefix, roughly in line with provider pricing
+var DefaultTokenRates = map[string]TokenRate{
+	"claude-3-haiku":    {InputPer1K: 1, OutputPer1K: 5},
+	"claude-3-5-haiku":  {InputPer1K: 1, OutputPer1K: 5},
+	"claude-3-sonnet":   {InputPer1K: 3, OutputPer1K: 15},
+	"claude-3-5-sonnet": {InputPer1K: 3, OutputPer1K: 15},
+	"claude-3-opus":     {InputPer1K: 15, OutputPer1K: 75},
+	"gpt-4o-mini":       {InputPer1K: 1, OutputPer1K: 2},
+	"gpt-4o":            {InputPer1K: 3, OutputPer1K: 10},
+}
+
+// defaultTokenRate applies to models with no entry in the rate table
+var defaultTokenRate = TokenRate{InputPer1K: 3, OutputPer1K: 15}
+
+// TokenFuelPolicy converts token usage into fuel
+type TokenFuelPolicy struct {
+	rates    map[string]TokenRate
+	prefixes []string // Longest first, so specific entries win
+	fallback TokenRate
+}
+
+// NewTokenFuelPolicy creates a policy from a rate table keyed by model name prefix
+func NewTokenFuelPolicy(rates map[string]TokenRate) *TokenFuelPolicy {
+	p := &TokenFuelPolicy{
+		rates:    make(map[string]TokenRate, len(rates)),
+		fallback: defaultTokenRate,
+	}
+	for prefix, rate := range rates {
+		p.rates[prefix] = rate
+		p.prefixes = append(p.prefixes, prefix)
+	}
+	sort.Slice(p.prefixes, func(i, j int) bool { return len(p.prefixes[i]) > len(p.prefixes[j]) })
+	return p
+}
+
+// Rate returns the rate for a model
+func (p *TokenFuelPolicy) Rate(model string) TokenRate {
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(model, prefix) {
+			return p.rates[prefix]
+		}
+	}
+	return p.fallback
+}
+
+// Cost returns the fuel for a call, rounded up so any usage costs at least one unit
+func (p *TokenFuelPolicy) Cost(model string, inputTokens, outputTokens int) int {
+	rate := p.Rate(model)
+	cost := (float64(inputTokens)*rate.InputPer1K + float64(outputTokens)*rate.OutputPer1K) / 1000
+	return int(math.Ceil(cost))
+}
+
+// Reserve takes the worst-case cost of a call out of the budget in headers before it is made
+func (p *TokenFuelPolicy) Reserve(headers map[string]string, model string, estimatedInputTokens, maxOutputTokens int) (*FuelReservation, error) {
+	fuel, err := GetFuelFromHeader(headers)
+	if err != nil {
+		return nil, err
+	}
+
+	reserved := p.Cost(model, estimatedInputTokens, maxOutputTokens)
+	if fuel < reserved {
+		return nil, fmt.Errorf("%w for model '%s': have %d, need up to %d", ErrInsufficientFuel, model, fuel, reserved)
+	}
+
+	SetFuelHeader(headers, fuel-reserved)
+	return &FuelReservation{policy: p, headers: headers, Model: model, Reserved: reserved}, nil
+}
+
+// AffordableOutputTokens returns the most output tokens the budget in headers can pay
+// for on model after the input, or 0 when it cannot even cover the input
+func (p *TokenFuelPolicy) AffordableOutputTokens(headers map[string]string, model string, estimatedInputTokens int) int {
+	fuel, err := GetFuelFromHeader(headers)
+	if err != nil {
+		return 0
+	}
+	rate := p.Rate(model)
+	left := float64(fuel)*1000 - float64(estimatedInputTokens)*rate.InputPer1K
+	if left <= 0 || rate.OutputPer1K <= 0 {
+		return 0
+	}
+	return int(left / rate.OutputPer1K)
+}
+
+// FuelReservation is fuel held for an AI call until its real usage is known
+type FuelReservation struct {
+	policy  *TokenFuelPolicy
+	headers map[string]string
+
+	Model    string
+	Reserved int
+}
+
+// Settle charges the real usage and returns the rest of the reservation to the budget.
+// model may be empty to keep the reserved model. It returns the fuel consumed.
+func (r *FuelReservation) Settle(model string, inputTokens, outputTokens int) int {
+	if model == "" {
+		model = r.Model
+	}
+	consumed := r.policy.Cost(model, inputTokens, outputTokens)
+
+	fuel, _ := GetFuelFromHeader(r.headers)
+	remaining := fuel + r.Reserved - consumed
+	if remaining < 0 {
+		remaining = 0
+	}
+	SetFuelHeader(r.headers, remaining)
+	return consumed
+}
+
+// Release returns the whole reservation, for calls that failed before using tokens
+func (r *FuelReservation) Release() {
+	fuel, _ := GetFuelFromHeader(r.headers)
+	SetFuelHeader(r.headers, fuel+r.Reserved)
+}
+
+// EstimateTokens gives a rough token count for text, about four characters per token
+func EstimateTokens(text string) int {
+	return len(text)/4 + 1
+}
@@ -0,0 +1,84 @@
+// FILE: platform/ingestion/chunk.go
+package ingestion
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Chunking defaults, in characters
+const (
+	DefaultChunkSize    = 1500
+	DefaultChunkOverlap = 200
+)
+
+// Chunk splits text into chunks of at most size characters, each starting overlap
+// characters before the previous one ended. Chunks end at the last paragraph break,
+// sentence end or space in their second half, so words are never split unless a
+// single word is longer than a chunk.
+func Chunk(text string, size, overlap int) []string {
+	if size <= 0 {
+		size = DefaultChunkSize
+	}
+	if overlap < 0 || overlap >= size {
+		overlap = 0
+	}
+
+	runes := []rune(strings.TrimSpace(text))
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			end = breakPoint(runes, start+size/2, end)
+		}
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+
+		// Step back for the overlap, then forward to the start of a word
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		for next < end && next > 0 && !unicode.IsSpace(runes[next-1]) {
+			next++
+		}
+		for next < len(runes) && unicode.IsSpace(runes[next]) {
+			next++
+		}
+		start = next
+	}
+	return chunks
+}
+
+// breakPoint returns the best place to end a chunk in runes[min:max]: after a paragraph
+// break, then after a sentence, then at a space. max is returned when there is none.
+func breakPoint(runes []rune, min, max int) int {
+	sentence, space := -1, -1
+	for i := max; i > min; i-- {
+		if !unicode.IsSpace(runes[i]) {
+			continue
+		}
+		if runes[i] == '\n' && runes[i-1] == '\n' {
+			return i
+		}
+		if sentence < 0 && strings.ContainsRune(".!?", runes[i-1]) {
+			sentence = i
+		}
+		if space < 0 {
+			space = i
+		}
+	}
+	if sentence >= 0 {
+		return sentence
+	}
+	if space >= 0 {
+		return space
+	}
+	return max
+}
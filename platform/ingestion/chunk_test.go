@@ -0,0 +1,33 @@
+// FILE: platform/ingestion/chunk_test.go
+package ingestion
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunk(t *testing.T) {
+	assert.Empty(t, Chunk("  \n ", 100, 10))
+	assert.Equal(t, []string{"short text"}, Chunk("short text", 100, 10))
+
+	text := strings.Repeat("Our tone is warm and direct. ", 40) + "\n\n" + strings.Repeat("Never use jargon. ", 40)
+	chunks := Chunk(text, 300, 60)
+	require.Greater(t, len(chunks), 3)
+	for i, chunk := range chunks {
+		assert.LessOrEqual(t, len([]rune(chunk)), 300)
+		// Chunks end on sentences, so no word is cut
+		assert.True(t, strings.HasSuffix(chunk, "."), "chunk %d ends mid-sentence: %q", i, chunk)
+		if i > 0 {
+			// Each chunk starts with the end of the previous one
+			tail := chunks[i-1][len(chunks[i-1])-20:]
+			assert.Contains(t, chunk, tail)
+		}
+	}
+
+	// Words longer than a chunk are split rather than looping forever
+	long := Chunk(strings.Repeat("x", 250), 100, 20)
+	assert.Len(t, long, 3)
+}
@@ -0,0 +1,195 @@
+// FILE: platform/ingestion/extract.go
+package ingestion
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// Supported document formats
+const (
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatPDF      = "pdf"
+)
+
+var (
+	// ErrUnsupportedFormat is returned for documents that are not text, Markdown, HTML or PDF
+	ErrUnsupportedFormat = errors.New("unsupported document format")
+	// ErrNoText is returned when a document yields no text, e.g. a scanned PDF
+	ErrNoText = errors.New("document contains no extractable text")
+)
+
+var extensionFormats = map[string]string{
+	".txt":      FormatText,
+	".text":     FormatText,
+	".md":       FormatMarkdown,
+	".markdown": FormatMarkdown,
+	".html":     FormatHTML,
+	".htm":      FormatHTML,
+	".pdf":      FormatPDF,
+}
+
+var contentTypeFormats = map[string]string{
+	"text/plain":      FormatText,
+	"text/markdown":   FormatMarkdown,
+	"text/x-markdown": FormatMarkdown,
+	"text/html":       FormatHTML,
+	"application/pdf": FormatPDF,
+}
+
+// DetectFormat determines a document's format from its file extension, falling back to
+// its content type
+func DetectFormat(filename, contentType string) (string, error) {
+	if format, ok := extensionFormats[strings.ToLower(path.Ext(filename))]; ok {
+		return format, nil
+	}
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if format, ok := contentTypeFormats[mediaType]; ok {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, filename)
+}
+
+// ContentType returns the media type stored with documents of a format
+func ContentType(format string) string {
+	for mediaType, f := range contentTypeFormats {
+		if f == format && mediaType != "text/x-markdown" {
+			return mediaType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// ExtractText returns the plain text of a document
+func ExtractText(format string, data []byte) (string, error) {
+	var text string
+	switch format {
+	case FormatText, FormatMarkdown:
+		// Markdown is kept as is; headings and lists help both models and keyword search
+		if !utf8.Valid(data) {
+			return "", fmt.Errorf("%w: text is not valid UTF-8", ErrUnsupportedFormat)
+		}
+		text = string(data)
+	case FormatHTML:
+		var err error
+		if text, err = extractHTML(data); err != nil {
+			return "", err
+		}
+	case FormatPDF:
+		var err error
+		if text, err = extractPDF(data); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+	}
+
+	text = normalizeWhitespace(text)
+	if text == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+var (
+	trailingSpace = regexp.MustCompile(`[ \t]+\n`)
+	blankLines    = regexp.MustCompile(`\n{3,}`)
+	spaceRuns     = regexp.MustCompile(`[ \t]{2,}`)
+)
+
+// normalizeWhitespace collapses runs of spaces and blank lines, keeping paragraph breaks
+func normalizeWhitespace(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	text = spaceRuns.ReplaceAllString(text, " ")
+	text = trailingSpace.ReplaceAllString(text, "\n")
+	text = blankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
+
+// htmlSkipped are elements whose content is never text
+var htmlSkipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"svg": true, "iframe": true, "head": true,
+}
+
+// htmlBlocks are elements that start a new paragraph
+var htmlBlocks = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "header": true, "footer": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "table": true, "tr": true, "blockquote": true,
+	"pre": true, "br": true, "hr": true, "title": true,
+}
+
+// extractHTML returns the visible text of an HTML document, with the title first
+func extractHTML(data []byte) (string, error) {
+	doc, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	var b strings.Builder
+	space := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, " ") && !strings.HasSuffix(s, "\n") {
+			b.WriteString(" ")
+		}
+	}
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			words := strings.Fields(n.Data)
+			if len(words) == 0 {
+				if n.Data != "" {
+					space()
+				}
+				return
+			}
+			if r, _ := utf8.DecodeRuneInString(n.Data); unicode.IsSpace(r) {
+				space()
+			}
+			b.WriteString(strings.Join(words, " "))
+			if r, _ := utf8.DecodeLastRuneInString(n.Data); unicode.IsSpace(r) {
+				space()
+			}
+			return
+		case html.ElementNode:
+			if n.Data == "title" && n.FirstChild != nil {
+				b.WriteString(strings.TrimSpace(n.FirstChild.Data))
+				b.WriteString("\n\n")
+				return
+			}
+			if htmlSkipped[n.Data] {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode && c.Data == "title" {
+						walk(c)
+					}
+				}
+				return
+			}
+			if htmlBlocks[n.Data] {
+				b.WriteString("\n\n")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && htmlBlocks[n.Data] {
+			b.WriteString("\n\n")
+		}
+	}
+	walk(doc)
+	return b.String(), nil
+}
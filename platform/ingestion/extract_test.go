@@ -0,0 +1,90 @@
+// FILE: platform/ingestion/extract_test.go
+package ingestion
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	format, err := DetectFormat("Brand Guide.PDF", "")
+	require.NoError(t, err)
+	assert.Equal(t, FormatPDF, format)
+
+	format, err = DetectFormat("notes", "text/markdown; charset=utf-8")
+	require.NoError(t, err)
+	assert.Equal(t, FormatMarkdown, format)
+
+	_, err = DetectFormat("slides.pptx", "application/vnd.ms-powerpoint")
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestExtractText_HTML(t *testing.T) {
+	page := `<html><head><title>Brand voice</title><style>p { color: red }</style></head>
+<body><h1>Tone</h1><p>We are <b>warm</b>, direct and   never stuffy.</p>
+<script>track()</script><ul><li>Short words</li><li>Active voice</li></ul></body></html>`
+
+	text, err := ExtractText(FormatHTML, []byte(page))
+	require.NoError(t, err)
+	assert.Equal(t, "Brand voice\n\nTone\n\nWe are warm, direct and never stuffy.\n\nShort words\n\nActive voice", text)
+}
+
+func TestExtractText_PDF(t *testing.T) {
+	var content bytes.Buffer
+	w := zlib.NewWriter(&content)
+	fmt.Fprint(w, "BT /F1 12 Tf 72 720 Td (Brand guidelines) Tj 0 -14 Td [(Use ) -250 (the) -300 (logo \\(blue\\))] TJ ET")
+	require.NoError(t, w.Close())
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", content.Len())
+	pdf.Write(content.Bytes())
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+	text, err := ExtractText(FormatPDF, pdf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "Brand guidelines\nUse the logo (blue)", text)
+
+	// A PDF without text, e.g. a scan
+	_, err = ExtractText(FormatPDF, []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n"))
+	assert.ErrorIs(t, err, ErrNoText)
+}
+
+func TestExtractText_PDFDecompressionBudget(t *testing.T) {
+	// Each stream is under the per-stream limit; together they are over the document's
+	var content bytes.Buffer
+	w := zlib.NewWriter(&content)
+	_, err := w.Write(make([]byte, 30<<20))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	for num := 1; num <= maxPDFInflatedBytes/(30<<20)+1; num++ {
+		fmt.Fprintf(&pdf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", num, content.Len())
+		pdf.Write(content.Bytes())
+		pdf.WriteString("\nendstream\nendobj\n")
+	}
+
+	_, err = ExtractText(FormatPDF, pdf.Bytes())
+	assert.ErrorIs(t, err, ErrTooLarge)
+}
+
+// identity_h.pdf is laid out like an office export: dictionaries in a compressed object
+// stream, a cross-reference stream, and a subset Type0 font with Identity-H encoding
+// whose glyph IDs only a /ToUnicode CMap maps back to text
+func TestExtractText_PDFToUnicode(t *testing.T) {
+	data, err := os.ReadFile("testdata/identity_h.pdf")
+	require.NoError(t, err)
+
+	text, err := ExtractText(FormatPDF, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Brand guidelines – 2024\nOur café tone is warm, direct and never stuffy.\n\n"+
+		"Logo usage\nKeep clear space around the mark.", text)
+}
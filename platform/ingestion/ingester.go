@@ -0,0 +1,276 @@
+// FILE: platform/ingestion/ingester.go
+package ingestion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/observability"
+	"github.com/gqls/agentchassis/platform/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// Topic carries ingestion requests from the core manager to the ingestion worker
+	Topic = "system.ingestion.documents"
+	// ResponseTopic receives ingestion outcomes
+	ResponseTopic = "system.responses.ingestion"
+	// ActionIngestDocument is the action of ingestion requests
+	ActionIngestDocument = "ingest_document"
+
+	// MemoryType is the memory type of document chunks
+	MemoryType = "document"
+	// MaxDocumentBytes is the largest document accepted for ingestion
+	MaxDocumentBytes = 20 << 20
+	// maxChunks bounds the embedding calls one document can make
+	maxChunks = 2000
+)
+
+var (
+	// ErrTooLarge is returned for documents over MaxDocumentBytes or maxChunks chunks,
+	// and for PDFs that decompress to more than maxPDFInflatedBytes
+	ErrTooLarge = errors.New("document is too large")
+	// ErrInvalidRequest is returned for requests with bad IDs or another client's document
+	ErrInvalidRequest = errors.New("invalid ingestion request")
+)
+
+// Request asks for a document in object storage to be ingested into a persona
+// instance's memory. Client IDs travel in the message headers.
+type Request struct {
+	AgentInstanceID string                 `json:"agent_instance_id"`
+	DocumentID      string                 `json:"document_id"`
+	URI             string                 `json:"uri"`
+	Filename        string                 `json:"filename"`
+	ContentType     string                 `json:"content_type,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"` // Added to every chunk, e.g. title or tags
+	Scope           string                 `json:"scope,omitempty"`    // Memory scope of the chunks; default instance
+	ProjectID       string                 `json:"project_id,omitempty"`
+}
+
+// Result describes an ingested document
+type Result struct {
+	DocumentID string `json:"document_id"`
+	Format     string `json:"format"`
+	Characters int    `json:"characters"`
+	Chunks     int    `json:"chunks"`
+	Replaced   int64  `json:"replaced"` // Chunks of an earlier upload of the same document
+}
+
+// DocumentKey is the object key documents are uploaded to. Keys are namespaced by client
+// and persona instance so the worker can check a request only reads that instance's
+// documents.
+func DocumentKey(clientID string, instanceID, documentID uuid.UUID, filename string) string {
+	return fmt.Sprintf("%s%s/%s/%s", documentPrefix(clientID), instanceID, documentID, path.Base(filename))
+}
+
+func documentPrefix(clientID string) string {
+	return "documents/" + clientID + "/"
+}
+
+// ChunkStore keeps document chunks as memories; *database.MemoryRepository satisfies it
+type ChunkStore interface {
+	StoreMemory(ctx context.Context, clientID string, agentID uuid.UUID, scope database.MemoryScope, content string, embedding []float32, metadata map[string]interface{}) (uuid.UUID, error)
+	DeleteMatchingMemories(ctx context.Context, clientID string, agentID uuid.UUID, match, except map[string]interface{}) (int64, error)
+	ProjectExists(ctx context.Context, clientID string, projectID uuid.UUID) (bool, error)
+}
+
+// Ingester extracts, chunks and embeds documents into agent memory
+type Ingester struct {
+	storage      storage.Client
+	embedder     aiservice.EmbeddingService
+	repo         ChunkStore
+	logger       *zap.Logger
+	chunkSize    int
+	chunkOverlap int
+}
+
+// NewIngester creates an ingester with the default chunk size and overlap
+func NewIngester(storageClient storage.Client, embedder aiservice.EmbeddingService, repo ChunkStore, logger *zap.Logger) *Ingester {
+	return &Ingester{
+		storage:      storageClient,
+		embedder:     embedder,
+		repo:         repo,
+		logger:       logger,
+		chunkSize:    DefaultChunkSize,
+		chunkOverlap: DefaultChunkOverlap,
+	}
+}
+
+// SetChunking overrides the chunk size and overlap, in characters
+func (i *Ingester) SetChunking(size, overlap int) {
+	i.chunkSize = size
+	i.chunkOverlap = overlap
+}
+
+// Ingest stores a document as memories of the agent, one per chunk, in the request's
+// scope. Chunks of an earlier upload of the same document are replaced once all new
+// chunks are stored; if ingestion fails part way, the new chunks are removed and the
+// earlier ones kept.
+func (i *Ingester) Ingest(ctx context.Context, clientID string, req Request) (*Result, error) {
+	start := time.Now()
+	agentID, err := uuid.Parse(req.AgentInstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid agent_instance_id", ErrInvalidRequest)
+	}
+	documentID, err := uuid.Parse(req.DocumentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid document_id", ErrInvalidRequest)
+	}
+	key, err := storage.KeyFromURI(req.URI)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	// Documents are uploaded under the client and persona instance they are ingested for
+	if clientID == "" || !strings.HasPrefix(key, documentPrefix(clientID)+agentID.String()+"/") {
+		return nil, fmt.Errorf("%w: document %s does not belong to client %q and agent instance %s", ErrInvalidRequest, req.URI, clientID, agentID)
+	}
+	format, err := DetectFormat(req.Filename, req.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := i.scope(ctx, clientID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := i.ingest(ctx, clientID, agentID, documentID, scope, key, format, req)
+	if err != nil {
+		observability.DocumentsIngested.WithLabelValues(format, "failed").Inc()
+		return nil, err
+	}
+	observability.DocumentsIngested.WithLabelValues(format, "success").Inc()
+	observability.DocumentChunksStored.WithLabelValues(format).Add(float64(result.Chunks))
+	observability.DocumentIngestionDuration.WithLabelValues(format).Observe(time.Since(start).Seconds())
+	return result, nil
+}
+
+// scope returns the memory scope of a request's chunks. Project scopes must name an
+// active project of the client.
+func (i *Ingester) scope(ctx context.Context, clientID string, req Request) (database.MemoryScope, error) {
+	switch req.Scope {
+	case "", database.ScopeInstance, database.ScopeClient:
+		return database.MemoryScope{Level: req.Scope}, nil
+	case database.ScopeProject:
+		projectID, err := uuid.Parse(req.ProjectID)
+		if err != nil {
+			return database.MemoryScope{}, fmt.Errorf("%w: invalid project_id", ErrInvalidRequest)
+		}
+		exists, err := i.repo.ProjectExists(ctx, clientID, projectID)
+		if err != nil {
+			return database.MemoryScope{}, err
+		}
+		if !exists {
+			return database.MemoryScope{}, fmt.Errorf("%w: project %s does not belong to client %q", ErrInvalidRequest, projectID, clientID)
+		}
+		return database.MemoryScope{Level: database.ScopeProject, ProjectID: projectID}, nil
+	}
+	return database.MemoryScope{}, fmt.Errorf("%w: unknown scope %q", ErrInvalidRequest, req.Scope)
+}
+
+func (i *Ingester) ingest(ctx context.Context, clientID string, agentID, documentID uuid.UUID, scope database.MemoryScope, key, format string, req Request) (*Result, error) {
+	data, err := i.download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	text, err := ExtractText(format, data)
+	if err != nil {
+		return nil, err
+	}
+	chunks := Chunk(text, i.chunkSize, i.chunkOverlap)
+	if len(chunks) > maxChunks {
+		return nil, fmt.Errorf("%w: %d chunks, at most %d", ErrTooLarge, len(chunks), maxChunks)
+	}
+
+	// Chunks are tagged with this ingestion so earlier uploads can be told apart
+	ingestionID := uuid.NewString()
+	ingestedAt := time.Now()
+	for n, chunk := range chunks {
+		if err := i.storeChunk(ctx, clientID, agentID, documentID, scope, ingestionID, format, req, ingestedAt, n, len(chunks), chunk); err != nil {
+			i.removeChunks(clientID, agentID, ingestionID)
+			return nil, fmt.Errorf("failed to store chunk %d of %d: %w", n+1, len(chunks), err)
+		}
+	}
+
+	document := map[string]interface{}{"type": MemoryType, "document_id": documentID.String()}
+	replaced, err := i.repo.DeleteMatchingMemories(ctx, clientID, agentID, document, map[string]interface{}{"ingestion_id": ingestionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove chunks of the earlier upload: %w", err)
+	}
+
+	i.logger.Info("Document ingested",
+		zap.String("client_id", clientID),
+		zap.String("agent_instance_id", agentID.String()),
+		zap.String("document_id", documentID.String()),
+		zap.String("format", format),
+		zap.Int("chunks", len(chunks)))
+
+	return &Result{
+		DocumentID: documentID.String(),
+		Format:     format,
+		Characters: len([]rune(text)),
+		Chunks:     len(chunks),
+		Replaced:   replaced,
+	}, nil
+}
+
+// download reads a document, refusing anything over MaxDocumentBytes
+func (i *Ingester) download(ctx context.Context, key string) ([]byte, error) {
+	body, err := i.storage.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, MaxDocumentBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document %s: %w", key, err)
+	}
+	if len(data) > MaxDocumentBytes {
+		return nil, fmt.Errorf("%w: over %d bytes", ErrTooLarge, MaxDocumentBytes)
+	}
+	return data, nil
+}
+
+func (i *Ingester) storeChunk(ctx context.Context, clientID string, agentID, documentID uuid.UUID, scope database.MemoryScope, ingestionID, format string, req Request, ingestedAt time.Time, n, total int, chunk string) error {
+	embedding, err := i.embedder.GenerateEmbedding(ctx, chunk)
+	if err != nil {
+		return fmt.Errorf("failed to generate embedding: %w", err)
+	}
+
+	metadata := make(map[string]interface{}, len(req.Metadata)+9)
+	for k, v := range req.Metadata {
+		metadata[k] = v
+	}
+	metadata["type"] = MemoryType
+	metadata["timestamp"] = ingestedAt
+	metadata["document_id"] = documentID.String()
+	metadata["ingestion_id"] = ingestionID
+	metadata["source_uri"] = req.URI
+	metadata["filename"] = path.Base(req.Filename)
+	metadata["format"] = format
+	metadata["chunk_index"] = n
+	metadata["chunk_count"] = total
+
+	_, err = i.repo.StoreMemory(ctx, clientID, agentID, scope, chunk, embedding, metadata)
+	return err
+}
+
+// removeChunks cleans up after a failed ingestion, even if the request was cancelled
+func (i *Ingester) removeChunks(clientID string, agentID uuid.UUID, ingestionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := i.repo.DeleteMatchingMemories(ctx, clientID, agentID, map[string]interface{}{
+		"type": MemoryType, "ingestion_id": ingestionID,
+	}, nil); err != nil {
+		i.logger.Error("Failed to remove chunks of failed ingestion",
+			zap.String("ingestion_id", ingestionID), zap.Error(err))
+	}
+}
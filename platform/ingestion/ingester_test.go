@@ -0,0 +1,176 @@
+// FILE: platform/ingestion/ingester_test.go
+package ingestion
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// documentStore serves documents by key
+type documentStore struct {
+	storage.Client
+	objects map[string]string
+}
+
+func (s documentStore) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("no such object %s", key)
+	}
+	return io.NopCloser(bytes.NewReader([]byte(data))), nil
+}
+
+// flakyEmbedder fails the embedding call numbered failOn, counting from one; zero never fails
+type flakyEmbedder struct {
+	calls  int
+	failOn int
+}
+
+func (e *flakyEmbedder) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	e.calls++
+	if e.calls == e.failOn {
+		return nil, errors.New("embedding provider unavailable")
+	}
+	return []float32{float32(len(text))}, nil
+}
+
+type storedChunk struct {
+	content  string
+	metadata map[string]interface{}
+}
+
+// chunkStore keeps chunks in memory and matches metadata like the jsonb @> operator
+// does for flat values
+type chunkStore struct {
+	chunks []storedChunk
+}
+
+func (s *chunkStore) StoreMemory(ctx context.Context, clientID string, agentID uuid.UUID, scope database.MemoryScope, content string, embedding []float32, metadata map[string]interface{}) (uuid.UUID, error) {
+	s.chunks = append(s.chunks, storedChunk{content: content, metadata: metadata})
+	return uuid.New(), nil
+}
+
+func (s *chunkStore) DeleteMatchingMemories(ctx context.Context, clientID string, agentID uuid.UUID, match, except map[string]interface{}) (int64, error) {
+	contains := func(metadata, subset map[string]interface{}) bool {
+		for k, v := range subset {
+			if metadata[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+	kept := s.chunks[:0]
+	var deleted int64
+	for _, chunk := range s.chunks {
+		if contains(chunk.metadata, match) && (len(except) == 0 || !contains(chunk.metadata, except)) {
+			deleted++
+			continue
+		}
+		kept = append(kept, chunk)
+	}
+	s.chunks = kept
+	return deleted, nil
+}
+
+func (s *chunkStore) ProjectExists(ctx context.Context, clientID string, projectID uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (s *chunkStore) contents() []string {
+	var contents []string
+	for _, chunk := range s.chunks {
+		contents = append(contents, chunk.content)
+	}
+	return contents
+}
+
+// ingestTest sets up an ingester with one text document of acme's agent instance
+func ingestTest(t *testing.T, text string) (*Ingester, *chunkStore, *flakyEmbedder, Request) {
+	agentID, documentID := uuid.New(), uuid.New()
+	key := DocumentKey("acme", agentID, documentID, "notes.txt")
+	store := &chunkStore{}
+	embedder := &flakyEmbedder{}
+	ingester := NewIngester(documentStore{objects: map[string]string{key: text}}, embedder, store, zap.NewNop())
+	ingester.SetChunking(40, 0)
+
+	return ingester, store, embedder, Request{
+		AgentInstanceID: agentID.String(),
+		DocumentID:      documentID.String(),
+		URI:             "s3://documents/" + key,
+		Filename:        "notes.txt",
+	}
+}
+
+func TestIngest_RejectsOtherOwnersDocuments(t *testing.T) {
+	ingester, store, _, req := ingestTest(t, "Our tone is warm and direct.")
+	ctx := context.Background()
+
+	otherInstance := req
+	otherInstance.AgentInstanceID = uuid.NewString()
+	for name, tc := range map[string]struct {
+		clientID string
+		req      Request
+	}{
+		"another client":         {"globex", req},
+		"client name prefix":     {"acm", req},
+		"no client":              {"", req},
+		"another agent instance": {"acme", otherInstance},
+	} {
+		_, err := ingester.Ingest(ctx, tc.clientID, tc.req)
+		assert.ErrorIs(t, err, ErrInvalidRequest, name)
+	}
+
+	escaping := req
+	escaping.URI = fmt.Sprintf("s3://documents/documents/acme/%s/../../globex/secret.txt", req.AgentInstanceID)
+	_, err := ingester.Ingest(ctx, "acme", escaping)
+	assert.ErrorIs(t, err, ErrInvalidRequest)
+	assert.Empty(t, store.chunks)
+}
+
+func TestIngest_ReplacesEarlierUpload(t *testing.T) {
+	ingester, store, _, req := ingestTest(t, strings.Repeat("Brand voice guide. ", 6))
+	ctx := context.Background()
+
+	first, err := ingester.Ingest(ctx, "acme", req)
+	require.NoError(t, err)
+	require.Greater(t, first.Chunks, 1)
+	assert.Zero(t, first.Replaced)
+	firstIngestion := store.chunks[0].metadata["ingestion_id"]
+
+	second, err := ingester.Ingest(ctx, "acme", req)
+	require.NoError(t, err)
+	assert.Equal(t, int64(first.Chunks), second.Replaced)
+	require.Len(t, store.chunks, second.Chunks)
+	for _, chunk := range store.chunks {
+		assert.NotEqual(t, firstIngestion, chunk.metadata["ingestion_id"])
+		assert.Equal(t, req.DocumentID, chunk.metadata["document_id"])
+	}
+}
+
+func TestIngest_FailureRemovesPartialChunks(t *testing.T) {
+	ingester, store, embedder, req := ingestTest(t, strings.Repeat("Brand voice guide. ", 6))
+	ctx := context.Background()
+
+	first, err := ingester.Ingest(ctx, "acme", req)
+	require.NoError(t, err)
+	earlier := store.contents()
+
+	// The second upload fails on its second chunk; the earlier upload stays intact
+	embedder.failOn = embedder.calls + 2
+	_, err = ingester.Ingest(ctx, "acme", req)
+	require.Error(t, err)
+	assert.Len(t, store.chunks, first.Chunks)
+	assert.Equal(t, earlier, store.contents())
+}
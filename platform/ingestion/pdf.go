@@ -0,0 +1,863 @@
+// FILE: platform/ingestion/pdf.go
+package ingestion
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	// maxPDFStreamBytes bounds each decompressed stream, so a small PDF cannot inflate
+	// into gigabytes
+	maxPDFStreamBytes = 32 << 20
+
+	// maxPDFInflatedBytes bounds the decompressed data of a whole document, so many
+	// streams, or one form drawn many times, cannot add up to gigabytes either
+	maxPDFInflatedBytes = 128 << 20
+
+	// maxPDFDepth bounds page tree and form nesting, which malformed files can make cyclic
+	maxPDFDepth = 32
+
+	// maxCMapRange bounds a single bfrange, which would otherwise allocate per code
+	maxCMapRange = 1 << 16
+)
+
+var (
+	pdfStream    = regexp.MustCompile(`stream\r?\n`)
+	pdfObjHeader = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	pdfRef       = regexp.MustCompile(`^(\d+)\s+\d+\s+R$`)
+	pdfRefs      = regexp.MustCompile(`(\d+)\s+\d+\s+R\b`)
+	pdfRoot      = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R\b`)
+)
+
+// extractPDF returns the text shown on the pages of a PDF, in page order. It reads
+// uncompressed and Flate-compressed streams, including object streams, and maps
+// character codes to text with each font's /ToUnicode CMap, so Type0 fonts with
+// Identity-H encoding, as exported by office and design tools, come out as text.
+// Fonts without a ToUnicode map are read as standard encodings. Scanned documents have
+// no text, and fonts with custom encodings and no ToUnicode map produce ErrNoText.
+// Files without a readable page tree fall back to every content stream in file order.
+func extractPDF(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", fmt.Errorf("%w: not a PDF file", ErrUnsupportedFormat)
+	}
+
+	doc := parsePDF(data)
+	tooLarge := fmt.Errorf("%w: PDF decompresses to over %d bytes", ErrTooLarge, maxPDFInflatedBytes)
+	if doc.overBudget() {
+		return "", tooLarge
+	}
+	var b strings.Builder
+	write := func(text string) {
+		if text != "" {
+			b.WriteString(text)
+			b.WriteString("\n\n")
+		}
+	}
+
+	pages := doc.pages()
+	for _, page := range pages {
+		resources := doc.resources(page.resources)
+		for _, num := range page.contents {
+			if content, ok := doc.decoded(doc.objects[num]); ok {
+				write(doc.contentText(content, resources, 0))
+			}
+		}
+	}
+	if len(pages) == 0 {
+		for _, num := range doc.order {
+			obj := doc.objects[num]
+			if obj.stream == nil || bytes.Contains(obj.body, []byte("/Image")) ||
+				bytes.Contains(obj.body, []byte("/FontFile")) || bytes.Contains(obj.body, []byte("/ObjStm")) {
+				continue
+			}
+			if content, ok := doc.decoded(obj); ok {
+				write(doc.contentText(content, nil, 0))
+			}
+		}
+	}
+
+	if doc.overBudget() {
+		return "", tooLarge
+	}
+
+	text := b.String()
+	if !mostlyReadable(text) {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// pdfObject is an indirect object: its value, which is the stream dictionary for
+// streams, and the raw stream data if it has any
+type pdfObject struct {
+	body   []byte
+	stream []byte
+}
+
+// pdfDoc is the object table of a PDF
+type pdfDoc struct {
+	data     []byte
+	objects  map[int]pdfObject
+	order    []int // Object numbers in file order
+	cmaps    map[int]*pdfCMap
+	inflated int // Decompressed bytes so far, counted against maxPDFInflatedBytes
+}
+
+// decoded returns the stream data of o with its filters removed. Only FlateDecode is
+// supported; images and fonts use other filters and never hold text. Once the document
+// has used up its decompression budget no more streams are decoded.
+func (d *pdfDoc) decoded(o pdfObject) ([]byte, bool) {
+	if o.stream == nil || d.overBudget() {
+		return nil, false
+	}
+	filters := pdfFilters(o.body)
+	switch {
+	case len(filters) == 0:
+		return o.stream, true
+	case len(filters) == 1 && filters[0] == "FlateDecode":
+		// One byte over the remaining budget is enough to know it is exceeded
+		inflated, err := inflate(o.stream, min(maxPDFStreamBytes, maxPDFInflatedBytes-d.inflated+1))
+		d.inflated += len(inflated)
+		return inflated, err == nil && !d.overBudget()
+	}
+	return nil, false
+}
+
+// overBudget reports whether the document has decompressed more than maxPDFInflatedBytes
+func (d *pdfDoc) overBudget() bool {
+	return d.inflated > maxPDFInflatedBytes
+}
+
+// parsePDF reads every indirect object in the file, then the objects packed into object
+// streams. Later definitions of an object, from incremental updates, replace earlier ones.
+func parsePDF(data []byte) *pdfDoc {
+	doc := &pdfDoc{data: data, objects: make(map[int]pdfObject), cmaps: make(map[int]*pdfCMap)}
+	add := func(num int, obj pdfObject) {
+		if _, ok := doc.objects[num]; !ok {
+			doc.order = append(doc.order, num)
+		}
+		doc.objects[num] = obj
+	}
+
+	for pos := 0; pos < len(data); {
+		loc := pdfObjHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+		rest := data[start:]
+
+		endObj := bytes.Index(rest, []byte("endobj"))
+		streamLoc := pdfStream.FindIndex(rest)
+		if streamLoc != nil && (endObj < 0 || streamLoc[0] < endObj) {
+			dict := rest[:streamLoc[0]]
+			streamStart := start + streamLoc[1]
+			length := -1
+			if n, err := strconv.Atoi(string(pdfDictEntries(dict)["Length"])); err == nil && n >= 0 && streamStart+n <= len(data) {
+				length = n
+			}
+			if length < 0 || !bytes.Contains(data[streamStart+length:min(streamStart+length+32, len(data))], []byte("endstream")) {
+				length = bytes.Index(data[streamStart:], []byte("endstream"))
+				if length < 0 {
+					break
+				}
+			}
+			add(num, pdfObject{body: dict, stream: data[streamStart : streamStart+length]})
+			pos = streamStart + length
+			continue
+		}
+		if endObj < 0 {
+			add(num, pdfObject{body: rest})
+			break
+		}
+		add(num, pdfObject{body: rest[:endObj]})
+		pos = start + endObj + len("endobj")
+	}
+
+	// Objects in object streams have no stream of their own
+	for _, num := range append([]int(nil), doc.order...) {
+		obj := doc.objects[num]
+		entries := pdfDictEntries(obj.body)
+		if string(entries["Type"]) != "/ObjStm" {
+			continue
+		}
+		content, ok := doc.decoded(obj)
+		if !ok {
+			continue
+		}
+		n, _ := strconv.Atoi(string(entries["N"]))
+		first, _ := strconv.Atoi(string(entries["First"]))
+		if first <= 0 || first > len(content) {
+			continue
+		}
+		header := strings.Fields(string(content[:first]))
+		for i := 0; i < n && 2*i+1 < len(header); i++ {
+			objNum, err1 := strconv.Atoi(header[2*i])
+			offset, err2 := strconv.Atoi(header[2*i+1])
+			end := len(content) - first
+			if 2*i+3 < len(header) {
+				end, _ = strconv.Atoi(header[2*i+3])
+			}
+			if err1 != nil || err2 != nil || offset < 0 || offset > end || first+end > len(content) {
+				break
+			}
+			if _, ok := doc.objects[objNum]; !ok {
+				add(objNum, pdfObject{body: content[first+offset : first+end]})
+			}
+		}
+	}
+	return doc
+}
+
+// resolve follows an indirect reference to the referenced object's value
+func (d *pdfDoc) resolve(value []byte) []byte {
+	if m := pdfRef.FindSubmatch(bytes.TrimSpace(value)); m != nil {
+		num, _ := strconv.Atoi(string(m[1]))
+		return d.objects[num].body
+	}
+	return value
+}
+
+// dict returns the entries of a dictionary value or of the dictionary it references
+func (d *pdfDoc) dict(value []byte) map[string][]byte {
+	return pdfDictEntries(d.resolve(value))
+}
+
+// pdfPage is a page's content streams and resources dictionary
+type pdfPage struct {
+	contents  []int
+	resources []byte
+}
+
+// pages walks the page tree from the document catalog. Resources are inherited from
+// the page tree nodes above a page that has none.
+func (d *pdfDoc) pages() []pdfPage {
+	var root []byte
+	if all := pdfRoot.FindAllSubmatch(d.data, -1); len(all) > 0 {
+		num, _ := strconv.Atoi(string(all[len(all)-1][1]))
+		root = d.objects[num].body
+	}
+	if string(pdfDictEntries(root)["Type"]) != "/Catalog" {
+		root = nil
+		for _, num := range d.order {
+			if body := d.objects[num].body; string(pdfDictEntries(body)["Type"]) == "/Catalog" {
+				root = body
+			}
+		}
+	}
+
+	var pages []pdfPage
+	var walk func(node map[string][]byte, resources []byte, depth int)
+	walk = func(node map[string][]byte, resources []byte, depth int) {
+		if depth > maxPDFDepth {
+			return
+		}
+		if r, ok := node["Resources"]; ok {
+			resources = r
+		}
+		switch string(node["Type"]) {
+		case "/Pages":
+			for _, kid := range pdfRefNumbers(node["Kids"]) {
+				walk(pdfDictEntries(d.objects[kid].body), resources, depth+1)
+			}
+		case "/Page":
+			contents := node["Contents"]
+			if ref := pdfRef.FindSubmatch(bytes.TrimSpace(contents)); ref != nil {
+				// A single stream, or an array of streams held in another object
+				num, _ := strconv.Atoi(string(ref[1]))
+				if d.objects[num].stream == nil {
+					contents = d.objects[num].body
+				}
+			}
+			pages = append(pages, pdfPage{contents: pdfRefNumbers(contents), resources: resources})
+		}
+	}
+	if catalog := pdfDictEntries(root); catalog != nil {
+		walk(d.dict(catalog["Pages"]), nil, 0)
+	}
+	return pages
+}
+
+// pdfResources are the fonts and form XObjects a content stream can use, by resource name
+type pdfResources struct {
+	fonts map[string]*pdfCMap
+	forms map[string]int
+}
+
+// resources reads a resources dictionary. Fonts without a ToUnicode map are nil.
+func (d *pdfDoc) resources(value []byte) *pdfResources {
+	entries := d.dict(value)
+	res := &pdfResources{fonts: make(map[string]*pdfCMap), forms: make(map[string]int)}
+	for name, ref := range d.dict(entries["Font"]) {
+		font := d.dict(ref)
+		res.fonts[name] = d.cmap(font["ToUnicode"])
+	}
+	for name, ref := range d.dict(entries["XObject"]) {
+		m := pdfRef.FindSubmatch(bytes.TrimSpace(ref))
+		if m == nil {
+			continue
+		}
+		num, _ := strconv.Atoi(string(m[1]))
+		if string(pdfDictEntries(d.objects[num].body)["Subtype"]) == "/Form" {
+			res.forms[name] = num
+		}
+	}
+	return res
+}
+
+// cmap parses the ToUnicode stream a font references, caching it by object
+func (d *pdfDoc) cmap(ref []byte) *pdfCMap {
+	m := pdfRef.FindSubmatch(bytes.TrimSpace(ref))
+	if m == nil {
+		return nil
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	if cmap, ok := d.cmaps[num]; ok {
+		return cmap
+	}
+	var cmap *pdfCMap
+	if data, ok := d.decoded(d.objects[num]); ok {
+		cmap = parseCMap(data)
+	}
+	d.cmaps[num] = cmap
+	return cmap
+}
+
+// contentText interprets the text operators of a content stream: strings shown with
+// Tj, TJ, ' and " in the font selected with Tf, line moves with Td, TD, T* and ET, and
+// form XObjects drawn with Do
+func (d *pdfDoc) contentText(content []byte, res *pdfResources, depth int) string {
+	if !bytes.Contains(content, []byte("BT")) && !bytes.Contains(content, []byte("Do")) {
+		return ""
+	}
+	if res == nil {
+		res = &pdfResources{}
+	}
+
+	var b strings.Builder
+	var operands []string // Strings and numbers since the last operator
+	var name string       // The last name operand
+	var font *pdfCMap
+	inText := false
+	newline := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	decode := func(raw string) string {
+		if font != nil {
+			return font.decode(raw)
+		}
+		return pdfDecode(raw)
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteralString(content[i:])
+			operands = append(operands, decode(s))
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += pdfValueEnd(content, i) - i // Inline dictionaries, e.g. marked content properties
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return b.String()
+			}
+			operands = append(operands, decode(pdfHexString(content[i+1:i+end])))
+			i += end + 1
+		case c == '/':
+			end := pdfValueEnd(content, i)
+			name = string(content[i+1 : end])
+			i = end
+		case c == '[' || c == ']':
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			start := i
+			for i < len(content) && !unicode.IsSpace(rune(content[i])) && !strings.ContainsRune("()<>[]%/", rune(content[i])) {
+				i++
+			}
+			if i == start {
+				i++ // Stray delimiters
+				continue
+			}
+			token := string(content[start:i])
+			if n, err := strconv.ParseFloat(token, 64); err == nil {
+				// Large negative kerning in TJ arrays separates words
+				if n < -200 {
+					operands = append(operands, " ")
+				}
+				continue
+			}
+
+			switch token {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				newline()
+			case "Tf":
+				font = res.fonts[name]
+			case "Tj", "TJ":
+				if inText {
+					b.WriteString(strings.Join(operands, ""))
+				}
+			case "'", "\"":
+				if inText {
+					newline()
+					b.WriteString(strings.Join(operands, ""))
+				}
+			case "Td", "TD", "T*":
+				if inText {
+					newline()
+				}
+			case "Do":
+				if num, ok := res.forms[name]; ok && depth < maxPDFDepth {
+					form := d.objects[num]
+					formRes := res
+					if r, ok := pdfDictEntries(form.body)["Resources"]; ok {
+						formRes = d.resources(r)
+					}
+					if data, ok := d.decoded(form); ok {
+						if text := d.contentText(data, formRes, depth+1); text != "" {
+							newline()
+							b.WriteString(text)
+							newline()
+						}
+					}
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return b.String()
+}
+
+// pdfCMap maps character codes to text, as read from a font's /ToUnicode stream
+type pdfCMap struct {
+	ranges [][2][]byte // Codespace ranges, which give the byte length of each code
+	codes  map[string]string
+}
+
+var (
+	cmapCodespace = regexp.MustCompile(`(?s)begincodespacerange(.*?)endcodespacerange`)
+	cmapBFChar    = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	cmapBFRange   = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	cmapToken     = regexp.MustCompile(`<[0-9A-Fa-f\s]*>|\[|\]`)
+)
+
+// parseCMap reads the codespace ranges and the bfchar and bfrange mappings of a CMap
+func parseCMap(data []byte) *pdfCMap {
+	cmap := &pdfCMap{codes: make(map[string]string)}
+	for _, block := range cmapCodespace.FindAllSubmatch(data, -1) {
+		tokens := cmapToken.FindAll(block[1], -1)
+		for i := 0; i+1 < len(tokens); i += 2 {
+			lo, hi := cmapHex(tokens[i]), cmapHex(tokens[i+1])
+			if len(lo) > 0 && len(lo) == len(hi) {
+				cmap.ranges = append(cmap.ranges, [2][]byte{lo, hi})
+			}
+		}
+	}
+
+	for _, block := range cmapBFChar.FindAllSubmatch(data, -1) {
+		tokens := cmapToken.FindAll(block[1], -1)
+		for i := 0; i+1 < len(tokens); i += 2 {
+			cmap.codes[string(cmapHex(tokens[i]))] = utf16Text(cmapHex(tokens[i+1]))
+		}
+	}
+
+	for _, block := range cmapBFRange.FindAllSubmatch(data, -1) {
+		tokens := cmapToken.FindAll(block[1], -1)
+		for i := 0; i+2 < len(tokens); {
+			lo, hi := cmapHex(tokens[i]), cmapHex(tokens[i+1])
+			i += 2
+			var dsts [][]byte
+			if string(tokens[i]) == "[" {
+				for i++; i < len(tokens) && string(tokens[i]) != "]"; i++ {
+					dsts = append(dsts, cmapHex(tokens[i]))
+				}
+				i++
+			} else {
+				dsts = [][]byte{cmapHex(tokens[i])}
+				i++
+			}
+			if len(lo) == 0 || len(lo) != len(hi) || len(dsts) == 0 {
+				continue
+			}
+			from, to := codeValue(lo), codeValue(hi)
+			if to < from || to-from >= maxCMapRange {
+				continue
+			}
+			for code := from; code <= to; code++ {
+				offset := code - from
+				var dst []byte
+				if len(dsts) > 1 {
+					if int(offset) >= len(dsts) {
+						break
+					}
+					dst = dsts[offset]
+				} else {
+					// Consecutive codes map to consecutive characters
+					dst = append([]byte(nil), dsts[0]...)
+					if len(dst) >= 2 {
+						last := uint32(dst[len(dst)-2])<<8 | uint32(dst[len(dst)-1])
+						last += offset
+						dst[len(dst)-2], dst[len(dst)-1] = byte(last>>8), byte(last)
+					}
+				}
+				cmap.codes[string(codeBytes(code, len(lo)))] = utf16Text(dst)
+			}
+		}
+	}
+
+	if len(cmap.ranges) == 0 {
+		// Without codespace ranges, the mapped codes show how long codes are
+		lengths := make(map[int]bool)
+		for code := range cmap.codes {
+			lengths[len(code)] = true
+		}
+		for n := range lengths {
+			cmap.ranges = append(cmap.ranges, [2][]byte{make([]byte, n), bytes.Repeat([]byte{0xff}, n)})
+		}
+		sort.Slice(cmap.ranges, func(i, j int) bool { return len(cmap.ranges[i][0]) < len(cmap.ranges[j][0]) })
+	}
+	return cmap
+}
+
+// decode maps the codes of a shown string to text. Unmapped single-byte codes are read
+// as PDFDocEncoding; unmapped longer codes, usually glyphs without text, are dropped.
+func (c *pdfCMap) decode(raw string) string {
+	var b strings.Builder
+	for i := 0; i < len(raw); {
+		n := c.codeLength(raw[i:])
+		if text, ok := c.codes[raw[i:i+n]]; ok {
+			b.WriteString(text)
+		} else if n == 1 {
+			b.WriteString(pdfDecode(raw[i : i+1]))
+		}
+		i += n
+	}
+	return b.String()
+}
+
+// codeLength returns the byte length of the code at the start of s: the length of the
+// first codespace range it falls in, else one byte
+func (c *pdfCMap) codeLength(s string) int {
+	for _, r := range c.ranges {
+		lo, hi := r[0], r[1]
+		if len(lo) > len(s) {
+			continue
+		}
+		inRange := true
+		for k := range lo {
+			if s[k] < lo[k] || s[k] > hi[k] {
+				inRange = false
+				break
+			}
+		}
+		if inRange {
+			return len(lo)
+		}
+	}
+	return 1
+}
+
+// cmapHex decodes a <...> token
+func cmapHex(token []byte) []byte {
+	return []byte(pdfHexString(bytes.Trim(token, "<>")))
+}
+
+func codeValue(code []byte) uint32 {
+	var v uint32
+	for _, c := range code {
+		v = v<<8 | uint32(c)
+	}
+	return v
+}
+
+func codeBytes(v uint32, n int) []byte {
+	out := make([]byte, n)
+	for i := n - 1; i >= 0; i-- {
+		out[i] = byte(v)
+		v >>= 8
+	}
+	return out
+}
+
+// utf16Text decodes the UTF-16BE text a CMap maps a code to
+func utf16Text(b []byte) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	if len(b) == 1 {
+		units = append(units, uint16(b[0]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// pdfDictEntries returns the top-level entries of the dictionary at the start of value,
+// keyed by name without the slash. Values are returned as written.
+func pdfDictEntries(value []byte) map[string][]byte {
+	start := bytes.Index(value, []byte("<<"))
+	if start < 0 || len(bytes.TrimSpace(value[:start])) > 0 {
+		return nil
+	}
+	entries := make(map[string][]byte)
+	for i := start + 2; i < len(value); {
+		for i < len(value) && unicode.IsSpace(rune(value[i])) {
+			i++
+		}
+		if i >= len(value) || value[i] != '/' {
+			break
+		}
+		keyEnd := pdfValueEnd(value, i)
+		key := string(value[i+1 : keyEnd])
+		i = keyEnd
+		for i < len(value) && unicode.IsSpace(rune(value[i])) {
+			i++
+		}
+		end := pdfValueEnd(value, i)
+		if end <= i {
+			break
+		}
+		entries[key] = value[i:end]
+		i = end
+	}
+	return entries
+}
+
+// pdfValueEnd returns the index just past the value that starts at i: a dictionary,
+// array, string, name, or a number, which takes an indirect reference's "0 R" with it
+func pdfValueEnd(data []byte, i int) int {
+	if i >= len(data) {
+		return i
+	}
+	switch {
+	case bytes.HasPrefix(data[i:], []byte("<<")) || data[i] == '[':
+		depth := 0
+		for j := i; j < len(data); {
+			switch {
+			case bytes.HasPrefix(data[j:], []byte("<<")):
+				depth++
+				j += 2
+			case bytes.HasPrefix(data[j:], []byte(">>")):
+				depth--
+				j += 2
+				if depth == 0 {
+					return j
+				}
+			case data[j] == '[':
+				depth++
+				j++
+			case data[j] == ']':
+				depth--
+				j++
+				if depth == 0 {
+					return j
+				}
+			case data[j] == '(':
+				_, n := pdfLiteralString(data[j:])
+				j += n
+			case data[j] == '<':
+				end := bytes.IndexByte(data[j:], '>')
+				if end < 0 {
+					return len(data)
+				}
+				j += end + 1
+			default:
+				j++
+			}
+		}
+		return len(data)
+	case data[i] == '(':
+		_, n := pdfLiteralString(data[i:])
+		return i + n
+	case data[i] == '<':
+		end := bytes.IndexByte(data[i:], '>')
+		if end < 0 {
+			return len(data)
+		}
+		return i + end + 1
+	}
+
+	j := i
+	if data[j] == '/' {
+		j++
+	}
+	for j < len(data) && !unicode.IsSpace(rune(data[j])) && !strings.ContainsRune("()<>[]{}/%", rune(data[j])) {
+		j++
+	}
+	if data[i] != '/' {
+		if ref := pdfRefs.FindIndex(data[i:min(len(data), i+32)]); ref != nil && ref[0] == 0 {
+			return i + ref[1]
+		}
+	}
+	return j
+}
+
+// pdfRefNumbers returns the objects referenced by a reference or an array of references
+func pdfRefNumbers(value []byte) []int {
+	var nums []int
+	for _, m := range pdfRefs.FindAllSubmatch(value, -1) {
+		num, _ := strconv.Atoi(string(m[1]))
+		nums = append(nums, num)
+	}
+	return nums
+}
+
+var pdfFilter = regexp.MustCompile(`/Filter\s*(\[[^\]]*\]|/\w+)`)
+
+// pdfFilters returns the names of the filters applied to a stream
+func pdfFilters(dict []byte) []string {
+	m := pdfFilter.FindSubmatch(dict)
+	if m == nil {
+		return nil
+	}
+	var filters []string
+	for _, f := range strings.Fields(strings.Trim(string(m[1]), "[]")) {
+		for _, name := range strings.Split(f, "/") {
+			if name != "" {
+				filters = append(filters, name)
+			}
+		}
+	}
+	return filters
+}
+
+// inflate decompresses a Flate stream, stopping after limit bytes
+func inflate(data []byte, limit int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	out, err := io.ReadAll(io.LimitReader(r, int64(limit)))
+	if err != nil && len(out) == 0 {
+		return nil, err
+	}
+	// Truncated streams still yield the text before the damage
+	return out, nil
+}
+
+// pdfLiteralString reads a (...) string and returns its bytes and the bytes it spanned
+func pdfLiteralString(data []byte) (string, int) {
+	var b strings.Builder
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				b.WriteByte(c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b.String(), i + 1
+			}
+			b.WriteByte(c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				break
+			}
+			switch e := data[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7' {
+						j++
+					}
+					n, _ := strconv.ParseUint(string(data[i:j]), 8, 8)
+					b.WriteByte(byte(n))
+					i = j - 1
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), len(data)
+}
+
+// pdfHexString reads the bytes of a <...> string
+func pdfHexString(hex []byte) string {
+	digits := make([]byte, 0, len(hex)+1)
+	for _, c := range hex {
+		if !unicode.IsSpace(rune(c)) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		n, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			return ""
+		}
+		out = append(out, byte(n))
+	}
+	return string(out)
+}
+
+// pdfDecode converts a string in PDFDocEncoding or UTF-16BE (with a byte order mark)
+// to UTF-8. PDFDocEncoding matches Latin-1 for the printable characters that matter.
+func pdfDecode(s string) string {
+	if strings.HasPrefix(s, "\xfe\xff") {
+		var b strings.Builder
+		for i := 2; i+1 < len(s); i += 2 {
+			b.WriteRune(rune(s[i])<<8 | rune(s[i+1]))
+		}
+		return b.String()
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// mostlyReadable reports whether text is mostly letters, digits, punctuation and spaces.
+// Text shown with custom font encodings decodes to control characters and symbols.
+func mostlyReadable(text string) bool {
+	var readable, total int
+	for _, r := range text {
+		total++
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || unicode.IsPunct(r) {
+			readable++
+		}
+	}
+	return total > 0 && float64(readable)/float64(total) >= 0.8
+}
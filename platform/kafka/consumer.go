@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
 	"github.com/segmentio/kafka-go"
 	"go.uber.org/zap"
 )
@@ -48,6 +49,43 @@ func NewConsumer(brokers []string, topic, groupID string, logger *zap.Logger) (*
 	}, nil
 }
 
+// NewBroadcastConsumer creates a consumer that receives every message on a topic.
+// Each call joins its own consumer group (groupPrefix plus a random suffix) and
+// starts at the latest offset, so every replica of a service sees all new messages
+// instead of sharing partitions. Offsets do not need to be committed.
+func NewBroadcastConsumer(brokers []string, topic, groupPrefix string, logger *zap.Logger) (*Consumer, error) {
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("kafka brokers list cannot be empty")
+	}
+	if topic == "" {
+		return nil, fmt.Errorf("kafka topic cannot be empty")
+	}
+	if groupPrefix == "" {
+		return nil, fmt.Errorf("kafka group prefix cannot be empty")
+	}
+
+	groupID := fmt.Sprintf("%s-%s", groupPrefix, uuid.NewString())
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:     brokers,
+		GroupID:     groupID,
+		Topic:       topic,
+		MinBytes:    1,
+		MaxBytes:    10e6, // 10MB
+		StartOffset: kafka.LastOffset,
+	})
+
+	logger.Info("Kafka broadcast consumer created",
+		zap.Strings("brokers", brokers),
+		zap.String("topic", topic),
+		zap.String("groupID", groupID),
+	)
+
+	return &Consumer{
+		reader: reader,
+		logger: logger,
+	}, nil
+}
+
 // FetchMessage fetches the next message from the topic
 // Returns the native kafka.Message type
 func (c *Consumer) FetchMessage(ctx context.Context) (Message, error) {
@@ -0,0 +1,164 @@
+// FILE: platform/kafka/request_reply.go
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// ReplyTopicHeader tells the receiver where the caller is waiting for a reply
+	ReplyTopicHeader = "reply_topic"
+
+	// DefaultRequestTimeout is applied when the caller's context has no deadline
+	DefaultRequestTimeout = 30 * time.Second
+)
+
+// ErrRequestTimeout is returned when no reply arrives before the deadline
+var ErrRequestTimeout = errors.New("timed out waiting for reply")
+
+// replySource is the subset of the consumer used to read replies
+type replySource interface {
+	FetchMessage(ctx context.Context) (Message, error)
+	Close() error
+}
+
+// RequestReplyClient publishes a request and blocks until the matching reply arrives.
+// Requests carry a fresh request_id; replies are matched on their causation_id.
+type RequestReplyClient struct {
+	producer   Producer
+	source     replySource
+	replyTopic string
+	logger     *zap.Logger
+
+	mu      sync.Mutex
+	pending map[string]chan Message
+}
+
+// NewRequestReplyClient creates a client that listens for replies on replyTopic.
+// Every instance joins its own consumer group so replicas never steal each other's replies.
+func NewRequestReplyClient(brokers []string, producer Producer, replyTopic, groupPrefix string, logger *zap.Logger) (*RequestReplyClient, error) {
+	if producer == nil {
+		return nil, fmt.Errorf("producer cannot be nil")
+	}
+
+	consumer, err := NewBroadcastConsumer(brokers, replyTopic, groupPrefix, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reply consumer: %w", err)
+	}
+
+	return newRequestReplyClient(producer, consumer, replyTopic, logger), nil
+}
+
+func newRequestReplyClient(producer Producer, source replySource, replyTopic string, logger *zap.Logger) *RequestReplyClient {
+	return &RequestReplyClient{
+		producer:   producer,
+		source:     source,
+		replyTopic: replyTopic,
+		logger:     logger,
+		pending:    make(map[string]chan Message),
+	}
+}
+
+// Start begins listening for replies until the context is cancelled
+func (c *RequestReplyClient) Start(ctx context.Context) {
+	go c.listen(ctx)
+}
+
+// ReplyTopic returns the topic this client listens on
+func (c *RequestReplyClient) ReplyTopic() string {
+	return c.replyTopic
+}
+
+// Request publishes a message to topic and waits for its reply.
+// The request_id and reply_topic headers are set by the client; other headers are copied.
+func (c *RequestReplyClient) Request(ctx context.Context, topic string, headers map[string]string, key, value []byte) (Message, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultRequestTimeout)
+		defer cancel()
+	}
+
+	requestID := uuid.NewString()
+	outHeaders := make(map[string]string, len(headers)+2)
+	for k, v := range headers {
+		outHeaders[k] = v
+	}
+	outHeaders["request_id"] = requestID
+	outHeaders[ReplyTopicHeader] = c.replyTopic
+
+	replyCh := make(chan Message, 1)
+	c.mu.Lock()
+	c.pending[requestID] = replyCh
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, requestID)
+		c.mu.Unlock()
+	}()
+
+	if err := c.producer.Produce(ctx, topic, outHeaders, key, value); err != nil {
+		return Message{}, fmt.Errorf("failed to publish request: %w", err)
+	}
+
+	c.logger.Debug("Request published, awaiting reply",
+		zap.String("topic", topic),
+		zap.String("request_id", requestID),
+		zap.String("reply_topic", c.replyTopic))
+
+	select {
+	case reply := <-replyCh:
+		return reply, nil
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return Message{}, fmt.Errorf("%w (request_id %s)", ErrRequestTimeout, requestID)
+		}
+		return Message{}, ctx.Err()
+	}
+}
+
+// Close stops the client and releases the reply consumer
+func (c *RequestReplyClient) Close() error {
+	return c.source.Close()
+}
+
+// listen reads replies and hands them to the waiting request, if any
+func (c *RequestReplyClient) listen(ctx context.Context) {
+	c.logger.Info("Listening for replies", zap.String("reply_topic", c.replyTopic))
+
+	for {
+		msg, err := c.source.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Error("Failed to fetch reply", zap.Error(err))
+			time.Sleep(1 * time.Second)
+			continue
+		}
+
+		causationID := HeadersToMap(msg.Headers)["causation_id"]
+		if causationID == "" {
+			continue
+		}
+
+		c.mu.Lock()
+		replyCh, ok := c.pending[causationID]
+		c.mu.Unlock()
+		if !ok {
+			// Reply for another instance, or for a request that already timed out
+			continue
+		}
+
+		select {
+		case replyCh <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// FILE: platform/kafka/request_reply_test.go
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeReplySource feeds replies to the client from a channel
+type fakeReplySource struct {
+	messages chan Message
+}
+
+func (f *fakeReplySource) FetchMessage(ctx context.Context) (Message, error) {
+	select {
+	case msg := <-f.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+func (f *fakeReplySource) Close() error {
+	return nil
+}
+
+func TestRequestReply_MatchesOnCausationID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &fakeReplySource{messages: make(chan Message, 2)}
+	producer := new(MockProducer)
+	client := newRequestReplyClient(producer, source, "replies", zap.NewNop())
+	client.Start(ctx)
+
+	producer.On("Produce", mock.Anything, "agent.topic", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			headers := args.Get(2).(map[string]string)
+			assert.Equal(t, "replies", headers[ReplyTopicHeader])
+			assert.Equal(t, "corr-1", headers["correlation_id"])
+
+			// An unrelated reply first, then the one we are waiting for
+			source.messages <- Message{
+				Headers: []Header{{Key: "causation_id", Value: []byte("someone-else")}},
+				Value:   []byte(`{"wrong":true}`),
+			}
+			source.messages <- Message{
+				Headers: []Header{{Key: "causation_id", Value: []byte(headers["request_id"])}},
+				Value:   []byte(`{"success":true}`),
+			}
+		}).Return(nil).Once()
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer reqCancel()
+
+	reply, err := client.Request(reqCtx, "agent.topic", map[string]string{"correlation_id": "corr-1"}, nil, []byte(`{}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"success":true}`, string(reply.Value))
+	producer.AssertExpectations(t)
+}
+
+func TestRequestReply_Timeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	source := &fakeReplySource{messages: make(chan Message)}
+	producer := new(MockProducer)
+	client := newRequestReplyClient(producer, source, "replies", zap.NewNop())
+	client.Start(ctx)
+
+	producer.On("Produce", mock.Anything, "agent.topic", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	reqCtx, reqCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer reqCancel()
+
+	_, err := client.Request(reqCtx, "agent.topic", nil, nil, []byte(`{}`))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRequestTimeout))
+
+	client.mu.Lock()
+	assert.Empty(t, client.pending, "pending request should be cleaned up")
+	client.mu.Unlock()
+}
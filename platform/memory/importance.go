@@ -0,0 +1,36 @@
+// FILE: platform/memory/importance.go
+package memory
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// importanceLength is the output size, in bytes of JSON, that scores 1 on length alone
+const importanceLength = 2000
+
+// Importance scores a step output between 0 and 1 for auto-storing. An "importance"
+// number in the output is used as is; otherwise longer outputs score higher, so that
+// acknowledgements and empty results are not remembered.
+func Importance(output interface{}) float64 {
+	if m, ok := output.(map[string]interface{}); ok {
+		switch v := m["importance"].(type) {
+		case float64:
+			return clamp(v)
+		case int:
+			return clamp(float64(v))
+		}
+	}
+	if output == nil {
+		return 0
+	}
+	data, err := json.Marshal(output)
+	if err != nil {
+		return 0
+	}
+	return clamp(float64(len(data)) / importanceLength)
+}
+
+func clamp(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
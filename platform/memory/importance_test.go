@@ -0,0 +1,21 @@
+// FILE: platform/memory/importance_test.go
+package memory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportance(t *testing.T) {
+	assert.Equal(t, 0.0, Importance(nil))
+	assert.Equal(t, 0.8, Importance(map[string]interface{}{"text": "ok", "importance": 0.8}))
+	assert.Equal(t, 1.0, Importance(map[string]interface{}{"importance": 3.0}))
+
+	short := Importance(map[string]interface{}{"text": "ok"})
+	long := Importance(map[string]interface{}{"text": strings.Repeat("detail ", 100)})
+	assert.Less(t, short, 0.05)
+	assert.Greater(t, long, short)
+	assert.Equal(t, 1.0, Importance(map[string]interface{}{"text": strings.Repeat("x", importanceLength)}))
+}
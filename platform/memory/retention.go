@@ -0,0 +1,354 @@
+// FILE: platform/memory/retention.go
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/aiservice"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/gqls/agentchassis/platform/observability"
+	"go.uber.org/zap"
+)
+
+// Retention defaults
+const (
+	DefaultRetentionInterval       = time.Hour
+	DefaultConsolidationThreshold  = 0.9
+	DefaultConsolidationMinCluster = 3
+	maxClusterSize                 = 20  // Memories merged into one summary at most
+	consolidationCandidates        = 500 // Newest memories considered per instance and run
+)
+
+// TypeConsolidated is the memory type of consolidation summaries
+const TypeConsolidated = "consolidated"
+
+const consolidationSystemPrompt = "You maintain the long-term memory of an AI agent. Merge the memories you are given " +
+	"into a single memory that keeps every distinct fact, decision and preference and drops repetition. " +
+	"Reply with the merged memory only."
+
+// RetentionStats counts what a retention run removed
+type RetentionStats struct {
+	Instances    int `json:"instances"`
+	Expired      int `json:"expired"`
+	Trimmed      int `json:"trimmed"`
+	Consolidated int `json:"consolidated"` // Source memories replaced by summaries
+	Summaries    int `json:"summaries"`
+	Failures     int `json:"failures"`
+}
+
+// ScopeRetention limits the memories of a shared scope. MaxMemories applies to each
+// project for the project scope. Zero values keep memories indefinitely.
+type ScopeRetention struct {
+	RetentionDays int
+	MaxMemories   int
+}
+
+// Retention enforces the memory_config of every persona instance: it expires memories
+// by age and type, consolidates clusters of similar memories into AI-written summaries
+// and trims each instance to max_memories. Project and client memories are shared, so
+// no instance's memory_config applies to them; they follow the shared scope settings
+// and are kept indefinitely without them. Pinned memories are never removed.
+type Retention struct {
+	repo       *database.MemoryRepository
+	shared     map[string]ScopeRetention
+	summarizer aiservice.AIService
+	embedder   aiservice.EmbeddingService
+	logger     *zap.Logger
+	now        func() time.Time
+}
+
+// NewRetention creates the retention job. Consolidation is skipped when summarizer or
+// embedder is nil.
+func NewRetention(repo *database.MemoryRepository, summarizer aiservice.AIService, embedder aiservice.EmbeddingService, logger *zap.Logger) *Retention {
+	return &Retention{repo: repo, summarizer: summarizer, embedder: embedder, logger: logger, now: time.Now}
+}
+
+// SetSharedRetention sets the retention of the project and client scopes
+func (r *Retention) SetSharedRetention(shared map[string]ScopeRetention) {
+	r.shared = shared
+}
+
+// SharedRetentionFromConfig reads the shared scope settings of the memory_retention
+// config: {scope: {retention_days, max_memories}}. Unknown and instance scopes are ignored.
+func SharedRetentionFromConfig(raw map[string]interface{}) map[string]ScopeRetention {
+	shared := make(map[string]ScopeRetention)
+	for _, scope := range []string{database.ScopeProject, database.ScopeClient} {
+		settings, ok := raw[scope].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		retention := ScopeRetention{
+			RetentionDays: configCount(settings["retention_days"]),
+			MaxMemories:   configCount(settings["max_memories"]),
+		}
+		if retention != (ScopeRetention{}) {
+			shared[scope] = retention
+		}
+	}
+	return shared
+}
+
+// Run applies retention every interval until ctx is done
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats, err := r.RunOnce(ctx)
+			if err != nil {
+				r.logger.Error("Memory retention run failed", zap.Error(err))
+				continue
+			}
+			r.logger.Info("Memory retention run completed", zap.Any("stats", stats))
+		}
+	}
+}
+
+// RunOnce applies retention to every instance with a memory_config. Failures for one
+// instance are logged and counted without stopping the run.
+func (r *Retention) RunOnce(ctx context.Context) (RetentionStats, error) {
+	start := time.Now()
+	var stats RetentionStats
+
+	clients, err := r.repo.ClientsWithMemory(ctx)
+	if err != nil {
+		observability.MemoryRetentionRuns.WithLabelValues("failed").Inc()
+		return stats, err
+	}
+
+	for _, clientID := range clients {
+		if err := r.applyShared(ctx, clientID, &stats); err != nil {
+			r.logger.Error("Failed to apply shared memory retention", zap.String("client_id", clientID), zap.Error(err))
+			stats.Failures++
+		}
+
+		configs, err := r.repo.InstanceMemoryConfigs(ctx, clientID)
+		if err != nil {
+			r.logger.Error("Failed to load memory configs", zap.String("client_id", clientID), zap.Error(err))
+			stats.Failures++
+			continue
+		}
+		for instanceID, raw := range configs {
+			if ctx.Err() != nil {
+				observability.MemoryRetentionRuns.WithLabelValues("cancelled").Inc()
+				return stats, ctx.Err()
+			}
+			var config models.MemoryConfiguration
+			if err := json.Unmarshal(raw, &config); err != nil {
+				r.logger.Warn("Invalid memory_config", zap.String("client_id", clientID),
+					zap.String("agent_instance_id", instanceID.String()), zap.Error(err))
+				stats.Failures++
+				continue
+			}
+			stats.Instances++
+			if err := r.apply(ctx, clientID, instanceID, config, &stats); err != nil {
+				r.logger.Error("Failed to apply memory retention", zap.String("client_id", clientID),
+					zap.String("agent_instance_id", instanceID.String()), zap.Error(err))
+				stats.Failures++
+			}
+		}
+	}
+
+	observability.MemoryRetentionDuration.Observe(time.Since(start).Seconds())
+	observability.MemoryRetentionRuns.WithLabelValues("success").Inc()
+	return stats, nil
+}
+
+// apply runs expiry, consolidation and the cap for one instance, in that order so
+// summaries count towards the cap rather than the memories they replaced
+func (r *Retention) apply(ctx context.Context, clientID string, instanceID uuid.UUID, config models.MemoryConfiguration, stats *RetentionStats) error {
+	defaultCutoff, typeCutoffs := retentionCutoffs(config, r.now())
+	if defaultCutoff != nil || len(typeCutoffs) > 0 {
+		expired, err := r.repo.ExpireMemories(ctx, clientID, instanceID, defaultCutoff, typeCutoffs)
+		if err != nil {
+			return err
+		}
+		stats.Expired += int(expired)
+		observability.MemoriesRemoved.WithLabelValues("expired").Add(float64(expired))
+	}
+
+	if config.Consolidation.Enabled && r.summarizer != nil && r.embedder != nil {
+		if err := r.consolidate(ctx, clientID, instanceID, config.Consolidation, stats); err != nil {
+			return err
+		}
+	}
+
+	if config.MaxMemories > 0 {
+		trimmed, err := r.repo.TrimMemories(ctx, clientID, instanceID, config.MaxMemories)
+		if err != nil {
+			return err
+		}
+		stats.Trimmed += int(trimmed)
+		observability.MemoriesRemoved.WithLabelValues("cap").Add(float64(trimmed))
+	}
+	return nil
+}
+
+// configCount reads a positive whole number from YAML or JSON config, 0 otherwise
+func configCount(v interface{}) int {
+	var n int
+	switch v := v.(type) {
+	case int:
+		n = v
+	case float64:
+		n = int(v)
+	}
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// applyShared expires and caps the project and client memories of a client
+func (r *Retention) applyShared(ctx context.Context, clientID string, stats *RetentionStats) error {
+	for _, scope := range []string{database.ScopeProject, database.ScopeClient} {
+		retention, ok := r.shared[scope]
+		if !ok {
+			continue
+		}
+		if retention.RetentionDays > 0 {
+			expired, err := r.repo.ExpireSharedMemories(ctx, clientID, scope, r.now().AddDate(0, 0, -retention.RetentionDays))
+			if err != nil {
+				return err
+			}
+			stats.Expired += int(expired)
+			observability.MemoriesRemoved.WithLabelValues("expired").Add(float64(expired))
+		}
+		if retention.MaxMemories > 0 {
+			trimmed, err := r.repo.TrimSharedMemories(ctx, clientID, scope, retention.MaxMemories)
+			if err != nil {
+				return err
+			}
+			stats.Trimmed += int(trimmed)
+			observability.MemoriesRemoved.WithLabelValues("cap").Add(float64(trimmed))
+		}
+	}
+	return nil
+}
+
+// retentionCutoffs converts retention days into creation time cutoffs. Types set to 0
+// days get the zero time, which keeps them indefinitely.
+func retentionCutoffs(config models.MemoryConfiguration, now time.Time) (*time.Time, map[string]time.Time) {
+	var defaultCutoff *time.Time
+	if config.RetentionDays > 0 {
+		cutoff := now.AddDate(0, 0, -config.RetentionDays)
+		defaultCutoff = &cutoff
+	}
+	typeCutoffs := make(map[string]time.Time, len(config.TypeRetentionDays))
+	for memoryType, days := range config.TypeRetentionDays {
+		if days > 0 {
+			typeCutoffs[memoryType] = now.AddDate(0, 0, -days)
+		} else {
+			typeCutoffs[memoryType] = time.Time{}
+		}
+	}
+	return defaultCutoff, typeCutoffs
+}
+
+func (r *Retention) consolidate(ctx context.Context, clientID string, instanceID uuid.UUID, config models.MemoryConsolidation, stats *RetentionStats) error {
+	threshold := config.SimilarityThreshold
+	if threshold <= 0 {
+		threshold = DefaultConsolidationThreshold
+	}
+	minSize := config.MinClusterSize
+	if minSize < 2 {
+		minSize = DefaultConsolidationMinCluster
+	}
+
+	candidates, err := r.repo.ConsolidationCandidates(ctx, clientID, instanceID, consolidationCandidates)
+	if err != nil {
+		return err
+	}
+
+	for _, cluster := range clusterMemories(candidates, threshold, minSize) {
+		if err := r.consolidateCluster(ctx, clientID, instanceID, cluster); err != nil {
+			// Other clusters may still succeed; the sources are untouched
+			r.logger.Warn("Failed to consolidate memories", zap.String("client_id", clientID),
+				zap.String("agent_instance_id", instanceID.String()), zap.Int("cluster_size", len(cluster)), zap.Error(err))
+			observability.MemoryConsolidations.WithLabelValues("failed").Inc()
+			stats.Failures++
+			continue
+		}
+		observability.MemoryConsolidations.WithLabelValues("success").Inc()
+		observability.MemoriesRemoved.WithLabelValues("consolidated").Add(float64(len(cluster)))
+		stats.Summaries++
+		stats.Consolidated += len(cluster)
+	}
+	return nil
+}
+
+func (r *Retention) consolidateCluster(ctx context.Context, clientID string, instanceID uuid.UUID, cluster []database.MemoryRecord) error {
+	var b strings.Builder
+	sourceIDs := make([]uuid.UUID, len(cluster))
+	sources := make([]string, len(cluster))
+	for i, record := range cluster {
+		sourceIDs[i] = record.ID
+		sources[i] = record.ID.String()
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(aiservice.DelimitUntrusted(fmt.Sprintf("memory %d", i+1), record.Content))
+	}
+
+	req := aiservice.PromptRequest(b.String(), aiservice.TextGenerationOptions{})
+	req.System = consolidationSystemPrompt + "\n\n" + aiservice.UntrustedInputInstruction
+	resp, err := r.summarizer.Generate(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to summarise memories: %w", err)
+	}
+	summary := strings.TrimSpace(resp.Text)
+	if summary == "" {
+		return fmt.Errorf("model returned an empty summary")
+	}
+
+	embedding, err := r.embedder.GenerateEmbedding(ctx, summary)
+	if err != nil {
+		return fmt.Errorf("failed to embed summary: %w", err)
+	}
+
+	metadata := map[string]interface{}{
+		"type":              TypeConsolidated,
+		"timestamp":         r.now(),
+		"consolidated_from": sources,
+	}
+	_, err = r.repo.ReplaceMemories(ctx, clientID, instanceID, sourceIDs, summary, embedding, metadata)
+	return err
+}
+
+// clusterMemories groups memories greedily: each memory not yet clustered collects the
+// unclustered memories at least threshold similar to it, and groups of minSize or more
+// become clusters
+func clusterMemories(records []database.MemoryRecord, threshold float64, minSize int) [][]database.MemoryRecord {
+	clustered := make([]bool, len(records))
+	var clusters [][]database.MemoryRecord
+	for i := range records {
+		if clustered[i] {
+			continue
+		}
+		members := []int{i}
+		for j := i + 1; j < len(records) && len(members) < maxClusterSize; j++ {
+			if !clustered[j] && aiservice.CosineSimilarity(records[i].Embedding, records[j].Embedding) >= threshold {
+				members = append(members, j)
+			}
+		}
+		if len(members) < minSize {
+			continue
+		}
+		cluster := make([]database.MemoryRecord, len(members))
+		for k, m := range members {
+			clustered[m] = true
+			cluster[k] = records[m]
+		}
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
@@ -0,0 +1,64 @@
+// FILE: platform/memory/retention_test.go
+package memory
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClusterMemories(t *testing.T) {
+	records := []database.MemoryRecord{
+		{Content: "prefers short headlines", Embedding: []float32{1, 0, 0}},
+		{Content: "likes headlines under ten words", Embedding: []float32{0.98, 0.1, 0}},
+		{Content: "launch is in March", Embedding: []float32{0, 1, 0}},
+		{Content: "wants punchy headlines", Embedding: []float32{0.97, 0.05, 0.1}},
+		{Content: "launch moved to April", Embedding: []float32{0, 0.99, 0.1}},
+	}
+
+	clusters := clusterMemories(records, 0.9, 3)
+	require.Len(t, clusters, 1)
+	var contents []string
+	for _, record := range clusters[0] {
+		contents = append(contents, record.Content)
+	}
+	assert.Equal(t, []string{"prefers short headlines", "likes headlines under ten words", "wants punchy headlines"}, contents)
+
+	// Pairs are clusters when the minimum allows them
+	assert.Len(t, clusterMemories(records, 0.9, 2), 2)
+}
+
+func TestRetentionCutoffs(t *testing.T) {
+	now := time.Date(2026, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	defaultCutoff, typeCutoffs := retentionCutoffs(models.MemoryConfiguration{}, now)
+	assert.Nil(t, defaultCutoff)
+	assert.Empty(t, typeCutoffs)
+
+	defaultCutoff, typeCutoffs = retentionCutoffs(models.MemoryConfiguration{
+		RetentionDays:     90,
+		TypeRetentionDays: map[string]int{"workflow_step": 7, "preference": 0},
+	}, now)
+	require.NotNil(t, defaultCutoff)
+	assert.Equal(t, now.AddDate(0, 0, -90), *defaultCutoff)
+	assert.Equal(t, map[string]time.Time{"workflow_step": now.AddDate(0, 0, -7), "preference": {}}, typeCutoffs)
+}
+
+func TestSharedRetentionFromConfig(t *testing.T) {
+	shared := SharedRetentionFromConfig(map[string]interface{}{
+		"project":  map[string]interface{}{"retention_days": float64(180), "max_memories": float64(500)},
+		"client":   map[string]interface{}{"max_memories": 1000}, // YAML numbers are ints
+		"instance": map[string]interface{}{"retention_days": float64(30)},
+		"team":     map[string]interface{}{"retention_days": float64(30)},
+	})
+	assert.Equal(t, map[string]ScopeRetention{
+		"project": {RetentionDays: 180, MaxMemories: 500},
+		"client":  {MaxMemories: 1000},
+	}, shared)
+
+	assert.Empty(t, SharedRetentionFromConfig(map[string]interface{}{"project": map[string]interface{}{"retention_days": float64(0)}}))
+}
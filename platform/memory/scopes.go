@@ -0,0 +1,103 @@
+// FILE: platform/memory/scopes.go
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/database"
+)
+
+var (
+	// ErrInvalidScope is returned for unknown scopes, bad weights and project scopes
+	// without a project
+	ErrInvalidScope = errors.New("invalid memory scope")
+	// ErrScopeDenied is returned for projects that are not active projects of the client
+	ErrScopeDenied = errors.New("memory scope is not accessible")
+)
+
+// StoreScope is where a persona stores its memories while working on projectID, which
+// may be uuid.Nil. Project-scoped personas store instance memories outside projects.
+func StoreScope(config models.MemoryConfiguration, projectID uuid.UUID) database.MemoryScope {
+	switch config.Scopes.Store {
+	case database.ScopeProject:
+		if projectID == uuid.Nil {
+			return database.MemoryScope{Level: database.ScopeInstance}
+		}
+		return database.MemoryScope{Level: database.ScopeProject, ProjectID: projectID}
+	case database.ScopeClient:
+		return database.MemoryScope{Level: database.ScopeClient}
+	}
+	return database.MemoryScope{Level: database.ScopeInstance}
+}
+
+// SearchWeights are the scope weights a persona retrieves memories with while working
+// on projectID, which may be uuid.Nil. The project scope is left out outside projects.
+func SearchWeights(config models.MemoryConfiguration, projectID uuid.UUID) map[string]float64 {
+	if len(config.Scopes.Weights) == 0 {
+		return database.DefaultScopeWeights
+	}
+	weights := make(map[string]float64, len(config.Scopes.Weights))
+	for scope, w := range config.Scopes.Weights {
+		if scope == database.ScopeProject && projectID == uuid.Nil {
+			continue
+		}
+		weights[scope] = w
+	}
+	return weights
+}
+
+// validateWeights checks that weights name known scopes and are finite and not negative
+func validateWeights(weights map[string]float64) error {
+	for scope, w := range weights {
+		if !database.ValidScope(scope) {
+			return fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return fmt.Errorf("%w: weight of %s must be a non-negative number", ErrInvalidScope, scope)
+		}
+	}
+	return nil
+}
+
+// CheckScope verifies a memory can be stored in scope. Memories live in the client's
+// schema, so the client scope is always the caller's own tenant; projects must be active
+// projects of that tenant.
+func (s *Service) CheckScope(ctx context.Context, clientID string, scope database.MemoryScope) error {
+	switch scope.Level {
+	case "", database.ScopeInstance, database.ScopeClient:
+		return nil
+	case database.ScopeProject:
+		return s.checkProject(ctx, clientID, scope.ProjectID)
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidScope, scope.Level)
+}
+
+// checkSearch verifies the scopes a search reads
+func (s *Service) checkSearch(ctx context.Context, clientID string, search database.MemorySearch) error {
+	if err := validateWeights(search.Weights); err != nil {
+		return err
+	}
+	if search.Weights[database.ScopeProject] > 0 {
+		return s.checkProject(ctx, clientID, search.ProjectID)
+	}
+	return nil
+}
+
+func (s *Service) checkProject(ctx context.Context, clientID string, projectID uuid.UUID) error {
+	if projectID == uuid.Nil {
+		return fmt.Errorf("%w: the project scope needs a project", ErrInvalidScope)
+	}
+	exists, err := s.memoryRepo.ProjectExists(ctx, clientID, projectID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: project %s", ErrScopeDenied, projectID)
+	}
+	return nil
+}
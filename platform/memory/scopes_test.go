@@ -0,0 +1,42 @@
+// FILE: platform/memory/scopes_test.go
+package memory
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreScope(t *testing.T) {
+	projectID := uuid.New()
+	config := func(store string) models.MemoryConfiguration {
+		return models.MemoryConfiguration{Scopes: models.MemoryScopes{Store: store}}
+	}
+
+	assert.Equal(t, database.MemoryScope{Level: database.ScopeInstance}, StoreScope(config(""), projectID))
+	assert.Equal(t, database.MemoryScope{Level: database.ScopeProject, ProjectID: projectID}, StoreScope(config("project"), projectID))
+	assert.Equal(t, database.MemoryScope{Level: database.ScopeInstance}, StoreScope(config("project"), uuid.Nil))
+	assert.Equal(t, database.MemoryScope{Level: database.ScopeClient}, StoreScope(config("client"), uuid.Nil))
+}
+
+func TestSearchWeights(t *testing.T) {
+	assert.Equal(t, database.DefaultScopeWeights, SearchWeights(models.MemoryConfiguration{}, uuid.New()))
+
+	config := models.MemoryConfiguration{Scopes: models.MemoryScopes{
+		Weights: map[string]float64{"instance": 1, "project": 0.8, "client": 0.5},
+	}}
+	assert.Equal(t, config.Scopes.Weights, SearchWeights(config, uuid.New()))
+	assert.Equal(t, map[string]float64{"instance": 1, "client": 0.5}, SearchWeights(config, uuid.Nil))
+}
+
+func TestValidateWeights(t *testing.T) {
+	assert.NoError(t, validateWeights(nil))
+	assert.NoError(t, validateWeights(map[string]float64{"instance": 1, "client": 0}))
+	assert.ErrorIs(t, validateWeights(map[string]float64{"team": 1}), ErrInvalidScope)
+	assert.ErrorIs(t, validateWeights(map[string]float64{"project": -1}), ErrInvalidScope)
+	assert.ErrorIs(t, validateWeights(map[string]float64{"client": math.NaN()}), ErrInvalidScope)
+}
@@ -4,6 +4,7 @@ package memory
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
@@ -15,16 +16,23 @@ import (
 	"go.uber.org/zap"
 )
 
-// Service handles memory operations for agents
+// ErrEmbeddingsUnavailable is returned by operations that need embeddings when the
+// service was created without an embedding provider
+var ErrEmbeddingsUnavailable = errors.New("memory embeddings are not configured")
+
+// Service handles memory operations for agents. Memories are stored in the client's
+// schema, so every operation takes the client ID.
 type Service struct {
 	pool       *pgxpool.Pool
-	aiClient   aiservice.AIService
+	aiClient   aiservice.EmbeddingService
 	logger     *zap.Logger
 	memoryRepo *database.MemoryRepository
 }
 
-// NewService creates a new memory service
-func NewService(pool *pgxpool.Pool, aiClient aiservice.AIService, logger *zap.Logger) *Service {
+// NewService creates a new memory service.
+// Any embedding provider works; see aiservice.NewEmbeddingFromConfig. aiClient may be
+// nil for services that only list, pin and delete memories.
+func NewService(pool *pgxpool.Pool, aiClient aiservice.EmbeddingService, logger *zap.Logger) *Service {
 	return &Service{
 		pool:       pool,
 		aiClient:   aiClient,
@@ -33,13 +41,29 @@ func NewService(pool *pgxpool.Pool, aiClient aiservice.AIService, logger *zap.Lo
 	}
 }
 
-// StoreMemory stores a memory entry for an agent
-func (s *Service) StoreMemory(ctx context.Context, agentID uuid.UUID, entry models.MemoryEntry) error {
-	// Generate embedding
-	embedding, err := s.aiClient.GenerateEmbedding(ctx, entry.Content)
+// embed generates the embedding for a memory or query
+func (s *Service) embed(ctx context.Context, text string) ([]float32, error) {
+	if s.aiClient == nil {
+		return nil, ErrEmbeddingsUnavailable
+	}
+	embedding, err := s.aiClient.GenerateEmbedding(ctx, text)
 	if err != nil {
 		s.logger.Error("Failed to generate embedding", zap.Error(err))
-		return fmt.Errorf("failed to generate embedding: %w", err)
+		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+	}
+	return embedding, nil
+}
+
+// StoreMemory stores a memory entry for an agent, shared as far as scope says
+func (s *Service) StoreMemory(ctx context.Context, clientID string, agentID uuid.UUID, scope database.MemoryScope, entry models.MemoryEntry) error {
+	if err := s.CheckScope(ctx, clientID, scope); err != nil {
+		return err
+	}
+
+	// Generate embedding
+	embedding, err := s.embed(ctx, entry.Content)
+	if err != nil {
+		return err
 	}
 
 	// Add timestamp to metadata
@@ -50,30 +74,28 @@ func (s *Service) StoreMemory(ctx context.Context, agentID uuid.UUID, entry mode
 	entry.Metadata["type"] = entry.Type
 
 	// Store in database
-	return s.memoryRepo.StoreMemory(ctx, agentID, entry.Content, embedding, entry.Metadata)
+	_, err = s.memoryRepo.StoreMemory(ctx, clientID, agentID, scope, entry.Content, embedding, entry.Metadata)
+	return err
 }
 
-// RetrieveRelevantMemories retrieves memories relevant to a query
-func (s *Service) RetrieveRelevantMemories(ctx context.Context, agentID uuid.UUID, query string, limit int) ([]models.MemoryEntry, error) {
-	// Generate query embedding
-	queryEmbedding, err := s.aiClient.GenerateEmbedding(ctx, query)
-	if err != nil {
-		s.logger.Error("Failed to generate query embedding", zap.Error(err))
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
-	}
-
-	// Search for similar memories
-	records, err := s.memoryRepo.SearchMemory(ctx, agentID, queryEmbedding, limit)
+// RetrieveRelevantMemories retrieves memories relevant to a query, narrowed by the
+// filters in search
+func (s *Service) RetrieveRelevantMemories(ctx context.Context, clientID string, agentID uuid.UUID, query string, search database.MemorySearch) ([]models.MemoryEntry, error) {
+	records, err := s.SearchMemories(ctx, clientID, agentID, query, search)
 	if err != nil {
 		return nil, err
 	}
+	return memoryEntries(records), nil
+}
 
-	// Convert to memory entries
+// memoryEntries converts memory records into the entries agents are given
+func memoryEntries(records []database.MemoryRecord) []models.MemoryEntry {
 	entries := make([]models.MemoryEntry, len(records))
 	for i, record := range records {
 		entries[i] = models.MemoryEntry{
 			Content:  record.Content,
 			Metadata: record.Metadata,
+			Scope:    record.Scope,
 		}
 
 		// Extract type and timestamp from metadata
@@ -86,14 +108,19 @@ func (s *Service) RetrieveRelevantMemories(ctx context.Context, agentID uuid.UUI
 			}
 		}
 	}
-
-	return entries, nil
+	return entries
 }
 
-// ProcessWorkflowMemory handles memory storage for workflow steps
-func (s *Service) ProcessWorkflowMemory(ctx context.Context, agentID uuid.UUID, config models.MemoryConfiguration, step models.Step, input, output interface{}) error {
-	// Check if memory is enabled and this step should store memory
-	if !config.Enabled || !step.StoreMemory {
+// ProcessWorkflowMemory handles memory storage for workflow steps. Steps with
+// store_memory set are always stored; with auto_store, other step outputs are stored
+// when their Importance reaches auto_store_threshold. Memories go to the scope in the
+// config; projectID is the workflow's project, or uuid.Nil outside projects.
+func (s *Service) ProcessWorkflowMemory(ctx context.Context, clientID string, agentID, projectID uuid.UUID, config models.MemoryConfiguration, step models.Step, input, output interface{}) error {
+	if !config.Enabled {
+		return nil
+	}
+	importance := Importance(output)
+	if !step.StoreMemory && (!config.AutoStore || importance < config.AutoStoreThreshold) {
 		return nil
 	}
 
@@ -104,6 +131,7 @@ func (s *Service) ProcessWorkflowMemory(ctx context.Context, agentID uuid.UUID,
 		Metadata: map[string]interface{}{
 			"step_action":      step.Action,
 			"step_description": step.Description,
+			"importance":       importance,
 		},
 	}
 
@@ -121,11 +149,18 @@ func (s *Service) ProcessWorkflowMemory(ctx context.Context, agentID uuid.UUID,
 	entry.Content = string(contentBytes)
 
 	// Store the memory
-	return s.StoreMemory(ctx, agentID, entry)
+	return s.StoreMemory(ctx, clientID, agentID, StoreScope(config, projectID), entry)
 }
 
-// GetMemoryContext retrieves relevant memories for a given context
-func (s *Service) GetMemoryContext(ctx context.Context, agentID uuid.UUID, config models.MemoryConfiguration, currentContext string) ([]models.MemoryEntry, error) {
+// maxPinnedMemories bounds the pinned memories offered per scope
+const maxPinnedMemories = 20
+
+// GetMemoryContext retrieves relevant memories for a given context, merging the scopes
+// in the config by their weights. Pinned memories of those scopes come first and are
+// always offered, whatever their relevance, up to maxPinnedMemories per scope, even
+// without a context to search for.
+// projectID is the workflow's project, or uuid.Nil.
+func (s *Service) GetMemoryContext(ctx context.Context, clientID string, agentID, projectID uuid.UUID, config models.MemoryConfiguration, currentContext string) ([]models.MemoryEntry, error) {
 	if !config.Enabled {
 		return nil, nil
 	}
@@ -135,5 +170,133 @@ func (s *Service) GetMemoryContext(ctx context.Context, agentID uuid.UUID, confi
 		count = 5 // default
 	}
 
-	return s.RetrieveRelevantMemories(ctx, agentID, currentContext, count)
+	weights := SearchWeights(config, projectID)
+	pinnedOnly := true
+	var pinned []database.MemoryRecord
+	for _, scope := range []string{database.ScopeInstance, database.ScopeProject, database.ScopeClient} {
+		if weights[scope] <= 0 {
+			continue
+		}
+		records, _, err := s.memoryRepo.ListMemories(ctx, clientID, agentID, database.MemoryFilter{
+			Pinned:    &pinnedOnly,
+			Scope:     scope,
+			ProjectID: projectID,
+			Limit:     maxPinnedMemories,
+		})
+		if err != nil {
+			return nil, err
+		}
+		pinned = append(pinned, records...)
+	}
+
+	// Pinned memories are offered even when there is nothing to search for or the search fails
+	if currentContext == "" {
+		return memoryEntries(pinned), nil
+	}
+	relevant, err := s.SearchMemories(ctx, clientID, agentID, currentContext, database.MemorySearch{
+		Types:         config.IncludeTypes,
+		MinSimilarity: config.MinSimilarity,
+		Weights:       weights,
+		ProjectID:     projectID,
+		Limit:         count,
+	})
+	if err != nil {
+		s.logger.Warn("Memory search failed, offering pinned memories only",
+			zap.String("client_id", clientID), zap.String("agent_id", agentID.String()), zap.Error(err))
+		return memoryEntries(pinned), nil
+	}
+	return memoryEntries(withPinned(pinned, relevant)), nil
+}
+
+// withPinned puts the pinned memories ahead of the relevant ones, leaving out relevant
+// memories that are already pinned
+func withPinned(pinned, relevant []database.MemoryRecord) []database.MemoryRecord {
+	if len(pinned) == 0 {
+		return relevant
+	}
+	seen := make(map[uuid.UUID]bool, len(pinned))
+	records := make([]database.MemoryRecord, 0, len(pinned)+len(relevant))
+	for _, record := range pinned {
+		seen[record.ID] = true
+		records = append(records, record)
+	}
+	for _, record := range relevant {
+		if !seen[record.ID] {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// SearchMemories returns the memories most relevant to query, combining vector similarity
+// with full-text matches. The filters, scope weights and limit come from search.
+func (s *Service) SearchMemories(ctx context.Context, clientID string, agentID uuid.UUID, query string, search database.MemorySearch) ([]database.MemoryRecord, error) {
+	if err := s.checkSearch(ctx, clientID, search); err != nil {
+		return nil, err
+	}
+	queryEmbedding, err := s.embed(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	search.Embedding = queryEmbedding
+	search.Text = query
+	return s.memoryRepo.SearchMemory(ctx, clientID, agentID, search)
+}
+
+// ListMemories returns a page of an agent's memories and the total matching the filter
+func (s *Service) ListMemories(ctx context.Context, clientID string, agentID uuid.UUID, filter database.MemoryFilter) ([]database.MemoryRecord, int, error) {
+	if filter.Scope != "" {
+		if err := s.CheckScope(ctx, clientID, database.MemoryScope{Level: filter.Scope, ProjectID: filter.ProjectID}); err != nil {
+			return nil, 0, err
+		}
+	}
+	return s.memoryRepo.ListMemories(ctx, clientID, agentID, filter)
+}
+
+// GetMemory returns one memory, or database.ErrMemoryNotFound
+func (s *Service) GetMemory(ctx context.Context, clientID string, agentID, id uuid.UUID) (*database.MemoryRecord, error) {
+	return s.memoryRepo.GetMemory(ctx, clientID, agentID, id)
+}
+
+// MemoryUpdate is a correction to a memory. Nil fields are left unchanged; metadata
+// keys are merged into the existing metadata.
+type MemoryUpdate struct {
+	Content  *string
+	Metadata map[string]interface{}
+}
+
+// UpdateMemory applies a correction, re-embedding the memory when its content changes
+func (s *Service) UpdateMemory(ctx context.Context, clientID string, agentID, id uuid.UUID, update MemoryUpdate) (*database.MemoryRecord, error) {
+	record, err := s.memoryRepo.GetMemory(ctx, clientID, agentID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Content != nil && *update.Content != record.Content {
+		embedding, err := s.embed(ctx, *update.Content)
+		if err != nil {
+			return nil, err
+		}
+		record.Content = *update.Content
+		record.Embedding = embedding
+	}
+	if record.Metadata == nil {
+		record.Metadata = make(map[string]interface{})
+	}
+	for k, v := range update.Metadata {
+		record.Metadata[k] = v
+	}
+	record.Metadata["edited"] = true
+
+	return s.memoryRepo.UpdateMemory(ctx, clientID, agentID, record)
+}
+
+// SetPinned pins or unpins a memory
+func (s *Service) SetPinned(ctx context.Context, clientID string, agentID, id uuid.UUID, pinned bool) (*database.MemoryRecord, error) {
+	return s.memoryRepo.SetPinned(ctx, clientID, agentID, id, pinned)
+}
+
+// DeleteMemory permanently removes a memory
+func (s *Service) DeleteMemory(ctx context.Context, clientID string, agentID, id uuid.UUID) error {
+	return s.memoryRepo.DeleteMemory(ctx, clientID, agentID, id)
 }
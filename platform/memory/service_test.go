@@ -0,0 +1,25 @@
+// FILE: platform/memory/service_test.go
+package memory
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/platform/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithPinned(t *testing.T) {
+	pinnedA, pinnedB, relevant := uuid.New(), uuid.New(), uuid.New()
+	pinned := []database.MemoryRecord{{ID: pinnedA, Content: "always sign off as Sam", Pinned: true}, {ID: pinnedB, Content: "UK spelling", Pinned: true}}
+
+	records := withPinned(pinned, []database.MemoryRecord{{ID: relevant, Content: "launch is in March"}, {ID: pinnedB, Content: "UK spelling", Pinned: true}})
+	var ids []uuid.UUID
+	for _, record := range records {
+		ids = append(ids, record.ID)
+	}
+	assert.Equal(t, []uuid.UUID{pinnedA, pinnedB, relevant}, ids)
+
+	assert.Equal(t, pinned, withPinned(pinned, nil))
+	assert.Len(t, withPinned(nil, pinned), 2)
+}
@@ -25,7 +25,7 @@ type MessageProcessor struct {
 	producer     kafka.Producer
 	orchestrator *orchestration.SagaCoordinator
 	validator    *validation.WorkflowValidator
-	configLoader *config.AgentConfigLoader
+	configCache  *config.AgentConfigCache
 	logger       *zap.Logger
 }
 
@@ -38,17 +38,30 @@ func NewMessageProcessor(
 	validator *validation.WorkflowValidator,
 	logger *zap.Logger,
 ) *MessageProcessor {
+	configLoader := config.NewAgentConfigLoader(logger)
+	configCache := config.NewAgentConfigCache(
+		func(ctx context.Context, clientID, agentInstanceID, agentType string) (*models.AgentConfig, error) {
+			return configLoader.LoadFromDatabase(ctx, db, clientID, agentInstanceID, agentType)
+		},
+		config.DefaultAgentConfigTTL,
+	)
+
 	return &MessageProcessor{
 		agentType:    agentType,
 		db:           db,
 		producer:     producer,
 		orchestrator: orchestrator,
 		validator:    validator,
-		configLoader: config.NewAgentConfigLoader(logger),
+		configCache:  configCache,
 		logger:       logger,
 	}
 }
 
+// ConfigCache returns the cache of agent configs so it can be invalidated on change events
+func (p *MessageProcessor) ConfigCache() *config.AgentConfigCache {
+	return p.configCache
+}
+
 // ProcessMessage handles a single message
 func (p *MessageProcessor) ProcessMessage(ctx context.Context, msg kafka.Message) error {
 	startTime := time.Now()
@@ -95,10 +108,9 @@ func (p *MessageProcessor) process(ctx context.Context, msgCtx *MessageContext)
 		return errors.ValidationError("headers", err.Error())
 	}
 
-	// Load agent configuration
-	agentConfig, err := p.configLoader.LoadFromDatabase(
+	// Load agent configuration (cached; invalidated by agent_config_changed events)
+	agentConfig, err := p.configCache.Get(
 		ctx,
-		p.db,
 		msgCtx.Headers["client_id"],
 		msgCtx.Headers["agent_instance_id"],
 		p.agentType,
@@ -130,7 +142,7 @@ func (p *MessageProcessor) executeWorkflow(ctx context.Context, msgCtx *MessageC
 	defer observability.ActiveWorkflows.WithLabelValues(p.agentType).Dec()
 
 	// Execute through orchestrator
-	return p.orchestrator.ExecuteWorkflow(ctx, config.Workflow, msgCtx.Headers, msgCtx.Message.Value)
+	return p.orchestrator.ExecuteWorkflow(ctx, config, msgCtx.Headers, msgCtx.Message.Value)
 }
 
 func (p *MessageProcessor) handleError(ctx context.Context, msgCtx *MessageContext, err error, errorType string) error {
@@ -150,6 +162,8 @@ func (p *MessageProcessor) handleError(ctx context.Context, msgCtx *MessageConte
 	return err
 }
 
+// sendErrorResponse publishes a failure to the agent's error topic and, when the
+// request named one, to its reply topic
 func (p *MessageProcessor) sendErrorResponse(ctx context.Context, msgCtx *MessageContext, domainErr *errors.DomainError) {
 	responseHeaders := msgCtx.CreateResponseHeaders(p.agentType)
 	domainErr.TraceID = msgCtx.Headers["correlation_id"]
@@ -170,4 +184,21 @@ func (p *MessageProcessor) sendErrorResponse(ctx context.Context, msgCtx *Messag
 	} else {
 		observability.KafkaMessagesProduced.WithLabelValues(errorTopic).Inc()
 	}
+
+	// A caller waiting on a reply topic gets the failure instead of timing out
+	replyTopic := msgCtx.Headers[kafka.ReplyTopicHeader]
+	if replyTopic == "" {
+		return
+	}
+	replyBytes, _ := json.Marshal(models.TaskResponse{
+		Success: false,
+		Error:   fmt.Sprintf("%s: %s", domainErr.Code, domainErr.Message),
+	})
+	if err := p.producer.Produce(ctx, replyTopic, responseHeaders,
+		[]byte(msgCtx.Headers["correlation_id"]), replyBytes); err != nil {
+		msgCtx.Logger.Error("Failed to send error reply", zap.String("reply_topic", replyTopic), zap.Error(err))
+		observability.SystemErrors.WithLabelValues(p.agentType, "produce_error").Inc()
+	} else {
+		observability.KafkaMessagesProduced.WithLabelValues(replyTopic).Inc()
+	}
 }
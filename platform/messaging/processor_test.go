@@ -0,0 +1,80 @@
+// FILE: platform/messaging/processor_test.go
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type producedMessage struct {
+	topic   string
+	headers map[string]string
+	value   []byte
+}
+
+// recordingProducer keeps what is produced
+type recordingProducer struct {
+	messages []producedMessage
+}
+
+func (p *recordingProducer) Produce(ctx context.Context, topic string, headers map[string]string, key, value []byte) error {
+	p.messages = append(p.messages, producedMessage{topic: topic, headers: headers, value: value})
+	return nil
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func messageHeaders(headers map[string]string) []kafka.Header {
+	var result []kafka.Header
+	for k, v := range headers {
+		result = append(result, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	return result
+}
+
+func TestProcessMessage_InvalidPayloadRepliesToCaller(t *testing.T) {
+	producer := &recordingProducer{}
+	processor := &MessageProcessor{agentType: "reasoning", producer: producer, logger: zap.NewNop()}
+
+	msg := kafka.Message{
+		Value: []byte("not json"),
+		Headers: messageHeaders(map[string]string{
+			"correlation_id":       "corr-1",
+			"request_id":           "req-1",
+			"client_id":            "acme",
+			kafka.ReplyTopicHeader: "system.responses.core-manager",
+		}),
+	}
+	require.Error(t, processor.ProcessMessage(context.Background(), msg))
+
+	require.Len(t, producer.messages, 2)
+	assert.Equal(t, "system.errors.reasoning", producer.messages[0].topic)
+
+	reply := producer.messages[1]
+	assert.Equal(t, "system.responses.core-manager", reply.topic)
+	assert.Equal(t, "req-1", reply.headers["causation_id"])
+	var response models.TaskResponse
+	require.NoError(t, json.Unmarshal(reply.value, &response))
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "INTERNAL_ERROR")
+}
+
+func TestProcessMessage_ErrorWithoutReplyTopic(t *testing.T) {
+	producer := &recordingProducer{}
+	processor := &MessageProcessor{agentType: "reasoning", producer: producer, logger: zap.NewNop()}
+
+	msg := kafka.Message{
+		Value:   []byte("not json"),
+		Headers: messageHeaders(map[string]string{"correlation_id": "corr-1", "request_id": "req-1"}),
+	}
+	require.Error(t, processor.ProcessMessage(context.Background(), msg))
+	require.Len(t, producer.messages, 1)
+	assert.Equal(t, "system.errors.reasoning", producer.messages[0].topic)
+}
@@ -55,6 +55,23 @@ var (
 		Help: "Total number of operations that failed due to insufficient fuel",
 	}, []string{"agent_type", "action", "client_id"})
 
+	// AI model metrics
+	AIModelFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_model_fallbacks_total",
+		Help: "Total number of AI calls moved off a model, by reason (fuel, unavailable, error)",
+	}, []string{"agent_type", "model", "reason"})
+
+	AIResponseCacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_response_cache_lookups_total",
+		Help: "Total number of AI response cache lookups, by result (hit, semantic_hit, miss)",
+	}, []string{"model", "result"})
+
+	// Guardrail metrics
+	GuardrailViolations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_guardrail_violations_total",
+		Help: "Total number of guardrail matches, by rule, stage and action (block, flag)",
+	}, []string{"agent_type", "rule", "stage", "action"})
+
 	// Kafka metrics
 	KafkaMessagesProduced = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "ai_persona_kafka_messages_produced_total",
@@ -117,6 +134,45 @@ var (
 		Help: "Total number of memories stored",
 	}, []string{"agent_type", "memory_type"})
 
+	// Memory retention metrics
+	MemoriesRemoved = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_memories_removed_total",
+		Help: "Total number of memories removed by retention",
+	}, []string{"reason"}) // expired, cap or consolidated
+
+	MemoryConsolidations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_memory_consolidations_total",
+		Help: "Total number of memory clusters consolidated into summaries",
+	}, []string{"status"})
+
+	MemoryRetentionRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_memory_retention_runs_total",
+		Help: "Total number of memory retention runs",
+	}, []string{"status"})
+
+	MemoryRetentionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ai_persona_memory_retention_duration_seconds",
+		Help:    "Duration of memory retention runs",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12), // 100ms to ~7min
+	})
+
+	// Document ingestion metrics
+	DocumentsIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_documents_ingested_total",
+		Help: "Total number of documents ingested into memory",
+	}, []string{"format", "status"})
+
+	DocumentChunksStored = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ai_persona_document_chunks_stored_total",
+		Help: "Total number of document chunks stored as memories",
+	}, []string{"format"})
+
+	DocumentIngestionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ai_persona_document_ingestion_duration_seconds",
+		Help:    "Duration of document ingestion, from download to the last stored chunk",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 12), // 100ms to ~7min
+	}, []string{"format"})
+
 	// Circuit breaker metrics
 	CircuitBreakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "ai_persona_circuit_breaker_state",
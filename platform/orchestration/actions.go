@@ -0,0 +1,29 @@
+// FILE: platform/orchestration/actions.go
+package orchestration
+
+import (
+	"context"
+
+	"github.com/gqls/agentchassis/pkg/models"
+)
+
+// ActionRequest is everything a local action handler gets to work with
+type ActionRequest struct {
+	Action      string
+	StepName    string
+	Step        models.Step
+	Headers     map[string]string
+	Data        map[string]interface{} // Data collected by earlier steps
+	InitialData []byte                 // The original request payload
+	Config      *models.AgentConfig    // The config pinned when the workflow started
+	Progress    ProgressFunc           // Sends partial output to the UI as STEP_PROGRESS events
+	Notify      NotifyFunc             // Sends other events about the step to the UI
+}
+
+// ActionHandler executes a workflow action in-process and returns the step output
+type ActionHandler func(ctx context.Context, req *ActionRequest) (map[string]interface{}, error)
+
+// ActionExecutor resolves actions that can be executed without a Kafka round trip
+type ActionExecutor interface {
+	Get(action string) (ActionHandler, bool)
+}
@@ -10,6 +10,8 @@ import (
 	"github.com/gqls/agentchassis/pkg/models"
 	"github.com/gqls/agentchassis/platform/governance"
 	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/observability"
+	"github.com/gqls/agentchassis/platform/prompts"
 	"go.uber.org/zap"
 )
 
@@ -26,6 +28,14 @@ type SagaCoordinator struct {
 	producer    kafka.Producer
 	logger      *zap.Logger
 	fuelManager *governance.FuelManager
+	executor    ActionExecutor
+	memory      MemoryStore
+	prompts     prompts.Library
+
+	// responseTopic receives workflow outcomes when the caller did not ask for a reply
+	responseTopic string
+	// stepReplyTopic receives the responses of steps dispatched to other services
+	stepReplyTopic string
 }
 
 // NewSagaCoordinator creates a new coordinator instance
@@ -38,27 +48,64 @@ func NewSagaCoordinator(db *sql.DB, producer kafka.Producer, logger *zap.Logger)
 	}
 }
 
-// ExecuteWorkflow manages the execution of a workflow plan
-func (s *SagaCoordinator) ExecuteWorkflow(ctx context.Context, plan models.WorkflowPlan, headers map[string]string, initialData []byte) error {
+// SetActionExecutor registers the resolver for actions that run in-process.
+// Actions it does not know about are dispatched to their step topic as before.
+func (s *SagaCoordinator) SetActionExecutor(executor ActionExecutor) {
+	s.executor = executor
+}
+
+// SetResponseTopic publishes completed and failed workflow outcomes to topic
+// for requests that did not carry a reply_topic header
+func (s *SagaCoordinator) SetResponseTopic(topic string) {
+	s.responseTopic = topic
+}
+
+// SetStepReplyTopic asks services running remote steps to reply on topic. The agent
+// consumes it and hands the replies to HandleResponse. Without it, workflows with
+// remote steps fail when they reach one, since nothing would continue them.
+func (s *SagaCoordinator) SetStepReplyTopic(topic string) {
+	s.stepReplyTopic = topic
+}
+
+// ExecuteWorkflow manages the execution of a workflow plan.
+// The config is pinned into the workflow state when the workflow starts, with the
+// versions of its prompt templates; every later step runs against that snapshot,
+// whatever the current config and templates say.
+func (s *SagaCoordinator) ExecuteWorkflow(ctx context.Context, agentConfig *models.AgentConfig, headers map[string]string, initialData []byte) error {
 	correlationID := headers["correlation_id"]
 	l := s.logger.With(zap.String("correlation_id", correlationID))
 
 	// Get or create state
-	state, err := s.getOrCreateState(ctx, correlationID, plan, initialData)
+	state, err := s.getOrCreateState(ctx, correlationID, agentConfig, initialData)
 	if err != nil {
 		return err
 	}
 
+	pinned := state.ConfigSnapshot
+	if pinned == nil {
+		// Workflow started before config pinning existed
+		pinned = agentConfig
+	} else if pinned.Version != agentConfig.Version {
+		l.Info("Running workflow against pinned config",
+			zap.Int("pinned_version", pinned.Version),
+			zap.Int("current_version", agentConfig.Version))
+	}
+	plan := pinned.Workflow
+
 	// Check if workflow is already complete
 	if state.Status == StatusCompleted || state.Status == StatusFailed {
 		l.Info("Workflow already finished", zap.String("status", string(state.Status)))
 		return nil
 	}
+	if state.Status == StatusAwaitingResponses {
+		l.Info("Workflow is waiting for remote steps", zap.Strings("awaited_steps", state.AwaitedSteps))
+		return nil
+	}
 
 	// Get current step configuration
 	currentStepConfig, ok := plan.Steps[state.CurrentStep]
 	if !ok {
-		return s.failWorkflow(ctx, state, fmt.Sprintf("step '%s' not found in plan", state.CurrentStep))
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("step '%s' not found in plan", state.CurrentStep))
 	}
 
 	// Check dependencies
@@ -70,17 +117,26 @@ func (s *SagaCoordinator) ExecuteWorkflow(ctx context.Context, plan models.Workf
 	// Check fuel budget
 	fuel, err := governance.GetFuelFromHeader(headers)
 	if err != nil {
-		return s.failWorkflow(ctx, state, fmt.Sprintf("failed to get fuel from headers: %v", err))
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("failed to get fuel from headers: %v", err))
 	}
 
 	if !s.fuelManager.HasEnoughFuel(fuel, currentStepConfig.Action) {
-		return s.failWorkflow(ctx, state, fmt.Sprintf("insufficient fuel for action '%s': have %d, need %d",
+		observability.FuelExhausted.WithLabelValues(pinned.AgentType, currentStepConfig.Action, headers["client_id"]).Inc()
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("insufficient fuel for action '%s': have %d, need %d",
 			currentStepConfig.Action, fuel, s.fuelManager.GetCost(currentStepConfig.Action)))
 	}
 
 	// Deduct fuel and update headers
 	remainingFuel := s.fuelManager.DeductFuel(fuel, currentStepConfig.Action)
 	governance.SetFuelHeader(headers, remainingFuel)
+	stepCost := fuel - remainingFuel
+	state.FuelConsumed += stepCost
+	observability.FuelConsumed.WithLabelValues(pinned.AgentType, currentStepConfig.Action, headers["client_id"]).Add(float64(stepCost))
+
+	// Memories relevant to the request are available to every step
+	if state.CurrentStep == plan.StartStep {
+		s.injectMemories(ctx, pinned, headers, initialData, state)
+	}
 
 	// Execute the action
 	switch currentStepConfig.Action {
@@ -89,20 +145,36 @@ func (s *SagaCoordinator) ExecuteWorkflow(ctx context.Context, plan models.Workf
 	case "pause_for_human_input":
 		return s.handlePauseForHumanInput(ctx, headers, currentStepConfig, state)
 	case "complete_workflow":
-		return s.completeWorkflow(ctx, state)
+		return s.completeWorkflow(ctx, headers, state)
+	case StoreMemoryAction, RetrieveMemoryAction:
+		return s.handleMemoryAction(ctx, pinned, headers, initialData, currentStepConfig, state)
 	default:
+		if handler, ok := s.localHandler(currentStepConfig.Action); ok {
+			return s.handleLocalAction(ctx, pinned, headers, initialData, currentStepConfig, state, handler)
+		}
+		if currentStepConfig.Topic == "" {
+			return s.failWorkflow(ctx, headers, state, fmt.Sprintf("no handler or topic for action '%s'", currentStepConfig.Action))
+		}
 		return s.handleStandardAction(ctx, headers, currentStepConfig, state)
 	}
 }
 
+// localHandler looks up an in-process handler for the action
+func (s *SagaCoordinator) localHandler(action string) (ActionHandler, bool) {
+	if s.executor == nil {
+		return nil, false
+	}
+	return s.executor.Get(action)
+}
+
 // getOrCreateState retrieves existing state or creates new one
-func (s *SagaCoordinator) getOrCreateState(ctx context.Context, correlationID string, plan models.WorkflowPlan, initialData []byte) (*OrchestrationState, error) {
+func (s *SagaCoordinator) getOrCreateState(ctx context.Context, correlationID string, agentConfig *models.AgentConfig, initialData []byte) (*OrchestrationState, error) {
 	repo := NewStateRepository(s.db, s.logger)
 
 	state, err := repo.GetState(ctx, correlationID)
 	if err != nil {
-		// State doesn't exist, create it
-		if err := repo.CreateInitialState(ctx, correlationID, plan.StartStep, initialData); err != nil {
+		// State doesn't exist, create it with the config and its prompt templates pinned
+		if err := repo.CreateInitialState(ctx, correlationID, s.pinPromptTemplates(ctx, agentConfig), initialData); err != nil {
 			return nil, fmt.Errorf("failed to create initial state: %w", err)
 		}
 		return repo.GetState(ctx, correlationID)
@@ -125,6 +197,10 @@ func (s *SagaCoordinator) dependenciesMet(dependencies []string, state *Orchestr
 func (s *SagaCoordinator) handleStandardAction(ctx context.Context, headers map[string]string, step models.Step, state *OrchestrationState) error {
 	l := s.logger.With(zap.String("correlation_id", state.CorrelationID))
 
+	if s.stepReplyTopic == "" {
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("action '%s' runs remotely and this agent has no step reply topic", step.Action))
+	}
+
 	// Prepare the message payload
 	payload := models.TaskRequest{
 		Action: step.Action,
@@ -140,6 +216,7 @@ func (s *SagaCoordinator) handleStandardAction(ctx context.Context, headers map[
 	}
 	outHeaders["causation_id"] = headers["request_id"]
 	outHeaders["request_id"] = newRequestID
+	outHeaders[kafka.ReplyTopicHeader] = s.stepReplyTopic
 
 	// Send the message
 	if err := s.producer.Produce(ctx, step.Topic, outHeaders, []byte(state.CorrelationID), payloadBytes); err != nil {
@@ -147,9 +224,11 @@ func (s *SagaCoordinator) handleStandardAction(ctx context.Context, headers map[
 	}
 
 	// Update state to await response
+	state.DispatchedSteps = map[string]DispatchedStep{newRequestID: {Step: state.CurrentStep, Output: state.CurrentStep}}
 	state.Status = StatusAwaitingResponses
 	state.CurrentStep = step.NextStep
 	state.AwaitedSteps = []string{newRequestID}
+	state.RequestHeaders = headers
 
 	repo := NewStateRepository(s.db, s.logger)
 	if err := repo.UpdateState(ctx, state); err != nil {
@@ -160,11 +239,75 @@ func (s *SagaCoordinator) handleStandardAction(ctx context.Context, headers map[
 	return nil
 }
 
+// handleLocalAction runs a registered handler in-process and advances the workflow
+func (s *SagaCoordinator) handleLocalAction(ctx context.Context, agentConfig *models.AgentConfig, headers map[string]string, initialData []byte, step models.Step, state *OrchestrationState, handler ActionHandler) error {
+	stepName := state.CurrentStep
+	progress := s.newProgressReporter(ctx, headers, stepName, step.Action)
+	fuelBefore, _ := governance.GetFuelFromHeader(headers)
+
+	output, err := handler(ctx, &ActionRequest{
+		Action:      step.Action,
+		StepName:    stepName,
+		Step:        step,
+		Headers:     headers,
+		Data:        state.CollectedData,
+		InitialData: initialData,
+		Config:      agentConfig,
+		Progress:    progress.Report,
+		Notify:      s.notifier(ctx, headers, stepName, step.Action),
+	})
+	progress.Flush()
+
+	// Handlers charge token usage and tool calls to the budget in the headers
+	if fuelAfter, ferr := governance.GetFuelFromHeader(headers); ferr == nil && fuelAfter < fuelBefore {
+		state.FuelConsumed += fuelBefore - fuelAfter
+	}
+	if err != nil {
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("action '%s' failed: %v", step.Action, err))
+	}
+
+	if output == nil {
+		output = map[string]interface{}{}
+	}
+	s.autoStoreMemory(ctx, agentConfig, headers, initialData, step, output)
+
+	return s.advance(ctx, agentConfig, headers, initialData, step, state, output)
+}
+
+// advance records the output of a step that ran in-process and continues with the next step
+func (s *SagaCoordinator) advance(ctx context.Context, agentConfig *models.AgentConfig, headers map[string]string, initialData []byte, step models.Step, state *OrchestrationState, output map[string]interface{}) error {
+	stepName := state.CurrentStep
+	state.CollectedData[stepName] = output
+	state.Status = StatusRunning
+	state.CurrentStep = step.NextStep
+
+	if step.NextStep == "" {
+		return s.completeWorkflow(ctx, headers, state)
+	}
+
+	repo := NewStateRepository(s.db, s.logger)
+	if err := repo.UpdateState(ctx, state); err != nil {
+		return fmt.Errorf("failed to update state: %w", err)
+	}
+
+	s.logger.Info("Local action executed",
+		zap.String("correlation_id", state.CorrelationID),
+		zap.String("action", step.Action), zap.String("step", stepName))
+
+	// Continue straight on to the next step
+	return s.ExecuteWorkflow(ctx, agentConfig, headers, initialData)
+}
+
 // handleFanOut sends multiple parallel requests
 func (s *SagaCoordinator) handleFanOut(ctx context.Context, headers map[string]string, step models.Step, state *OrchestrationState) error {
 	l := s.logger.With(zap.String("correlation_id", state.CorrelationID))
 
+	if s.stepReplyTopic == "" {
+		return s.failWorkflow(ctx, headers, state, "fan_out runs remotely and this agent has no step reply topic")
+	}
+
 	awaitedSteps := make([]string, 0, len(step.SubTasks))
+	dispatched := make(map[string]DispatchedStep, len(step.SubTasks))
 
 	for _, subTask := range step.SubTasks {
 		payload := models.TaskRequest{
@@ -180,18 +323,22 @@ func (s *SagaCoordinator) handleFanOut(ctx context.Context, headers map[string]s
 		}
 		outHeaders["causation_id"] = headers["request_id"]
 		outHeaders["request_id"] = newRequestID
+		outHeaders[kafka.ReplyTopicHeader] = s.stepReplyTopic
 
 		if err := s.producer.Produce(ctx, subTask.Topic, outHeaders, []byte(state.CorrelationID), payloadBytes); err != nil {
 			return fmt.Errorf("failed to produce fan-out message: %w", err)
 		}
 
 		awaitedSteps = append(awaitedSteps, newRequestID)
+		dispatched[newRequestID] = DispatchedStep{Step: state.CurrentStep, Output: subTask.StepName}
 	}
 
 	// Update state
 	state.Status = StatusAwaitingResponses
 	state.CurrentStep = step.NextStep
 	state.AwaitedSteps = awaitedSteps
+	state.DispatchedSteps = dispatched
+	state.RequestHeaders = headers
 
 	repo := NewStateRepository(s.db, s.logger)
 	if err := repo.UpdateState(ctx, state); err != nil {
@@ -233,7 +380,9 @@ func (s *SagaCoordinator) handlePauseForHumanInput(ctx context.Context, headers
 	return nil
 }
 
-// HandleResponse processes a response from a sub-task
+// HandleResponse processes a response from a sub-task. Once every awaited response
+// is in, the workflow continues with its next step, or completes if there is none,
+// and replies to its caller with the headers of the request that started it.
 func (s *SagaCoordinator) HandleResponse(ctx context.Context, headers map[string]string, response []byte) error {
 	correlationID := headers["correlation_id"]
 	causationID := headers["causation_id"]
@@ -249,14 +398,44 @@ func (s *SagaCoordinator) HandleResponse(ctx context.Context, headers map[string
 		return fmt.Errorf("failed to get state: %w", err)
 	}
 
+	// Late, duplicate and unrelated responses must not move the workflow on
+	if state.Status != StatusAwaitingResponses || !contains(state.AwaitedSteps, causationID) {
+		l.Warn("Ignoring response the workflow is not waiting for", zap.String("status", string(state.Status)))
+		return nil
+	}
+
 	// Parse response
 	var taskResponse models.TaskResponse
 	if err := json.Unmarshal(response, &taskResponse); err != nil {
 		return fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	// Store response data
-	state.CollectedData[causationID] = taskResponse.Data
+	// The workflow continues and replies with the headers of the request that started it.
+	// Workflows dispatched before those were stored only know their correlation ID.
+	requestHeaders := make(map[string]string, len(state.RequestHeaders)+1)
+	for k, v := range state.RequestHeaders {
+		requestHeaders[k] = v
+	}
+	requestHeaders["correlation_id"] = state.CorrelationID
+	if !taskResponse.Success {
+		return s.failWorkflow(ctx, requestHeaders, state, fmt.Sprintf("remote step failed: %s", taskResponse.Error))
+	}
+
+	// Store response data under the step that asked for it; requests sent before
+	// dispatched steps were recorded are collected under their request ID
+	dispatched, ok := state.DispatchedSteps[causationID]
+	if !ok {
+		dispatched = DispatchedStep{Output: causationID}
+	}
+	state.CollectedData[dispatched.Output] = taskResponse.Data
+	delete(state.DispatchedSteps, causationID)
+
+	// Remote outputs are offered to memory like those of local steps
+	if state.ConfigSnapshot != nil {
+		if step, ok := state.ConfigSnapshot.Workflow.Steps[dispatched.Step]; ok {
+			s.autoStoreMemory(ctx, state.ConfigSnapshot, requestHeaders, state.InitialRequestData, step, taskResponse.Data)
+		}
+	}
 
 	// Remove from awaited steps
 	newAwaitedSteps := make([]string, 0)
@@ -267,25 +446,34 @@ func (s *SagaCoordinator) HandleResponse(ctx context.Context, headers map[string
 	}
 	state.AwaitedSteps = newAwaitedSteps
 
-	// If all responses received, set status back to running
-	if len(state.AwaitedSteps) == 0 {
-		state.Status = StatusRunning
+	l.Info("Response processed", zap.Int("remaining_awaited", len(state.AwaitedSteps)))
+
+	if len(state.AwaitedSteps) > 0 {
+		return repo.UpdateState(ctx, state)
 	}
 
+	// All responses received, continue the workflow
+	state.Status = StatusRunning
+	if state.CurrentStep == "" {
+		return s.completeWorkflow(ctx, requestHeaders, state)
+	}
+	if state.ConfigSnapshot == nil {
+		return s.failWorkflow(ctx, requestHeaders, state, "workflow started before config pinning cannot continue after a remote step")
+	}
 	if err := repo.UpdateState(ctx, state); err != nil {
 		return fmt.Errorf("failed to update state: %w", err)
 	}
+	return s.ExecuteWorkflow(ctx, state.ConfigSnapshot, requestHeaders, state.InitialRequestData)
+}
 
-	l.Info("Response processed", zap.Int("remaining_awaited", len(state.AwaitedSteps)))
-
-	// If all responses received, continue workflow
-	if len(state.AwaitedSteps) == 0 {
-		// Need to reload the workflow plan - this would come from the agent config
-		// For now, we'll need to pass it through somehow
-		// This is a limitation we'll address in the actual implementation
+// contains reports whether values holds value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
 	}
-
-	return nil
+	return false
 }
 
 // ResumeWorkflow resumes a paused workflow after human input
@@ -336,17 +524,23 @@ func (s *SagaCoordinator) ResumeWorkflow(ctx context.Context, headers map[string
 }
 
 // completeWorkflow marks the workflow as completed
-func (s *SagaCoordinator) completeWorkflow(ctx context.Context, state *OrchestrationState) error {
+func (s *SagaCoordinator) completeWorkflow(ctx context.Context, headers map[string]string, state *OrchestrationState) error {
 	state.Status = StatusCompleted
-	finalResult, _ := json.Marshal(state.CollectedData)
+	result := stepOutputs(state.CollectedData)
+	finalResult, _ := json.Marshal(result)
 	state.FinalResult = finalResult
 
 	repo := NewStateRepository(s.db, s.logger)
-	return repo.UpdateState(ctx, state)
+	if err := repo.UpdateState(ctx, state); err != nil {
+		return err
+	}
+
+	s.sendReply(ctx, headers, models.TaskResponse{Success: true, Data: result})
+	return nil
 }
 
 // failWorkflow marks the workflow as failed
-func (s *SagaCoordinator) failWorkflow(ctx context.Context, state *OrchestrationState, errorMsg string) error {
+func (s *SagaCoordinator) failWorkflow(ctx context.Context, headers map[string]string, state *OrchestrationState, errorMsg string) error {
 	state.Status = StatusFailed
 	state.Error = errorMsg
 
@@ -355,6 +549,35 @@ func (s *SagaCoordinator) failWorkflow(ctx context.Context, state *Orchestration
 		return fmt.Errorf("failed to update state to failed: %w", err)
 	}
 
+	s.sendReply(ctx, headers, models.TaskResponse{Success: false, Error: errorMsg})
+
 	// IMPORTANT: Return the error message as an error
 	return fmt.Errorf(errorMsg)
 }
+
+// sendReply publishes the workflow outcome to the caller's reply topic if one was requested,
+// otherwise to the coordinator's response topic if one is configured
+func (s *SagaCoordinator) sendReply(ctx context.Context, headers map[string]string, response models.TaskResponse) {
+	replyTopic := headers[kafka.ReplyTopicHeader]
+	if replyTopic == "" {
+		replyTopic = s.responseTopic
+	}
+	if replyTopic == "" {
+		return
+	}
+
+	replyHeaders := map[string]string{
+		"correlation_id": headers["correlation_id"],
+		"causation_id":   headers["request_id"],
+		"request_id":     uuid.NewString(),
+		"client_id":      headers["client_id"],
+	}
+	responseBytes, _ := json.Marshal(response)
+
+	if err := s.producer.Produce(ctx, replyTopic, replyHeaders, []byte(headers["correlation_id"]), responseBytes); err != nil {
+		s.logger.Error("Failed to send workflow reply",
+			zap.String("correlation_id", headers["correlation_id"]),
+			zap.String("reply_topic", replyTopic),
+			zap.Error(err))
+	}
+}
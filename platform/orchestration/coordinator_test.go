@@ -4,6 +4,7 @@ package orchestration
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"testing"
 	"time"
@@ -12,6 +13,8 @@ import (
 	"github.com/google/uuid"
 	"github.com/gqls/agentchassis/pkg/models"
 	"github.com/gqls/agentchassis/platform/governance"
+	"github.com/gqls/agentchassis/platform/kafka"
+	"github.com/gqls/agentchassis/platform/prompts"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -33,6 +36,9 @@ func (m *MockKafkaProducer) Close() error {
 	return args.Error(0)
 }
 
+// stepReplyTopic is where remote steps reply in tests
+const stepReplyTopic = "system.responses.test-steps"
+
 // setupTest creates the coordinator with mocked dependencies for testing.
 func setupTest(t *testing.T) (*SagaCoordinator, *MockKafkaProducer, *sql.DB, sqlmock.Sqlmock) {
 	db, mockDB, err := sqlmock.New()
@@ -52,6 +58,8 @@ func TestExecuteWorkflow_InitialStep(t *testing.T) {
 	coordinator, mockProducer, db, mockDB := setupTest(t)
 	defer db.Close()
 
+	coordinator.SetStepReplyTopic(stepReplyTopic)
+
 	ctx := context.Background()
 	correlationID := uuid.NewString()
 	headers := map[string]string{
@@ -83,6 +91,8 @@ func TestExecuteWorkflow_InitialStep(t *testing.T) {
 			sqlmock.AnyArg(), // awaited_steps
 			sqlmock.AnyArg(), // collected_data
 			initialData,      // initial_request_data
+			1,                // config_version
+			sqlmock.AnyArg(), // config_snapshot
 			sqlmock.AnyArg(), // created_at
 			sqlmock.AnyArg(), // updated_at
 		).WillReturnResult(sqlmock.NewResult(1, 1))
@@ -90,19 +100,20 @@ func TestExecuteWorkflow_InitialStep(t *testing.T) {
 	// Then expect fetch of the newly created state
 	rows := sqlmock.NewRows([]string{
 		"correlation_id", "status", "current_step", "awaited_steps",
-		"collected_data", "initial_request_data", "final_result", "error",
-		"created_at", "updated_at",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
 	}).AddRow(
 		correlationID, StatusRunning, "step1", "[]",
-		"{}", initialData, nil, nil, // Use nil for NULL values
+		"{}", initialData, nil, nil, 0, nil, nil, nil, nil, // Use nil for NULL values
 		time.Now(), time.Now(),
 	)
 	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
 		WithArgs(correlationID).
 		WillReturnRows(rows)
 
-	// Expect Kafka message production
-	mockProducer.On("Produce", ctx, "topic.do_something", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+	// Expect Kafka message production, answered on the step reply topic
+	replyTo := mock.MatchedBy(func(h map[string]string) bool { return h[kafka.ReplyTopicHeader] == stepReplyTopic })
+	mockProducer.On("Produce", ctx, "topic.do_something", replyTo, mock.Anything, mock.Anything).Return(nil).Once()
 
 	// Expect state update
 	mockDB.ExpectExec("UPDATE orchestrator_state SET").
@@ -115,9 +126,12 @@ func TestExecuteWorkflow_InitialStep(t *testing.T) {
 			sqlmock.AnyArg(),        // final_result = $6
 			"",                      // error = $7
 			sqlmock.AnyArg(),        // updated_at = $8
+			sqlmock.AnyArg(),        // fuel_consumed = $9
+			sqlmock.AnyArg(),        // request_headers = $10
+			sqlmock.AnyArg(),        // dispatched_steps = $11
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err := coordinator.ExecuteWorkflow(ctx, plan, headers, initialData)
+	err := coordinator.ExecuteWorkflow(ctx, &models.AgentConfig{Version: 1, Workflow: plan}, headers, initialData)
 	require.NoError(t, err)
 
 	mockProducer.AssertExpectations(t)
@@ -158,6 +172,8 @@ func TestExecuteWorkflow_DependenciesNotMet(t *testing.T) {
 			sqlmock.AnyArg(), // awaited_steps
 			sqlmock.AnyArg(), // collected_data
 			sqlmock.AnyArg(), // initial_request_data (nil)
+			1,                // config_version
+			sqlmock.AnyArg(), // config_snapshot
 			sqlmock.AnyArg(), // created_at
 			sqlmock.AnyArg(), // updated_at
 		).WillReturnResult(sqlmock.NewResult(1, 1))
@@ -166,11 +182,11 @@ func TestExecuteWorkflow_DependenciesNotMet(t *testing.T) {
 	stateJSON := `{}` // No step1 data
 	rows := sqlmock.NewRows([]string{
 		"correlation_id", "status", "current_step", "awaited_steps",
-		"collected_data", "initial_request_data", "final_result", "error",
-		"created_at", "updated_at",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
 	}).AddRow(
 		correlationID, StatusRunning, "step2", "[]",
-		stateJSON, nil, nil, nil,
+		stateJSON, nil, nil, nil, 0, nil, nil, nil, nil,
 		time.Now(), time.Now(),
 	)
 	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
@@ -178,7 +194,7 @@ func TestExecuteWorkflow_DependenciesNotMet(t *testing.T) {
 		WillReturnRows(rows)
 
 	// Should not produce any messages or update state
-	err := coordinator.ExecuteWorkflow(ctx, plan, headers, nil)
+	err := coordinator.ExecuteWorkflow(ctx, &models.AgentConfig{Version: 1, Workflow: plan}, headers, nil)
 	require.NoError(t, err, "Waiting for dependencies should not be an error")
 
 	require.NoError(t, mockDB.ExpectationsWereMet())
@@ -217,6 +233,8 @@ func TestExecuteWorkflow_FuelCheckFail(t *testing.T) {
 			sqlmock.AnyArg(), // awaited_steps
 			sqlmock.AnyArg(), // collected_data
 			sqlmock.AnyArg(), // initial_request_data
+			1,                // config_version
+			sqlmock.AnyArg(), // config_snapshot
 			sqlmock.AnyArg(), // created_at
 			sqlmock.AnyArg(), // updated_at
 		).WillReturnResult(sqlmock.NewResult(1, 1))
@@ -224,11 +242,11 @@ func TestExecuteWorkflow_FuelCheckFail(t *testing.T) {
 	// Fetch state
 	rows := sqlmock.NewRows([]string{
 		"correlation_id", "status", "current_step", "awaited_steps",
-		"collected_data", "initial_request_data", "final_result", "error",
-		"created_at", "updated_at",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
 	}).AddRow(
 		correlationID, StatusRunning, "step1", "[]",
-		"{}", nil, nil, nil,
+		"{}", nil, nil, nil, 0, nil, nil, nil, nil,
 		time.Now(), time.Now(),
 	)
 	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
@@ -246,10 +264,13 @@ func TestExecuteWorkflow_FuelCheckFail(t *testing.T) {
 			sqlmock.AnyArg(), // final_result = $6
 			sqlmock.AnyArg(), // error = $7 (will contain "insufficient fuel")
 			sqlmock.AnyArg(), // updated_at = $8
+			sqlmock.AnyArg(), // fuel_consumed = $9
+			sqlmock.AnyArg(), // request_headers = $10
+			sqlmock.AnyArg(), // dispatched_steps = $11
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Execute the workflow - it should fail with insufficient fuel error
-	err := coordinator.ExecuteWorkflow(ctx, plan, headers, nil)
+	err := coordinator.ExecuteWorkflow(ctx, &models.AgentConfig{Version: 1, Workflow: plan}, headers, nil)
 
 	// Assert that we got an error
 	require.Error(t, err, "Expected an error for insufficient fuel")
@@ -272,6 +293,8 @@ func TestHandleFanOut(t *testing.T) {
 		governance.FuelHeader: "1000",
 	}
 
+	coordinator.SetStepReplyTopic(stepReplyTopic)
+
 	step := models.Step{
 		Action:   "fan_out",
 		NextStep: "aggregate_results",
@@ -300,6 +323,9 @@ func TestHandleFanOut(t *testing.T) {
 			sqlmock.AnyArg(),        // final_result = $6
 			"",                      // error = $7
 			sqlmock.AnyArg(),        // updated_at = $8
+			sqlmock.AnyArg(),        // fuel_consumed = $9
+			sqlmock.AnyArg(),        // request_headers = $10
+			sqlmock.AnyArg(),        // dispatched_steps = $11
 		).WillReturnResult(sqlmock.NewResult(1, 1))
 
 	err := coordinator.handleFanOut(ctx, headers, step, state)
@@ -308,3 +334,639 @@ func TestHandleFanOut(t *testing.T) {
 	mockProducer.AssertExpectations(t)
 	require.NoError(t, mockDB.ExpectationsWereMet())
 }
+
+// staticExecutor resolves actions from a fixed map for testing.
+type staticExecutor map[string]ActionHandler
+
+func (e staticExecutor) Get(action string) (ActionHandler, bool) {
+	h, ok := e[action]
+	return h, ok
+}
+
+// TestExecuteWorkflow_LocalAction verifies registered actions run in-process instead of going to Kafka,
+// against the config pinned when the workflow started rather than the current one.
+func TestExecuteWorkflow_LocalAction(t *testing.T) {
+	coordinator, mockProducer, db, mockDB := setupTest(t)
+	defer db.Close()
+
+	var received *ActionRequest
+	coordinator.SetActionExecutor(staticExecutor{
+		"summarise": func(ctx context.Context, req *ActionRequest) (map[string]interface{}, error) {
+			received = req
+			return map[string]interface{}{"summary": "short"}, nil
+		},
+	})
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":      correlationID,
+		governance.FuelHeader: "100",
+	}
+
+	plan := models.WorkflowPlan{
+		StartStep: "summarise_step",
+		Steps: map[string]models.Step{
+			"summarise_step": {Action: "summarise"},
+		},
+	}
+
+	pinned := &models.AgentConfig{Version: 1, CoreLogic: map[string]interface{}{"tone": "formal"}, Workflow: plan}
+	pinnedJSON, _ := json.Marshal(pinned)
+
+	// The persona was edited after the workflow started
+	current := &models.AgentConfig{
+		Version:   2,
+		CoreLogic: map[string]interface{}{"tone": "casual"},
+		Workflow: models.WorkflowPlan{
+			StartStep: "summarise_step",
+			Steps:     map[string]models.Step{"summarise_step": {Action: "summarise", NextStep: "other"}},
+		},
+	}
+
+	// State already exists
+	rows := sqlmock.NewRows([]string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}).AddRow(
+		correlationID, StatusRunning, "summarise_step", "[]",
+		`{"research": {"text": "long"}}`, nil, 1, pinnedJSON, 0, nil, nil, nil, nil,
+		time.Now(), time.Now(),
+	)
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(rows)
+
+	// No next step, so the workflow completes straight away
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(
+			correlationID,    // WHERE correlation_id = $1
+			StatusCompleted,  // status = $2
+			"",               // current_step = $3
+			sqlmock.AnyArg(), // awaited_steps = $4
+			sqlmock.AnyArg(), // collected_data = $5
+			sqlmock.AnyArg(), // final_result = $6
+			"",               // error = $7
+			sqlmock.AnyArg(), // updated_at = $8
+			1,                // fuel_consumed = $9: the flat step cost
+			sqlmock.AnyArg(), // request_headers = $10
+			sqlmock.AnyArg(), // dispatched_steps = $11
+		).WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := coordinator.ExecuteWorkflow(ctx, current, headers, nil)
+	require.NoError(t, err)
+
+	require.NotNil(t, received, "handler should have been called")
+	assert.Equal(t, "summarise_step", received.StepName)
+	assert.Contains(t, received.Data, "research")
+	require.NotNil(t, received.Config)
+	assert.Equal(t, 1, received.Config.Version)
+	assert.Equal(t, "formal", received.Config.CoreLogic["tone"])
+
+	// Fuel was charged for the local step
+	assert.Equal(t, "99", headers[governance.FuelHeader])
+
+	mockProducer.AssertNotCalled(t, "Produce", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// TestExecuteWorkflow_LocalActionProgress verifies partial output is forwarded as STEP_PROGRESS notifications.
+func TestExecuteWorkflow_LocalActionProgress(t *testing.T) {
+	coordinator, mockProducer, db, mockDB := setupTest(t)
+	defer db.Close()
+
+	coordinator.SetActionExecutor(staticExecutor{
+		"write": func(ctx context.Context, req *ActionRequest) (map[string]interface{}, error) {
+			require.NotNil(t, req.Progress)
+			req.Progress("Hello")
+			req.Progress(", world")
+			return map[string]interface{}{"text": "Hello, world"}, nil
+		},
+	})
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":      correlationID,
+		governance.FuelHeader: "100",
+	}
+	plan := models.WorkflowPlan{
+		StartStep: "write_step",
+		Steps:     map[string]models.Step{"write_step": {Action: "write"}},
+	}
+
+	rows := sqlmock.NewRows([]string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}).AddRow(
+		correlationID, StatusRunning, "write_step", "[]",
+		"{}", nil, nil, nil, 0, nil, nil, nil, nil,
+		time.Now(), time.Now(),
+	)
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(rows)
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Small deltas are batched into a single notification
+	var notification map[string]interface{}
+	mockProducer.On("Produce", ctx, NotificationTopic, mock.Anything, []byte(correlationID), mock.Anything).
+		Run(func(args mock.Arguments) {
+			require.NoError(t, json.Unmarshal(args.Get(4).([]byte), &notification))
+		}).Return(nil).Once()
+
+	err := coordinator.ExecuteWorkflow(ctx, &models.AgentConfig{Version: 1, Workflow: plan}, headers, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, StepProgressEvent, notification["event_type"])
+	assert.Equal(t, "write_step", notification["step"])
+	assert.Equal(t, "Hello, world", notification["delta"])
+	mockProducer.AssertExpectations(t)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// fakeMemory records what the coordinator stores and returns fixed memories
+type fakeMemory struct {
+	memories []models.MemoryEntry
+	queries  []string
+	stored   []interface{}
+	projects []uuid.UUID
+}
+
+func (m *fakeMemory) ProcessWorkflowMemory(ctx context.Context, clientID string, agentID, projectID uuid.UUID, config models.MemoryConfiguration, step models.Step, input, output interface{}) error {
+	m.stored = append(m.stored, output)
+	m.projects = append(m.projects, projectID)
+	return nil
+}
+
+func (m *fakeMemory) GetMemoryContext(ctx context.Context, clientID string, agentID, projectID uuid.UUID, config models.MemoryConfiguration, currentContext string) ([]models.MemoryEntry, error) {
+	m.queries = append(m.queries, currentContext)
+	m.projects = append(m.projects, projectID)
+	return m.memories, nil
+}
+
+// TestExecuteWorkflow_Memory verifies memories are injected when the workflow starts and
+// outputs of steps marked store_memory are stored.
+func TestExecuteWorkflow_Memory(t *testing.T) {
+	coordinator, _, db, mockDB := setupTest(t)
+	defer db.Close()
+
+	store := &fakeMemory{memories: []models.MemoryEntry{{Content: "prefers short headlines", Type: "preference"}}}
+	coordinator.SetMemoryStore(store)
+
+	var received *ActionRequest
+	coordinator.SetActionExecutor(staticExecutor{
+		"write": func(ctx context.Context, req *ActionRequest) (map[string]interface{}, error) {
+			received = req
+			return map[string]interface{}{"text": "Launch day"}, nil
+		},
+	})
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	projectID := uuid.New()
+	headers := map[string]string{
+		"correlation_id":      correlationID,
+		"client_id":           "acme",
+		"agent_instance_id":   uuid.NewString(),
+		"project_id":          projectID.String(),
+		governance.FuelHeader: "100",
+	}
+	agentConfig := &models.AgentConfig{
+		Version:      1,
+		MemoryConfig: models.MemoryConfiguration{Enabled: true},
+		Workflow: models.WorkflowPlan{
+			StartStep: "write_step",
+			Steps:     map[string]models.Step{"write_step": {Action: "write", StoreMemory: true}},
+		},
+	}
+
+	rows := sqlmock.NewRows([]string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}).AddRow(
+		correlationID, StatusRunning, "write_step", "[]",
+		"{}", nil, nil, nil, 0, nil, nil, nil, nil,
+		time.Now(), time.Now(),
+	)
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(rows)
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	initialData := []byte(`{"action": "write", "data": {"prompt": "Write a headline for the launch"}}`)
+	err := coordinator.ExecuteWorkflow(ctx, agentConfig, headers, initialData)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Write a headline for the launch"}, store.queries)
+	require.NotNil(t, received)
+	assert.Equal(t, store.memories, received.Data[MemoryContextKey])
+	assert.Equal(t, []interface{}{map[string]interface{}{"text": "Launch day"}}, store.stored)
+	assert.Equal(t, []uuid.UUID{projectID, projectID}, store.projects)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// TestInjectMemories_WithoutQuery verifies requests with nothing to search for still get
+// the memories the store always offers, such as pinned ones.
+func TestInjectMemories_WithoutQuery(t *testing.T) {
+	coordinator, _, db, _ := setupTest(t)
+	defer db.Close()
+
+	store := &fakeMemory{memories: []models.MemoryEntry{{Content: "always use British spelling", Type: "pinned"}}}
+	coordinator.SetMemoryStore(store)
+
+	headers := map[string]string{"client_id": "acme", "agent_instance_id": uuid.NewString()}
+	agentConfig := &models.AgentConfig{MemoryConfig: models.MemoryConfiguration{Enabled: true}}
+	state := &OrchestrationState{CollectedData: map[string]interface{}{}}
+	coordinator.injectMemories(context.Background(), agentConfig, headers, nil, state)
+
+	assert.Equal(t, []string{""}, store.queries)
+	assert.Equal(t, store.memories, state.CollectedData[MemoryContextKey])
+}
+
+// TestHandleResponse_StoresRemoteOutput verifies outputs of remote steps are collected
+// and offered to memory under the step that sent the request, not another step that
+// also leads to the current one.
+func TestHandleResponse_StoresRemoteOutput(t *testing.T) {
+	coordinator, _, db, mockDB := setupTest(t)
+	defer db.Close()
+
+	store := &fakeMemory{}
+	coordinator.SetMemoryStore(store)
+
+	correlationID := uuid.NewString()
+	requestID := uuid.NewString()
+	snapshot, _ := json.Marshal(models.AgentConfig{
+		AgentID:      uuid.NewString(),
+		Version:      1,
+		MemoryConfig: models.MemoryConfiguration{Enabled: true},
+		Workflow: models.WorkflowPlan{
+			StartStep: "research",
+			Steps: map[string]models.Step{
+				"outline":  {Action: "outline", Topic: "system.tasks.outline", NextStep: "finish"},
+				"research": {Action: "research", Topic: "system.tasks.research", NextStep: "finish", StoreMemory: true},
+				"finish":   {Action: "complete_workflow"},
+			},
+		},
+	})
+
+	requestHeaders, _ := json.Marshal(map[string]string{"client_id": "acme", governance.FuelHeader: "100"})
+	dispatched, _ := json.Marshal(map[string]DispatchedStep{requestID: {Step: "research", Output: "research"}})
+
+	rows := sqlmock.NewRows([]string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}).AddRow(
+		correlationID, StatusAwaitingResponses, "finish", `["`+requestID+`"]`,
+		"{}", `{"data": {"prompt": "Research the launch"}}`, 1, string(snapshot), 0, string(requestHeaders), string(dispatched), nil, nil,
+		time.Now(), time.Now(),
+	)
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(rows)
+	collected := &capturedArg{}
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(correlationID, StatusRunning, "finish", sqlmock.AnyArg(), collected, sqlmock.AnyArg(),
+			"", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// The workflow then continues with its next step
+	continued := sqlmock.NewRows([]string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}).AddRow(
+		correlationID, StatusRunning, "finish", "[]",
+		"{}", nil, 1, string(snapshot), 0, string(requestHeaders), nil, nil, nil,
+		time.Now(), time.Now(),
+	)
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(continued)
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(correlationID, StatusCompleted, "finish", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			"", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// The response only identifies the workflow and the request it answers
+	headers := map[string]string{
+		"correlation_id": correlationID,
+		"causation_id":   requestID,
+	}
+	response := []byte(`{"success": true, "data": {"findings": "Launch is in May"}}`)
+	err := coordinator.HandleResponse(context.Background(), headers, response)
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"research": {"findings": "Launch is in May"}}`, string(collected.value.([]byte)))
+	assert.Equal(t, []interface{}{map[string]interface{}{"findings": "Launch is in May"}}, store.stored)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// capturedArg matches any argument and keeps it
+type capturedArg struct {
+	value driver.Value
+}
+
+func (c *capturedArg) Match(v driver.Value) bool {
+	c.value = v
+	return true
+}
+
+// TestRemoteStep_RepliesToCaller drives a workflow with a remote step the way /run does:
+// the caller waits on its reply topic while the step is answered on the agent's step
+// reply topic, and the workflow replies once the step responds.
+func TestRemoteStep_RepliesToCaller(t *testing.T) {
+	coordinator, mockProducer, db, mockDB := setupTest(t)
+	defer db.Close()
+	coordinator.SetStepReplyTopic(stepReplyTopic)
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	callerRequestID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":       correlationID,
+		"request_id":           callerRequestID,
+		"client_id":            "acme",
+		kafka.ReplyTopicHeader: "system.responses.core-manager",
+		governance.FuelHeader:  "1000",
+	}
+	agentConfig := &models.AgentConfig{
+		Version: 1,
+		Workflow: models.WorkflowPlan{
+			StartStep: "search",
+			Steps: map[string]models.Step{
+				"search": {Action: "web_search", Topic: "system.adapter.websearch"},
+			},
+		},
+	}
+	snapshot, _ := json.Marshal(agentConfig)
+	columns := []string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}
+
+	// The request starts the workflow and dispatches the remote step
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnError(sql.ErrNoRows)
+	mockDB.ExpectExec("INSERT INTO orchestrator_state").WillReturnResult(sqlmock.NewResult(1, 1))
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			correlationID, StatusRunning, "search", "[]",
+			"{}", nil, 1, string(snapshot), 0, nil, nil, nil, nil,
+			time.Now(), time.Now(),
+		))
+
+	var dispatched map[string]string
+	mockProducer.On("Produce", ctx, "system.adapter.websearch", mock.Anything, []byte(correlationID), mock.Anything).
+		Run(func(args mock.Arguments) { dispatched = args.Get(2).(map[string]string) }).
+		Return(nil).Once()
+
+	stored, dispatchedSteps := &capturedArg{}, &capturedArg{}
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(correlationID, StatusAwaitingResponses, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			"", sqlmock.AnyArg(), sqlmock.AnyArg(), stored, dispatchedSteps).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	require.NoError(t, coordinator.ExecuteWorkflow(ctx, agentConfig, headers, nil))
+	require.NotNil(t, dispatched)
+	assert.Equal(t, stepReplyTopic, dispatched[kafka.ReplyTopicHeader])
+	assert.Equal(t, callerRequestID, dispatched["causation_id"])
+
+	// The adapter replies on the step reply topic; the workflow has no next step and completes
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(sqlmock.NewRows(columns).AddRow(
+			correlationID, StatusAwaitingResponses, "", `["`+dispatched["request_id"]+`"]`,
+			"{}", nil, 1, string(snapshot), 0, stored.value, dispatchedSteps.value, nil, nil,
+			time.Now(), time.Now(),
+		))
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(correlationID, StatusCompleted, "", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			"", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	var replyHeaders map[string]string
+	var reply models.TaskResponse
+	mockProducer.On("Produce", ctx, "system.responses.core-manager", mock.Anything, []byte(correlationID), mock.Anything).
+		Run(func(args mock.Arguments) {
+			replyHeaders = args.Get(2).(map[string]string)
+			require.NoError(t, json.Unmarshal(args.Get(4).([]byte), &reply))
+		}).Return(nil).Once()
+
+	responseHeaders := map[string]string{
+		"correlation_id": correlationID,
+		"causation_id":   dispatched["request_id"],
+	}
+	response := []byte(`{"success": true, "data": {"results": ["Launch is in May"]}}`)
+	require.NoError(t, coordinator.HandleResponse(ctx, responseHeaders, response))
+
+	// The caller matches the reply to its request
+	assert.Equal(t, callerRequestID, replyHeaders["causation_id"])
+	assert.Equal(t, "acme", replyHeaders["client_id"])
+	assert.True(t, reply.Success)
+	assert.Equal(t, map[string]interface{}{"search": map[string]interface{}{"results": []interface{}{"Launch is in May"}}}, reply.Data)
+	mockProducer.AssertExpectations(t)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// TestHandleResponse_FailedStepFailsWorkflow verifies a failed remote step fails the
+// workflow and tells the caller, and that responses nobody awaits are ignored.
+func TestHandleResponse_FailedStepFailsWorkflow(t *testing.T) {
+	coordinator, mockProducer, db, mockDB := setupTest(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	requestID := uuid.NewString()
+	requestHeaders, _ := json.Marshal(map[string]string{
+		"request_id":           "caller-request",
+		kafka.ReplyTopicHeader: "system.responses.core-manager",
+	})
+	awaiting := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{
+			"correlation_id", "status", "current_step", "awaited_steps",
+			"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+			"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+		}).AddRow(
+			correlationID, StatusAwaitingResponses, "summarise", `["`+requestID+`"]`,
+			"{}", nil, 1, nil, 0, string(requestHeaders), nil, nil, nil,
+			time.Now(), time.Now(),
+		)
+	}
+
+	// A response to another request changes nothing
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(awaiting())
+	unrelated := map[string]string{"correlation_id": correlationID, "causation_id": uuid.NewString()}
+	require.NoError(t, coordinator.HandleResponse(ctx, unrelated, []byte(`{"success": true}`)))
+
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(awaiting())
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(correlationID, StatusFailed, "summarise", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			"remote step failed: search unavailable", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	causedByCaller := mock.MatchedBy(func(h map[string]string) bool { return h["causation_id"] == "caller-request" })
+	mockProducer.On("Produce", ctx, "system.responses.core-manager", causedByCaller, mock.Anything, mock.Anything).Return(nil).Once()
+
+	headers := map[string]string{"correlation_id": correlationID, "causation_id": requestID}
+	err := coordinator.HandleResponse(ctx, headers, []byte(`{"success": false, "error": "search unavailable"}`))
+	assert.EqualError(t, err, "remote step failed: search unavailable")
+
+	mockProducer.AssertExpectations(t)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// TestExecuteWorkflow_RemoteStepWithoutReplyTopic verifies workflows fail up front when
+// nothing would receive the response of a remote step.
+func TestExecuteWorkflow_RemoteStepWithoutReplyTopic(t *testing.T) {
+	coordinator, mockProducer, db, mockDB := setupTest(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":       correlationID,
+		"request_id":           "caller-request",
+		kafka.ReplyTopicHeader: "system.responses.core-manager",
+	}
+	state := &OrchestrationState{CorrelationID: correlationID, CollectedData: map[string]interface{}{}}
+
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WithArgs(correlationID, StatusFailed, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			"action 'web_search' runs remotely and this agent has no step reply topic",
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mockProducer.On("Produce", ctx, "system.responses.core-manager", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	err := coordinator.handleStandardAction(ctx, headers, models.Step{Action: "web_search", Topic: "system.adapter.websearch"}, state)
+	assert.Error(t, err)
+
+	mockProducer.AssertExpectations(t)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// TestExecuteWorkflow_MemoryContextNotInResult verifies injected memories are neither
+// stored again by store_memory nor returned as part of the workflow result.
+func TestExecuteWorkflow_MemoryContextNotInResult(t *testing.T) {
+	coordinator, mockProducer, db, mockDB := setupTest(t)
+	defer db.Close()
+	coordinator.SetResponseTopic("system.responses.workflow")
+
+	store := &fakeMemory{memories: []models.MemoryEntry{{Content: "prefers short headlines", Type: "preference"}}}
+	coordinator.SetMemoryStore(store)
+
+	ctx := context.Background()
+	correlationID := uuid.NewString()
+	headers := map[string]string{
+		"correlation_id":      correlationID,
+		"client_id":           "acme",
+		"agent_instance_id":   uuid.NewString(),
+		governance.FuelHeader: "100",
+	}
+	agentConfig := &models.AgentConfig{
+		Version:      1,
+		MemoryConfig: models.MemoryConfiguration{Enabled: true},
+		Workflow: models.WorkflowPlan{
+			StartStep: "remember",
+			Steps:     map[string]models.Step{"remember": {Action: StoreMemoryAction}},
+		},
+	}
+
+	rows := sqlmock.NewRows([]string{
+		"correlation_id", "status", "current_step", "awaited_steps",
+		"collected_data", "initial_request_data", "config_version", "config_snapshot", "fuel_consumed",
+		"request_headers", "dispatched_steps", "final_result", "error", "created_at", "updated_at",
+	}).AddRow(
+		correlationID, StatusRunning, "remember", "[]",
+		`{"research": {"findings": "Launch is in May"}}`, nil, nil, nil, 0, nil, nil, nil, nil,
+		time.Now(), time.Now(),
+	)
+	mockDB.ExpectQuery("SELECT .* FROM orchestrator_state WHERE correlation_id = \\$1").
+		WithArgs(correlationID).
+		WillReturnRows(rows)
+	mockDB.ExpectExec("UPDATE orchestrator_state SET").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	var reply models.TaskResponse
+	mockProducer.On("Produce", ctx, "system.responses.workflow", mock.Anything, []byte(correlationID), mock.Anything).
+		Run(func(args mock.Arguments) {
+			require.NoError(t, json.Unmarshal(args.Get(4).([]byte), &reply))
+		}).Return(nil).Once()
+
+	initialData := []byte(`{"action": "write", "data": {"prompt": "Write a headline for the launch"}}`)
+	err := coordinator.ExecuteWorkflow(ctx, agentConfig, headers, initialData)
+	require.NoError(t, err)
+
+	require.Len(t, store.stored, 1)
+	assert.Equal(t, map[string]interface{}{
+		"research": map[string]interface{}{"findings": "Launch is in May"},
+	}, store.stored[0])
+
+	assert.True(t, reply.Success)
+	assert.NotContains(t, reply.Data, MemoryContextKey)
+	assert.Contains(t, reply.Data, "research")
+	mockProducer.AssertExpectations(t)
+	require.NoError(t, mockDB.ExpectationsWereMet())
+}
+
+// fakeLibrary serves the latest versions of templates by ID
+type fakeLibrary map[string]int
+
+func (l fakeLibrary) Get(ctx context.Context, id string, version int) (*prompts.Template, error) {
+	latest, ok := l[id]
+	if !ok {
+		return nil, prompts.ErrTemplateNotFound
+	}
+	if version == 0 {
+		version = latest
+	}
+	return &prompts.Template{ID: id, Version: version}, nil
+}
+
+func TestPinPromptTemplates(t *testing.T) {
+	coordinator, _, db, _ := setupTest(t)
+	defer db.Close()
+
+	config := &models.AgentConfig{
+		Version:   3,
+		CoreLogic: map[string]interface{}{"prompt_template": "persona-voice", "model": "claude-3-haiku-20240307"},
+		Workflow: models.WorkflowPlan{
+			StartStep: "draft",
+			Steps: map[string]models.Step{
+				"draft":  {Action: "ai_text_generate", PromptTemplate: "blog-draft", NextStep: "review"},
+				"review": {Action: "ai_text_generate", PromptTemplate: "review@2", NextStep: "edit"},
+				"edit":   {Action: "ai_text_generate", PromptTemplate: "deleted", NextStep: "finish"},
+				"finish": {Action: "complete_workflow"},
+			},
+		},
+	}
+
+	// Without a library references stay as they are
+	assert.Same(t, config, coordinator.pinPromptTemplates(context.Background(), config))
+
+	coordinator.SetPromptLibrary(fakeLibrary{"persona-voice": 4, "blog-draft": 7, "review": 5})
+	pinned := coordinator.pinPromptTemplates(context.Background(), config)
+
+	assert.Equal(t, "persona-voice@4", pinned.CoreLogic["prompt_template"])
+	assert.Equal(t, "claude-3-haiku-20240307", pinned.CoreLogic["model"])
+	assert.Equal(t, "blog-draft@7", pinned.Workflow.Steps["draft"].PromptTemplate)
+	assert.Equal(t, "review@2", pinned.Workflow.Steps["review"].PromptTemplate, "pinned references are kept")
+	assert.Equal(t, "deleted", pinned.Workflow.Steps["edit"].PromptTemplate, "unresolved references are kept")
+	assert.Equal(t, 3, pinned.Version)
+
+	// The current config is not changed
+	assert.Equal(t, "persona-voice", config.CoreLogic["prompt_template"])
+	assert.Equal(t, "blog-draft", config.Workflow.Steps["draft"].PromptTemplate)
+}
@@ -0,0 +1,179 @@
+// FILE: platform/orchestration/memory.go
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/gqls/agentchassis/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Memory actions run by the coordinator itself
+const (
+	StoreMemoryAction    = "store_memory"
+	RetrieveMemoryAction = "retrieve_memory"
+)
+
+// MemoryContextKey is the collected data key that holds the memories retrieved when a
+// workflow starts. Downstream steps see it with the rest of the collected data.
+const MemoryContextKey = "memory_context"
+
+// MemoryStore is the long-term memory the coordinator reads and writes; see memory.Service
+type MemoryStore interface {
+	ProcessWorkflowMemory(ctx context.Context, clientID string, agentID, projectID uuid.UUID, config models.MemoryConfiguration, step models.Step, input, output interface{}) error
+	GetMemoryContext(ctx context.Context, clientID string, agentID, projectID uuid.UUID, config models.MemoryConfiguration, currentContext string) ([]models.MemoryEntry, error)
+}
+
+// SetMemoryStore enables the memory actions, auto-storing of step outputs and memory
+// retrieval at the start of workflows whose config has memory enabled
+func (s *SagaCoordinator) SetMemoryStore(store MemoryStore) {
+	s.memory = store
+}
+
+// memoryScope returns the client and persona instance memories belong to, and the
+// project the workflow runs in, or uuid.Nil outside projects
+func memoryScope(headers map[string]string, agentConfig *models.AgentConfig) (string, uuid.UUID, uuid.UUID, bool) {
+	clientID := headers["client_id"]
+	instanceID := headers["agent_instance_id"]
+	if instanceID == "" {
+		instanceID = agentConfig.AgentID
+	}
+	agentID, err := uuid.Parse(instanceID)
+	if clientID == "" || err != nil {
+		return "", uuid.Nil, uuid.Nil, false
+	}
+	projectID, _ := uuid.Parse(headers["project_id"])
+	return clientID, agentID, projectID, true
+}
+
+// requestData returns the data of the original request
+func requestData(initialData []byte) map[string]interface{} {
+	var task struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if len(initialData) > 0 {
+		_ = json.Unmarshal(initialData, &task)
+	}
+	return task.Data
+}
+
+// memoryQuery is the text memories are retrieved for: the request prompt, or the whole
+// request when it has none
+func memoryQuery(initialData []byte) string {
+	data := requestData(initialData)
+	if prompt, ok := data["prompt"].(string); ok && prompt != "" {
+		return prompt
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	query, _ := json.Marshal(data)
+	return string(query)
+}
+
+// injectMemories adds the memories relevant to the request to the collected data.
+// Failures are logged; the workflow runs without memories.
+func (s *SagaCoordinator) injectMemories(ctx context.Context, agentConfig *models.AgentConfig, headers map[string]string, initialData []byte, state *OrchestrationState) {
+	if s.memory == nil || !agentConfig.MemoryConfig.Enabled {
+		return
+	}
+	if _, ok := state.CollectedData[MemoryContextKey]; ok {
+		return
+	}
+	clientID, agentID, projectID, ok := memoryScope(headers, agentConfig)
+	if !ok {
+		return
+	}
+	// Without a query only pinned memories are offered
+	memories, err := s.memory.GetMemoryContext(ctx, clientID, agentID, projectID, agentConfig.MemoryConfig, memoryQuery(initialData))
+	if err != nil {
+		s.logger.Warn("Failed to retrieve memories for workflow",
+			zap.String("correlation_id", state.CorrelationID), zap.Error(err))
+		return
+	}
+	if len(memories) > 0 {
+		state.CollectedData[MemoryContextKey] = memories
+	}
+}
+
+// autoStoreMemory offers a step's output to memory. Steps with store_memory set are
+// always stored; with auto_store, other outputs are stored when important enough.
+func (s *SagaCoordinator) autoStoreMemory(ctx context.Context, agentConfig *models.AgentConfig, headers map[string]string, initialData []byte, step models.Step, output map[string]interface{}) {
+	config := agentConfig.MemoryConfig
+	if s.memory == nil || !config.Enabled || (!step.StoreMemory && !config.AutoStore) {
+		return
+	}
+	clientID, agentID, projectID, ok := memoryScope(headers, agentConfig)
+	if !ok {
+		return
+	}
+	if err := s.memory.ProcessWorkflowMemory(ctx, clientID, agentID, projectID, config, step, requestData(initialData), output); err != nil {
+		s.logger.Warn("Failed to store step output in memory",
+			zap.String("correlation_id", headers["correlation_id"]),
+			zap.String("action", step.Action), zap.Error(err))
+	}
+}
+
+// stepOutputs returns the collected data without the injected memories, which are
+// context for the workflow's steps rather than part of its result
+func stepOutputs(collected map[string]interface{}) map[string]interface{} {
+	outputs := make(map[string]interface{}, len(collected))
+	for k, v := range collected {
+		if k != MemoryContextKey {
+			outputs[k] = v
+		}
+	}
+	return outputs
+}
+
+// handleMemoryAction runs store_memory and retrieve_memory steps. store_memory stores the
+// outputs of the step's dependencies, or all step outputs so far without any;
+// retrieve_memory outputs the memories relevant to the request.
+func (s *SagaCoordinator) handleMemoryAction(ctx context.Context, agentConfig *models.AgentConfig, headers map[string]string, initialData []byte, step models.Step, state *OrchestrationState) error {
+	if s.memory == nil {
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("action '%s' requires memory, which is not configured", step.Action))
+	}
+	clientID, agentID, projectID, ok := memoryScope(headers, agentConfig)
+	if !ok {
+		return s.failWorkflow(ctx, headers, state, fmt.Sprintf("action '%s' requires a client and agent instance", step.Action))
+	}
+
+	// The step asked for memory explicitly, whatever the persona's defaults
+	config := agentConfig.MemoryConfig
+	config.Enabled = true
+
+	var output map[string]interface{}
+	switch step.Action {
+	case StoreMemoryAction:
+		outputs := make(map[string]interface{})
+		for _, dep := range step.Dependencies {
+			outputs[dep] = state.CollectedData[dep]
+		}
+		if len(step.Dependencies) == 0 {
+			outputs = stepOutputs(state.CollectedData)
+		}
+		step.StoreMemory = true
+		if err := s.memory.ProcessWorkflowMemory(ctx, clientID, agentID, projectID, config, step, requestData(initialData), outputs); err != nil {
+			return s.failWorkflow(ctx, headers, state, fmt.Sprintf("action '%s' failed: %v", step.Action, err))
+		}
+		output = map[string]interface{}{"stored": true}
+	case RetrieveMemoryAction:
+		var memories []models.MemoryEntry
+		if query := memoryQuery(initialData); query != "" {
+			var err error
+			memories, err = s.memory.GetMemoryContext(ctx, clientID, agentID, projectID, config, query)
+			if err != nil {
+				return s.failWorkflow(ctx, headers, state, fmt.Sprintf("action '%s' failed: %v", step.Action, err))
+			}
+		}
+		if memories == nil {
+			memories = []models.MemoryEntry{}
+		}
+		output = map[string]interface{}{"memories": memories}
+	}
+
+	return s.advance(ctx, agentConfig, headers, initialData, step, state, output)
+}
@@ -0,0 +1,126 @@
+// FILE: platform/orchestration/progress.go
+package orchestration
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// StepProgressEvent carries partial step output to the UI
+	StepProgressEvent = "STEP_PROGRESS"
+
+	// Deltas are batched so a token stream does not become one Kafka message per token
+	progressFlushBytes    = 256
+	progressFlushInterval = 250 * time.Millisecond
+)
+
+// ProgressFunc forwards a piece of partial output from a running action
+type ProgressFunc func(delta string)
+
+// NotifyFunc sends an event about a running action to the UI. The workflow's
+// correlation, project and client IDs and the step are added to the payload.
+type NotifyFunc func(eventType string, payload map[string]interface{})
+
+// notifier returns the NotifyFunc for a step. Notifications are best effort.
+func (s *SagaCoordinator) notifier(ctx context.Context, headers map[string]string, step, action string) NotifyFunc {
+	return func(eventType string, payload map[string]interface{}) {
+		notification := map[string]interface{}{
+			"event_type":     eventType,
+			"correlation_id": headers["correlation_id"],
+			"project_id":     headers["project_id"],
+			"client_id":      headers["client_id"],
+			"step":           step,
+			"action":         action,
+		}
+		for k, v := range payload {
+			notification[k] = v
+		}
+		notificationBytes, _ := json.Marshal(notification)
+
+		if err := s.producer.Produce(ctx, NotificationTopic, headers, []byte(headers["correlation_id"]), notificationBytes); err != nil {
+			s.logger.Warn("Failed to send step notification",
+				zap.String("correlation_id", headers["correlation_id"]),
+				zap.String("event_type", eventType),
+				zap.Error(err))
+		}
+	}
+}
+
+// progressReporter batches partial output of a step into STEP_PROGRESS notifications
+type progressReporter struct {
+	coordinator *SagaCoordinator
+	ctx         context.Context
+	headers     map[string]string
+	step        string
+	action      string
+
+	mu        sync.Mutex
+	buffer    strings.Builder
+	sequence  int
+	lastFlush time.Time
+}
+
+func (s *SagaCoordinator) newProgressReporter(ctx context.Context, headers map[string]string, step, action string) *progressReporter {
+	return &progressReporter{
+		coordinator: s,
+		ctx:         ctx,
+		headers:     headers,
+		step:        step,
+		action:      action,
+		lastFlush:   time.Now(),
+	}
+}
+
+// Report buffers a delta and sends it once enough output or time has accumulated
+func (p *progressReporter) Report(delta string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buffer.WriteString(delta)
+	if p.buffer.Len() >= progressFlushBytes || time.Since(p.lastFlush) >= progressFlushInterval {
+		p.flushLocked()
+	}
+}
+
+// Flush sends whatever output is still buffered
+func (p *progressReporter) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.flushLocked()
+}
+
+func (p *progressReporter) flushLocked() {
+	p.lastFlush = time.Now()
+	if p.buffer.Len() == 0 {
+		return
+	}
+
+	notification := map[string]interface{}{
+		"event_type":     StepProgressEvent,
+		"correlation_id": p.headers["correlation_id"],
+		"project_id":     p.headers["project_id"],
+		"client_id":      p.headers["client_id"],
+		"step":           p.step,
+		"action":         p.action,
+		"sequence":       p.sequence,
+		"delta":          p.buffer.String(),
+	}
+	p.buffer.Reset()
+	p.sequence++
+
+	notificationBytes, _ := json.Marshal(notification)
+
+	// Progress is best effort; a lost update must not fail the step
+	if err := p.coordinator.producer.Produce(p.ctx, NotificationTopic, p.headers, []byte(p.headers["correlation_id"]), notificationBytes); err != nil {
+		p.coordinator.logger.Warn("Failed to send step progress",
+			zap.String("correlation_id", p.headers["correlation_id"]),
+			zap.String("step", p.step),
+			zap.Error(err))
+	}
+}
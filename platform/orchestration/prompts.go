@@ -0,0 +1,56 @@
+// FILE: platform/orchestration/prompts.go
+package orchestration
+
+import (
+	"context"
+
+	"github.com/gqls/agentchassis/pkg/models"
+	"github.com/gqls/agentchassis/platform/prompts"
+	"go.uber.org/zap"
+)
+
+// SetPromptLibrary pins prompt templates referenced without a version to their latest
+// version when a workflow starts, so template edits do not change running workflows
+func (s *SagaCoordinator) SetPromptLibrary(library prompts.Library) {
+	s.prompts = library
+}
+
+// pinPromptTemplates returns config with its persona and step prompt template
+// references pinned to the latest version. config itself is left unchanged. References
+// that cannot be resolved are kept; the step reports the error when it renders them.
+func (s *SagaCoordinator) pinPromptTemplates(ctx context.Context, config *models.AgentConfig) *models.AgentConfig {
+	if s.prompts == nil {
+		return config
+	}
+
+	pin := func(refString string) string {
+		ref, err := prompts.ParseRef(refString)
+		if err != nil || ref.Version > 0 {
+			return refString
+		}
+		tmpl, err := s.prompts.Get(ctx, ref.ID, 0)
+		if err != nil {
+			s.logger.Warn("Failed to pin prompt template", zap.String("prompt_template", refString), zap.Error(err))
+			return refString
+		}
+		ref.Version = tmpl.Version
+		return ref.String()
+	}
+
+	pinned := *config
+	if ref, ok := config.CoreLogic["prompt_template"].(string); ok && ref != "" {
+		pinned.CoreLogic = make(map[string]interface{}, len(config.CoreLogic))
+		for k, v := range config.CoreLogic {
+			pinned.CoreLogic[k] = v
+		}
+		pinned.CoreLogic["prompt_template"] = pin(ref)
+	}
+	pinned.Workflow.Steps = make(map[string]models.Step, len(config.Workflow.Steps))
+	for name, step := range config.Workflow.Steps {
+		if step.PromptTemplate != "" {
+			step.PromptTemplate = pin(step.PromptTemplate)
+		}
+		pinned.Workflow.Steps[name] = step
+	}
+	return &pinned
+}
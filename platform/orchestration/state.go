@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/gqls/agentchassis/pkg/models"
 	"go.uber.org/zap"
 )
 
@@ -24,16 +25,27 @@ const (
 
 // OrchestrationState is the database model for a Saga instance
 type OrchestrationState struct {
-	CorrelationID      string                 `db:"correlation_id"`
-	Status             OrchestrationStatus    `db:"status"`
-	CurrentStep        string                 `db:"current_step"`
-	AwaitedSteps       []string               `db:"awaited_steps"`
-	CollectedData      map[string]interface{} `db:"collected_data"`
-	InitialRequestData json.RawMessage        `db:"initial_request_data"`
-	FinalResult        json.RawMessage        `db:"final_result"`
-	Error              string                 `db:"error"`
-	CreatedAt          time.Time              `db:"created_at"`
-	UpdatedAt          time.Time              `db:"updated_at"`
+	CorrelationID      string                    `db:"correlation_id"`
+	Status             OrchestrationStatus       `db:"status"`
+	CurrentStep        string                    `db:"current_step"`
+	AwaitedSteps       []string                  `db:"awaited_steps"`
+	CollectedData      map[string]interface{}    `db:"collected_data"`
+	InitialRequestData json.RawMessage           `db:"initial_request_data"`
+	ConfigVersion      int                       `db:"config_version"`
+	ConfigSnapshot     *models.AgentConfig       `db:"config_snapshot"` // Config pinned when the workflow started
+	FuelConsumed       int                       `db:"fuel_consumed"`
+	RequestHeaders     map[string]string         `db:"request_headers"`  // Headers to continue and reply with after remote steps
+	DispatchedSteps    map[string]DispatchedStep `db:"dispatched_steps"` // Remote requests by request ID
+	FinalResult        json.RawMessage           `db:"final_result"`
+	Error              string                    `db:"error"`
+	CreatedAt          time.Time                 `db:"created_at"`
+	UpdatedAt          time.Time                 `db:"updated_at"`
+}
+
+// DispatchedStep records which step sent a remote request
+type DispatchedStep struct {
+	Step   string `json:"step"`   // The workflow step that sent the request
+	Output string `json:"output"` // The name its response is collected under
 }
 
 // StateRepository provides an interface for persisting and retrieving workflow state
@@ -47,27 +59,35 @@ func NewStateRepository(db *sql.DB, logger *zap.Logger) *StateRepository {
 	return &StateRepository{db: db, logger: logger}
 }
 
-// CreateInitialState creates a new record for a workflow
-func (r *StateRepository) CreateInitialState(ctx context.Context, correlationID, startStep string, initialData []byte) error {
+// CreateInitialState creates a new record for a workflow, pinning the config it runs against
+func (r *StateRepository) CreateInitialState(ctx context.Context, correlationID string, snapshot *models.AgentConfig, initialData []byte) error {
 	awaitedStepsJSON, _ := json.Marshal([]string{})
 	collectedDataJSON, _ := json.Marshal(map[string]interface{}{})
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config snapshot: %w", err)
+	}
 
 	query := `
         INSERT INTO orchestrator_state 
-        (correlation_id, status, current_step, awaited_steps, collected_data, initial_request_data, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        (correlation_id, status, current_step, awaited_steps, collected_data, initial_request_data,
+         config_version, config_snapshot, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
     `
 
 	now := time.Now().UTC()
-	_, err := r.db.ExecContext(ctx, query,
-		correlationID, StatusRunning, startStep, awaitedStepsJSON, collectedDataJSON, initialData, now, now)
+	_, err = r.db.ExecContext(ctx, query,
+		correlationID, StatusRunning, snapshot.Workflow.StartStep, awaitedStepsJSON, collectedDataJSON, initialData,
+		snapshot.Version, snapshotJSON, now, now)
 
 	if err != nil {
 		r.logger.Error("Failed to create initial orchestration state", zap.Error(err))
 		return fmt.Errorf("failed to create initial state: %w", err)
 	}
 
-	r.logger.Info("Initial orchestration state created", zap.String("correlation_id", correlationID))
+	r.logger.Info("Initial orchestration state created",
+		zap.String("correlation_id", correlationID),
+		zap.Int("config_version", snapshot.Version))
 	return nil
 }
 
@@ -75,7 +95,9 @@ func (r *StateRepository) CreateInitialState(ctx context.Context, correlationID,
 func (r *StateRepository) GetState(ctx context.Context, correlationID string) (*OrchestrationState, error) {
 	query := `
         SELECT correlation_id, status, current_step, awaited_steps, collected_data, 
-               initial_request_data, final_result, error, created_at, updated_at
+               initial_request_data, config_version, config_snapshot, fuel_consumed, request_headers, dispatched_steps,
+               final_result, error,
+               created_at, updated_at
         FROM orchestrator_state
         WHERE correlation_id = $1
     `
@@ -85,6 +107,10 @@ func (r *StateRepository) GetState(ctx context.Context, correlationID string) (*
 	var initialRequestDataNull sql.NullString // Handle NULL for initial_request_data
 	var finalResultNull sql.NullString
 	var errorNull sql.NullString
+	var configVersionNull sql.NullInt64
+	var configSnapshotNull sql.NullString
+	var requestHeadersNull sql.NullString
+	var dispatchedStepsNull sql.NullString
 
 	err := r.db.QueryRowContext(ctx, query, correlationID).Scan(
 		&state.CorrelationID,
@@ -93,6 +119,11 @@ func (r *StateRepository) GetState(ctx context.Context, correlationID string) (*
 		&awaitedStepsJSON,
 		&collectedDataJSON,
 		&initialRequestDataNull, // Scan into NullString
+		&configVersionNull,
+		&configSnapshotNull,
+		&state.FuelConsumed,
+		&requestHeadersNull,
+		&dispatchedStepsNull,
 		&finalResultNull,
 		&errorNull,
 		&state.CreatedAt,
@@ -123,6 +154,28 @@ func (r *StateRepository) GetState(ctx context.Context, correlationID string) (*
 		state.Error = errorNull.String
 	}
 
+	// Workflows created before config pinning have no snapshot
+	if configVersionNull.Valid {
+		state.ConfigVersion = int(configVersionNull.Int64)
+	}
+	if configSnapshotNull.Valid {
+		var snapshot models.AgentConfig
+		if err := json.Unmarshal([]byte(configSnapshotNull.String), &snapshot); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal config_snapshot: %w", err)
+		}
+		state.ConfigSnapshot = &snapshot
+	}
+	if requestHeadersNull.Valid {
+		if err := json.Unmarshal([]byte(requestHeadersNull.String), &state.RequestHeaders); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal request_headers: %w", err)
+		}
+	}
+	if dispatchedStepsNull.Valid {
+		if err := json.Unmarshal([]byte(dispatchedStepsNull.String), &state.DispatchedSteps); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dispatched_steps: %w", err)
+		}
+	}
+
 	// Unmarshal JSON fields
 	if err := json.Unmarshal(awaitedStepsJSON, &state.AwaitedSteps); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal awaited_steps: %w", err)
@@ -138,11 +191,20 @@ func (r *StateRepository) GetState(ctx context.Context, correlationID string) (*
 func (r *StateRepository) UpdateState(ctx context.Context, state *OrchestrationState) error {
 	awaitedStepsJSON, _ := json.Marshal(state.AwaitedSteps)
 	collectedDataJSON, _ := json.Marshal(state.CollectedData)
+	var requestHeadersJSON []byte
+	if state.RequestHeaders != nil {
+		requestHeadersJSON, _ = json.Marshal(state.RequestHeaders)
+	}
+	var dispatchedStepsJSON []byte
+	if state.DispatchedSteps != nil {
+		dispatchedStepsJSON, _ = json.Marshal(state.DispatchedSteps)
+	}
 
 	query := `
         UPDATE orchestrator_state 
         SET status = $2, current_step = $3, awaited_steps = $4, collected_data = $5, 
-            final_result = $6, error = $7, updated_at = $8
+            final_result = $6, error = $7, updated_at = $8, fuel_consumed = $9,
+            request_headers = $10, dispatched_steps = $11
         WHERE correlation_id = $1
     `
 
@@ -155,6 +217,9 @@ func (r *StateRepository) UpdateState(ctx context.Context, state *OrchestrationS
 		state.FinalResult,
 		state.Error,
 		time.Now().UTC(),
+		state.FuelConsumed,
+		requestHeadersJSON,
+		dispatchedStepsJSON,
 	)
 
 	if err != nil {
@@ -178,6 +243,11 @@ CREATE TABLE IF NOT EXISTS orchestrator_state (
     awaited_steps JSONB DEFAULT '[]',
     collected_data JSONB DEFAULT '{}',
     initial_request_data JSONB,
+    config_version INTEGER,
+    config_snapshot JSONB,
+    fuel_consumed INTEGER NOT NULL DEFAULT 0,
+    request_headers JSONB,
+    dispatched_steps JSONB,
     final_result JSONB,
     error TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
@@ -0,0 +1,293 @@
+// FILE: platform/prompts/store.go
+package prompts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// uniqueViolation is the Postgres error code for a duplicate key
+const uniqueViolation = "23505"
+
+// Store keeps prompt templates in the templates database
+type Store struct {
+	db     *pgxpool.Pool
+	logger *zap.Logger
+}
+
+// NewStore creates a store on the templates database
+func NewStore(db *pgxpool.Pool, logger *zap.Logger) *Store {
+	return &Store{db: db, logger: logger}
+}
+
+// Create adds a template as version 1. Names are unique among active templates;
+// a taken name returns ErrTemplateExists.
+func (s *Store) Create(ctx context.Context, tmpl *Template) (*Template, error) {
+	if err := tmpl.Validate(); err != nil {
+		return nil, err
+	}
+	s.logger.Info("Creating prompt template", zap.String("name", tmpl.Name))
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+        INSERT INTO prompt_templates (name, description, latest_version)
+        VALUES ($1, $2, 1)
+        RETURNING id
+    `, tmpl.Name, tmpl.Description).Scan(&tmpl.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateExists, tmpl.Name)
+		}
+		return nil, fmt.Errorf("failed to create prompt template: %w", err)
+	}
+
+	tmpl.Version = 1
+	if err := insertVersion(ctx, tx, tmpl); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// AddVersion stores tmpl as the next version of template id. Earlier versions are kept,
+// so workflows pinned to them are unaffected.
+func (s *Store) AddVersion(ctx context.Context, id string, tmpl *Template) (*Template, error) {
+	if err := tmpl.Validate(); err != nil {
+		return nil, err
+	}
+	if !validID(id) {
+		return nil, ErrTemplateNotFound
+	}
+	s.logger.Info("Adding prompt template version", zap.String("id", id))
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The row lock serialises concurrent edits
+	err = tx.QueryRow(ctx, `
+        UPDATE prompt_templates
+        SET latest_version = latest_version + 1,
+            description = COALESCE(NULLIF($2, ''), description),
+            updated_at = NOW()
+        WHERE id = $1 AND is_active = true
+        RETURNING id, name, COALESCE(description, ''), latest_version
+    `, id, tmpl.Description).Scan(&tmpl.ID, &tmpl.Name, &tmpl.Description, &tmpl.Version)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to update prompt template: %w", err)
+	}
+
+	if err := insertVersion(ctx, tx, tmpl); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func insertVersion(ctx context.Context, tx pgx.Tx, tmpl *Template) error {
+	variables, _ := json.Marshal(tmpl.Variables)
+	if tmpl.Variables == nil {
+		variables = []byte("[]")
+	}
+	err := tx.QueryRow(ctx, `
+        INSERT INTO prompt_template_versions (template_id, version, system_template, body, variables, created_by)
+        VALUES ($1, $2, $3, $4, $5, $6)
+        RETURNING created_at
+    `, tmpl.ID, tmpl.Version, tmpl.System, tmpl.Body, variables, tmpl.CreatedBy).Scan(&tmpl.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to store prompt template version: %w", err)
+	}
+	return nil
+}
+
+const selectVersion = `
+        SELECT t.id, t.name, COALESCE(t.description, ''), v.version, v.system_template, v.body,
+               v.variables, COALESCE(v.created_by, ''), v.created_at
+        FROM prompt_templates t
+        JOIN prompt_template_versions v ON v.template_id = t.id`
+
+// Get returns a version of a template, or the latest when version is 0.
+// Deleted templates have no latest version but keep their numbered ones.
+func (s *Store) Get(ctx context.Context, id string, version int) (*Template, error) {
+	if !validID(id) {
+		return nil, ErrTemplateNotFound
+	}
+	query, args := getQuery(id, version)
+	tmpl, err := scanTemplate(s.db.QueryRow(ctx, query, args...))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// getQuery selects a version of a template, or its latest. Pinned versions of deleted
+// templates stay readable, so workflows that pinned them before the delete can finish.
+func getQuery(id string, version int) (string, []interface{}) {
+	if version > 0 {
+		return selectVersion + `
+        WHERE t.id = $1 AND v.version = $2`, []interface{}{id, version}
+	}
+	return selectVersion + `
+        WHERE t.id = $1 AND t.is_active = true AND v.version = t.latest_version`, []interface{}{id}
+}
+
+// List returns the latest version of every active template
+func (s *Store) List(ctx context.Context) ([]Template, error) {
+	query := selectVersion + ` AND v.version = t.latest_version
+        WHERE t.is_active = true
+        ORDER BY t.name`
+	return s.query(ctx, query)
+}
+
+// ListVersions returns every version of a template, newest first
+func (s *Store) ListVersions(ctx context.Context, id string) ([]Template, error) {
+	if !validID(id) {
+		return nil, ErrTemplateNotFound
+	}
+	query := selectVersion + `
+        WHERE t.id = $1 AND t.is_active = true
+        ORDER BY v.version DESC`
+	templates, err := s.query(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) == 0 {
+		return nil, ErrTemplateNotFound
+	}
+	return templates, nil
+}
+
+// Delete deactivates a template. Its versions are kept for audit.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrTemplateNotFound
+	}
+	s.logger.Info("Deleting prompt template", zap.String("id", id))
+
+	tag, err := s.db.Exec(ctx, `UPDATE prompt_templates SET is_active = false, updated_at = NOW() WHERE id = $1 AND is_active = true`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete prompt template: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+func (s *Store) query(ctx context.Context, query string, args ...interface{}) ([]Template, error) {
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prompt templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []Template
+	for rows.Next() {
+		tmpl, err := scanTemplate(rows)
+		if err != nil {
+			s.logger.Error("Failed to scan prompt template row", zap.Error(err))
+			continue
+		}
+		templates = append(templates, *tmpl)
+	}
+	return templates, rows.Err()
+}
+
+// validID reports whether id can be a template ID; anything else cannot exist
+func validID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil
+}
+
+func scanTemplate(row pgx.Row) (*Template, error) {
+	var tmpl Template
+	var variables []byte
+	if err := row.Scan(&tmpl.ID, &tmpl.Name, &tmpl.Description, &tmpl.Version, &tmpl.System, &tmpl.Body,
+		&variables, &tmpl.CreatedBy, &tmpl.CreatedAt); err != nil {
+		return nil, err
+	}
+	json.Unmarshal(variables, &tmpl.Variables)
+	return &tmpl, nil
+}
+
+// Cache keeps templates in memory. Versions never change, so pinned lookups are
+// cached for good; lookups of the latest version expire after ttl.
+type Cache struct {
+	library Library
+	ttl     time.Duration
+
+	mu     sync.RWMutex
+	pinned map[Ref]*Template
+	latest map[string]cachedTemplate
+}
+
+type cachedTemplate struct {
+	tmpl    *Template
+	expires time.Time
+}
+
+// NewCache wraps a library with an in-memory cache
+func NewCache(library Library, ttl time.Duration) *Cache {
+	return &Cache{
+		library: library,
+		ttl:     ttl,
+		pinned:  make(map[Ref]*Template),
+		latest:  make(map[string]cachedTemplate),
+	}
+}
+
+// Get returns a template from the cache, loading it on a miss
+func (c *Cache) Get(ctx context.Context, id string, version int) (*Template, error) {
+	c.mu.RLock()
+	if version > 0 {
+		if tmpl, ok := c.pinned[Ref{ID: id, Version: version}]; ok {
+			c.mu.RUnlock()
+			return tmpl, nil
+		}
+	} else if entry, ok := c.latest[id]; ok && time.Now().Before(entry.expires) {
+		c.mu.RUnlock()
+		return entry.tmpl, nil
+	}
+	c.mu.RUnlock()
+
+	tmpl, err := c.library.Get(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.pinned[Ref{ID: id, Version: tmpl.Version}] = tmpl
+	if version == 0 {
+		c.latest[id] = cachedTemplate{tmpl: tmpl, expires: time.Now().Add(c.ttl)}
+	}
+	c.mu.Unlock()
+	return tmpl, nil
+}
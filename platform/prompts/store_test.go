@@ -0,0 +1,23 @@
+// FILE: platform/prompts/store_test.go
+package prompts
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetQuery_PinnedVersionsOfDeletedTemplates(t *testing.T) {
+	// Delete only clears is_active, so a pinned version must not filter on it
+	query, args := getQuery("tmpl-1", 3)
+	where := strings.Join(strings.Fields(query[strings.Index(query, "WHERE"):]), " ")
+	assert.Equal(t, "WHERE t.id = $1 AND v.version = $2", where)
+	assert.Equal(t, []interface{}{"tmpl-1", 3}, args)
+
+	// Deleted templates have no latest version
+	query, args = getQuery("tmpl-1", 0)
+	where = strings.Join(strings.Fields(query[strings.Index(query, "WHERE"):]), " ")
+	assert.Equal(t, "WHERE t.id = $1 AND t.is_active = true AND v.version = t.latest_version", where)
+	assert.Equal(t, []interface{}{"tmpl-1"}, args)
+}
@@ -0,0 +1,192 @@
+// FILE: platform/prompts/template.go
+package prompts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	// ErrTemplateNotFound is returned when a template or version does not exist
+	ErrTemplateNotFound = errors.New("prompt template not found")
+
+	// ErrTemplateExists is returned when an active template already has the name
+	ErrTemplateExists = errors.New("prompt template already exists")
+
+	// ErrMissingVariable is returned when a required variable has no value
+	ErrMissingVariable = errors.New("missing prompt variable")
+)
+
+// Template is one version of a prompt template.
+// System and Body are Go templates rendered with the caller's variables.
+type Template struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Version     int        `json:"version"`
+	System      string     `json:"system,omitempty"`
+	Body        string     `json:"body"`
+	Variables   []Variable `json:"variables,omitempty"`
+	CreatedBy   string     `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Variable declares a value the template expects
+type Variable struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+}
+
+// Rendered is a template filled in with variables
+type Rendered struct {
+	System string
+	Prompt string
+}
+
+// Library looks up templates by ID. Version 0 means the latest version.
+type Library interface {
+	Get(ctx context.Context, id string, version int) (*Template, error)
+}
+
+// Ref points at a template from a persona config or workflow step,
+// as "<id>" for the latest version or "<id>@<version>" to pin one
+type Ref struct {
+	ID      string
+	Version int
+}
+
+// ParseRef parses a template reference
+func ParseRef(ref string) (Ref, error) {
+	id, version, pinned := strings.Cut(strings.TrimSpace(ref), "@")
+	if id == "" {
+		return Ref{}, fmt.Errorf("empty prompt template reference")
+	}
+	if !pinned {
+		return Ref{ID: id}, nil
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil || v < 1 {
+		return Ref{}, fmt.Errorf("invalid version in prompt template reference '%s'", ref)
+	}
+	return Ref{ID: id, Version: v}, nil
+}
+
+// String formats the reference
+func (r Ref) String() string {
+	if r.Version == 0 {
+		return r.ID
+	}
+	return fmt.Sprintf("%s@%d", r.ID, r.Version)
+}
+
+// funcs are available to every template
+var funcs = template.FuncMap{
+	"join": func(items interface{}, sep string) string {
+		switch v := items.(type) {
+		case []string:
+			return strings.Join(v, sep)
+		case []interface{}:
+			parts := make([]string, len(v))
+			for i, item := range v {
+				parts[i] = fmt.Sprint(item)
+			}
+			return strings.Join(parts, sep)
+		case nil:
+			return ""
+		default:
+			return fmt.Sprint(v)
+		}
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"default": func(fallback, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+}
+
+// Validate checks that the template parses and its variables are well formed
+func (t *Template) Validate() error {
+	if strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+	seen := make(map[string]bool, len(t.Variables))
+	for _, v := range t.Variables {
+		if v.Name == "" {
+			return fmt.Errorf("variable name is required")
+		}
+		if seen[v.Name] {
+			return fmt.Errorf("variable '%s' is declared twice", v.Name)
+		}
+		seen[v.Name] = true
+	}
+	if _, err := parse("system", t.System); err != nil {
+		return err
+	}
+	_, err := parse("body", t.Body)
+	return err
+}
+
+// Render fills in the template. Declared defaults apply to variables the caller
+// did not set, and referencing a variable that is neither set nor declared is an error.
+func (t *Template) Render(vars map[string]interface{}) (*Rendered, error) {
+	data := make(map[string]interface{}, len(vars)+len(t.Variables))
+	for k, v := range vars {
+		data[k] = v
+	}
+	for _, v := range t.Variables {
+		if _, ok := data[v.Name]; ok {
+			continue
+		}
+		if v.Required {
+			return nil, fmt.Errorf("%w '%s' for template '%s'", ErrMissingVariable, v.Name, t.Name)
+		}
+		data[v.Name] = v.Default
+	}
+
+	system, err := execute("system", t.System, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render system prompt of '%s' v%d: %w", t.Name, t.Version, err)
+	}
+	prompt, err := execute("body", t.Body, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render '%s' v%d: %w", t.Name, t.Version, err)
+	}
+	return &Rendered{System: system, Prompt: prompt}, nil
+}
+
+func parse(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func execute(name, text string, data map[string]interface{}) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := parse(name, text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
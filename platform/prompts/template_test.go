@@ -0,0 +1,105 @@
+// FILE: platform/prompts/template_test.go
+package prompts
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	tmpl := &Template{
+		Name:   "review",
+		System: "You review {{.kind}} content.",
+		Body:   `Criteria: {{join .criteria ", "}}. Context: {{json .context}}. Tone: {{.tone}}`,
+		Variables: []Variable{
+			{Name: "criteria", Required: true},
+			{Name: "kind", Default: "marketing"},
+			{Name: "tone", Default: "neutral"},
+		},
+	}
+	require.NoError(t, tmpl.Validate())
+
+	rendered, err := tmpl.Render(map[string]interface{}{
+		"criteria": []interface{}{"clarity", "accuracy"},
+		"context":  map[string]interface{}{"audience": "developers"},
+		"tone":     "friendly",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "You review marketing content.", rendered.System)
+	assert.Equal(t, `Criteria: clarity, accuracy. Context: {"audience":"developers"}. Tone: friendly`, rendered.Prompt)
+}
+
+func TestRender_MissingVariables(t *testing.T) {
+	tmpl := &Template{
+		Name:      "review",
+		Body:      "{{.content}} {{.typo}}",
+		Variables: []Variable{{Name: "content", Required: true}},
+	}
+
+	_, err := tmpl.Render(nil)
+	require.ErrorIs(t, err, ErrMissingVariable)
+
+	// Undeclared variables are errors rather than silently empty
+	_, err = tmpl.Render(map[string]interface{}{"content": "x"})
+	require.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	assert.Error(t, (&Template{Body: ""}).Validate())
+	assert.Error(t, (&Template{Body: "{{.x"}).Validate())
+	assert.Error(t, (&Template{Body: "{{.x}}", Variables: []Variable{{Name: "x"}, {Name: "x"}}}).Validate())
+}
+
+func TestParseRef(t *testing.T) {
+	ref, err := ParseRef("6f1c2a3e-0000-4000-8000-000000000001@3")
+	require.NoError(t, err)
+	assert.Equal(t, Ref{ID: "6f1c2a3e-0000-4000-8000-000000000001", Version: 3}, ref)
+	assert.Equal(t, "6f1c2a3e-0000-4000-8000-000000000001@3", ref.String())
+
+	ref, err = ParseRef("6f1c2a3e-0000-4000-8000-000000000001")
+	require.NoError(t, err)
+	assert.Equal(t, 0, ref.Version)
+
+	_, err = ParseRef("id@latest")
+	assert.Error(t, err)
+	_, err = ParseRef("")
+	assert.Error(t, err)
+}
+
+type countingLibrary struct {
+	version int
+	calls   int
+}
+
+func (l *countingLibrary) Get(ctx context.Context, id string, version int) (*Template, error) {
+	l.calls++
+	if version == 0 {
+		version = l.version
+	}
+	return &Template{ID: id, Version: version, Body: "v"}, nil
+}
+
+func TestCache(t *testing.T) {
+	library := &countingLibrary{version: 2}
+	cache := NewCache(library, time.Hour)
+	ctx := context.Background()
+
+	latest, err := cache.Get(ctx, "a", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, latest.Version)
+
+	// The latest lookup also fills the pinned entry for its version
+	_, err = cache.Get(ctx, "a", 2)
+	require.NoError(t, err)
+	_, err = cache.Get(ctx, "a", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, library.calls)
+
+	_, err = cache.Get(ctx, "a", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, library.calls)
+}
@@ -18,6 +18,10 @@ type CircuitBreakerConfig struct {
 	Timeout             time.Duration
 	ConsecutiveFailures uint32
 	FailureRatio        float64
+
+	// IsSuccessful decides whether an error counts against the breaker.
+	// Nil means every non-nil error is a failure.
+	IsSuccessful func(err error) bool
 }
 
 // DefaultCircuitBreakerConfig returns sensible defaults
@@ -50,6 +54,7 @@ func NewCircuitBreaker(config CircuitBreakerConfig, logger *zap.Logger) *Circuit
 			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
 			return counts.Requests >= config.ConsecutiveFailures && failureRatio >= config.FailureRatio
 		},
+		IsSuccessful: config.IsSuccessful,
 		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
 			logger.Warn("Circuit breaker state change",
 				zap.String("name", name),
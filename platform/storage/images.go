@@ -0,0 +1,88 @@
+// FILE: platform/storage/images.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrForeignObject is returned for URIs outside the requesting client's objects
+var ErrForeignObject = errors.New("object does not belong to the client")
+
+// ImageKeyPrefix is the key prefix of a client's images. Keys are namespaced by client
+// so fetches can be limited to the requesting client's images.
+func ImageKeyPrefix(clientID string) string {
+	return "images/" + clientID + "/"
+}
+
+// ImageFetcher loads images from object storage by their s3:// URI, as returned by
+// Upload. Only images under ImageKeyPrefix of the requesting client, in the fetcher's
+// bucket, are read.
+type ImageFetcher struct {
+	client   Client
+	bucket   string
+	maxBytes int64
+}
+
+// NewImageFetcher creates a fetcher for bucket that refuses images larger than maxBytes
+func NewImageFetcher(client Client, bucket string, maxBytes int64) *ImageFetcher {
+	return &ImageFetcher{client: client, bucket: bucket, maxBytes: maxBytes}
+}
+
+// FetchImage downloads one of the client's images and detects its media type
+func (f *ImageFetcher) FetchImage(ctx context.Context, clientID, uri string) ([]byte, string, error) {
+	bucket, key, err := ParseURI(uri)
+	if err != nil {
+		return nil, "", err
+	}
+	if bucket != f.bucket {
+		return nil, "", fmt.Errorf("%w: %s is not in bucket %s", ErrForeignObject, uri, f.bucket)
+	}
+	if clientID == "" || !strings.HasPrefix(key, ImageKeyPrefix(clientID)) {
+		return nil, "", fmt.Errorf("%w: %s is not an image of client %q", ErrForeignObject, uri, clientID)
+	}
+
+	body, err := f.client.Download(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, f.maxBytes+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read object %s: %w", key, err)
+	}
+	if int64(len(data)) > f.maxBytes {
+		return nil, "", fmt.Errorf("object %s is larger than %d bytes", key, f.maxBytes)
+	}
+	return data, http.DetectContentType(data), nil
+}
+
+// KeyFromURI returns the object key of an s3://bucket/key URI
+func KeyFromURI(uri string) (string, error) {
+	_, key, err := ParseURI(uri)
+	return key, err
+}
+
+// ParseURI splits an s3://bucket/key URI. Keys with ".." segments are refused so a
+// prefix check on the key holds.
+func ParseURI(uri string) (string, string, error) {
+	rest, ok := strings.CutPrefix(uri, "s3://")
+	if !ok {
+		return "", "", fmt.Errorf("not an s3 URI: %s", uri)
+	}
+	bucket, key, ok := strings.Cut(rest, "/")
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("s3 URI has no object key: %s", uri)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." {
+			return "", "", fmt.Errorf("s3 URI has a relative key: %s", uri)
+		}
+	}
+	return bucket, key, nil
+}
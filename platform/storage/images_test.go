@@ -0,0 +1,57 @@
+// FILE: platform/storage/images_test.go
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// objectStore serves objects by key
+type objectStore struct {
+	Client
+	objects map[string][]byte
+}
+
+func (s objectStore) Download(ctx context.Context, key string) (io.ReadCloser, error) {
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, fmt.Errorf("no such object %s", key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestImageFetcherLimitsToClient(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	store := objectStore{objects: map[string][]byte{
+		"images/acme/hero.png":   png,
+		"images/globex/logo.png": png,
+	}}
+	fetcher := NewImageFetcher(store, "assets", 1<<20)
+	ctx := context.Background()
+
+	data, mediaType, err := fetcher.FetchImage(ctx, "acme", "s3://assets/images/acme/hero.png")
+	require.NoError(t, err)
+	assert.Equal(t, png, data)
+	assert.Equal(t, "image/png", mediaType)
+
+	for _, uri := range []string{
+		"s3://assets/images/globex/logo.png",           // Another client's image
+		"s3://other/images/acme/hero.png",              // Another bucket
+		"s3://assets/documents/acme/report/report.pdf", // Not an image key
+	} {
+		_, _, err := fetcher.FetchImage(ctx, "acme", uri)
+		assert.ErrorIs(t, err, ErrForeignObject, uri)
+	}
+	_, _, err = fetcher.FetchImage(ctx, "", "s3://assets/images/acme/hero.png")
+	assert.ErrorIs(t, err, ErrForeignObject)
+
+	// Keys cannot escape the client's prefix
+	_, _, err = fetcher.FetchImage(ctx, "acme", "s3://assets/images/acme/../globex/logo.png")
+	assert.Error(t, err)
+}
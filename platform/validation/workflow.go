@@ -7,13 +7,20 @@ import (
 )
 
 // WorkflowValidator provides validation for workflow plans
-type WorkflowValidator struct{}
+type WorkflowValidator struct {
+	isLocalAction func(action string) bool
+}
 
 // NewWorkflowValidator creates a new workflow validator
 func NewWorkflowValidator() *WorkflowValidator {
 	return &WorkflowValidator{}
 }
 
+// SetLocalActionResolver tells the validator which actions run in-process and so need no topic
+func (v *WorkflowValidator) SetLocalActionResolver(resolver func(action string) bool) {
+	v.isLocalAction = resolver
+}
+
 // ValidateWorkflowPlan validates a workflow plan for correctness
 func (v *WorkflowValidator) ValidateWorkflowPlan(plan models.WorkflowPlan) error {
 	if plan.StartStep == "" {
@@ -75,7 +82,7 @@ func (v *WorkflowValidator) validateStep(name string, step models.Step, plan mod
 		}
 	default:
 		// For standard actions, ensure topic is set if not internal actions
-		if step.Topic == "" && !v.isInternalAction(step.Action) {
+		if step.Topic == "" && !v.isInternalAction(step.Action) && !v.hasLocalHandler(step.Action) {
 			return fmt.Errorf("step '%s' with action '%s' requires a topic", name, step.Action)
 		}
 	}
@@ -101,6 +108,11 @@ func (v *WorkflowValidator) isInternalAction(action string) bool {
 	return internalActions[action]
 }
 
+// hasLocalHandler checks if an action is handled by a registered in-process handler
+func (v *WorkflowValidator) hasLocalHandler(action string) bool {
+	return v.isLocalAction != nil && v.isLocalAction(action)
+}
+
 // validateDependencies ensures all dependencies exist
 func (v *WorkflowValidator) validateDependencies(plan models.WorkflowPlan) error {
 	for stepName, step := range plan.Steps {
@@ -214,10 +214,14 @@ deploy_agents() {
     print_step "Deploying Web Search Adapter..."
     kubectl apply -f k8s/web-search-adapter.yaml
 
+    print_step "Deploying Document Ingestion Worker..."
+    kubectl apply -f k8s/document-ingestion-worker.yaml
+
     wait_for_pods "app=agent-chassis" "Agent Chassis" "600s"
     wait_for_pods "app=reasoning-agent" "Reasoning Agent"
     wait_for_pods "app=image-generator-adapter" "Image Generator"
     wait_for_pods "app=web-search-adapter" "Web Search"
+    wait_for_pods "app=document-ingestion-worker" "Document Ingestion"
 
     print_success "Agent services deployed"
 }
@@ -71,6 +71,14 @@ run_postgres_migration \
     "/app/migrations/002_create_templates_schema.sql" \
     "Templates database migration"
 
+run_postgres_migration \
+    "postgres-templates" \
+    "templates_user" \
+    "templates_db" \
+    "$TEMPLATES_DB_PASSWORD" \
+    "/app/migrations/008_prompt_templates.sql" \
+    "Prompt templates migration"
+
 # 3. Create base clients database structure
 run_postgres_migration \
     "postgres-clients" \
@@ -91,6 +99,9 @@ CREATE TABLE IF NOT EXISTS orchestrator_state (
     awaited_steps JSONB DEFAULT '[]',
     collected_data JSONB DEFAULT '{}',
     initial_request_data JSONB,
+    config_version INTEGER,
+    config_snapshot JSONB,
+    fuel_consumed INTEGER NOT NULL DEFAULT 0,
     final_result JSONB,
     error TEXT,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
@@ -105,6 +116,78 @@ CREATE INDEX IF NOT EXISTS idx_orchestrator_state_updated_at ON orchestrator_sta
 }
 echo -e "${GREEN}✅ Orchestrator state table created${NC}"
 
+# 4b. Config versioning for existing client schemas
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/006_config_versioning.sql" \
+    "Config versioning migration"
+
+# 4c. Fuel accounting for workflows
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/007_fuel_accounting.sql" \
+    "Fuel accounting migration"
+
+# 4d. Shared AI response cache
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/009_ai_response_cache.sql" \
+    "AI response cache migration"
+
+# 4e. Memory management columns for existing client schemas
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/010_memory_management.sql" \
+    "Memory management migration"
+
+# 4f. Memory search indexes for existing client schemas
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/011_memory_search.sql" \
+    "Memory search migration"
+
+# 4g. Shared memory scopes for existing client schemas
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/012_memory_scopes.sql" \
+    "Memory scopes migration"
+
+# 4h. Workflow request headers for existing client schemas
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/013_workflow_request_headers.sql" \
+    "Workflow request headers migration"
+
+# 4i. Dispatched workflow steps for existing client schemas
+run_postgres_migration \
+    "postgres-clients" \
+    "clients_user" \
+    "clients_db" \
+    "$CLIENTS_DB_PASSWORD" \
+    "/app/migrations/014_workflow_dispatched_steps.sql" \
+    "Workflow dispatched steps migration"
+
 # 5. Migrate auth database (MySQL)
 run_mysql_migration \
     "mysql-auth" \
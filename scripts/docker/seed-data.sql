@@ -69,7 +69,7 @@ seed_postgres_data \
          'A versatile copywriting assistant that can create engaging content across various formats and tones.',
          'copywriter',
          '{
-             \"model\": \"claude-3-sonnet\",
+             \"model\": \"claude-3-5-sonnet-20240620\",
              \"temperature\": 0.7,
              \"max_tokens\": 2000,
              \"system_prompt\": \"You are a professional copywriter. Create compelling, engaging content that resonates with the target audience. Always consider the tone, style, and purpose of the content.\",
@@ -107,7 +107,7 @@ seed_postgres_data \
          'An in-depth research specialist that can gather, analyze, and synthesize information from multiple sources.',
          'researcher',
          '{
-             \"model\": \"claude-3-opus\",
+             \"model\": \"claude-3-opus-20240229\",
              \"temperature\": 0.3,
              \"max_tokens\": 4000,
              \"system_prompt\": \"You are a thorough research assistant. Provide comprehensive, well-sourced, and analytically rigorous research. Always cite sources and present balanced perspectives.\",
@@ -151,7 +151,7 @@ seed_postgres_data \
          'A specialized content creator for well-structured, engaging blog posts with research backing.',
          'content-creator',
          '{
-             \"model\": \"claude-3-sonnet\",
+             \"model\": \"claude-3-5-sonnet-20240620\",
              \"temperature\": 0.6,
              \"max_tokens\": 3000,
              \"system_prompt\": \"You are a professional blog writer. Create well-structured, engaging blog posts with clear introductions, informative body content, and compelling conclusions. Use proper headings and maintain consistent tone.\",
@@ -203,7 +203,7 @@ seed_postgres_data \
          'Creates both textual content and accompanying images for comprehensive visual storytelling.',
          'multimedia-creator',
          '{
-             \"model\": \"claude-3-sonnet\",
+             \"model\": \"claude-3-5-sonnet-20240620\",
              \"temperature\": 0.7,
              \"max_tokens\": 2500,
              \"system_prompt\": \"You are a visual content creator. Create compelling text content and provide detailed image descriptions for visual elements that enhance the narrative.\",